@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const agentLifecycleMCPName = "agent_lifecycle"
+
+type agentMCPStartInput struct {
+	Name         string `json:"name" jsonschema:"feature name; also the branch and tmux window name"`
+	SourceBranch string `json:"source_branch,omitempty" jsonschema:"branch to fork from (default: the repo's base branch)"`
+	Device       string `json:"device,omitempty" jsonschema:"flutter device for the run pane (flutter repos only)"`
+	NoDevice     bool   `json:"no_device,omitempty" jsonschema:"leave the run pane idle (flutter repos only)"`
+	Prompt       string `json:"prompt,omitempty" jsonschema:"first message for the new agent"`
+}
+
+type agentMCPListInput struct {
+	All bool `json:"all,omitempty" jsonschema:"include agents from every repo, not just this one"`
+}
+
+type agentMCPIDInput struct {
+	ID string `json:"id" jsonschema:"agent id as returned by list_agents"`
+}
+
+type agentMCPPromptInput struct {
+	ID     string `json:"id" jsonschema:"agent id as returned by list_agents"`
+	Prompt string `json:"prompt" jsonschema:"message to type into the agent's AI pane"`
+}
+
+type agentMCPDestroyInput struct {
+	ID      string `json:"id" jsonschema:"agent id as returned by list_agents"`
+	Confirm string `json:"confirm,omitempty" jsonschema:"must be \"destroy\" when the agent has uncommitted changes"`
+}
+
+type agentMCPAgent struct {
+	ID           string `json:"id"`
+	State        string `json:"state"`
+	Branch       string `json:"branch"`
+	SourceBranch string `json:"source_branch,omitempty"`
+	RepoCopyPath string `json:"repo_copy_path"`
+	WindowID     string `json:"window_id,omitempty"`
+	AIPane       string `json:"ai_pane,omitempty"`
+}
+
+type agentMCPList struct {
+	Agents []agentMCPAgent `json:"agents"`
+}
+
+type agentMCPTask struct {
+	Pane           string `json:"pane,omitempty"`
+	Status         string `json:"status"`
+	Summary        string `json:"summary,omitempty"`
+	StatusNote     string `json:"status_note,omitempty"`
+	CompletionNote string `json:"completion_note,omitempty"`
+	Acknowledged   bool   `json:"acknowledged"`
+	Stalled        bool   `json:"stalled,omitempty"`
+}
+
+type agentMCPGit struct {
+	Branch       string   `json:"branch,omitempty"`
+	Dirty        bool     `json:"dirty"`
+	ChangedFiles []string `json:"changed_files,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+type agentMCPStatus struct {
+	agentMCPAgent
+	Bootstrap    string         `json:"bootstrap"`
+	Tasks        []agentMCPTask `json:"tasks"`
+	TrackerError string         `json:"tracker_error,omitempty"`
+	Git          agentMCPGit    `json:"git"`
+}
+
+type agentMCPResult struct {
+	Message string `json:"message"`
+}
+
+// runAgentMCP serves the agent lifecycle over MCP so a lead agent can start,
+// watch, prompt and destroy sub-agents in the repo it runs in.
+func runAgentMCP(args []string) error {
+	fs := flagSet("agent mcp")
+	var repo string
+	fs.StringVar(&repo, "repo", "", "repo root to manage agents in (default: the current repo)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	root := strings.TrimSpace(repo)
+	if root == "" {
+		detected, err := repoRoot()
+		if err != nil {
+			return fmt.Errorf("%w; run inside a repo or pass --repo", err)
+		}
+		root = detected
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: agentLifecycleMCPName, Version: "0.1.0"}, nil)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "start_agent",
+		Description: "Create a new agent workspace on its own branch, open its tmux window in the background and optionally start it with a prompt. Bootstrap continues after this returns; poll agent_status for progress.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input agentMCPStartInput) (*mcp.CallToolResult, any, error) {
+		record, err := startAgent(root, agentStartRequest{
+			Feature:      input.Name,
+			Device:       input.Device,
+			NoDevice:     input.NoDevice,
+			SourceBranch: input.SourceBranch,
+			Prompt:       input.Prompt,
+			Detached:     true,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, agentMCPSummary(record), nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_agents",
+		Description: "List agents in this repo (or every repo with all=true) and whether their tmux window is running.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input agentMCPListInput) (*mcp.CallToolResult, any, error) {
+		reg, err := loadRegistry()
+		if err != nil {
+			return nil, nil, err
+		}
+		list := agentMCPList{Agents: []agentMCPAgent{}}
+		for _, id := range sortedAgentIDs(reg) {
+			record := reg.Agents[id]
+			if !input.All && filepath.Clean(record.RepoRoot) != filepath.Clean(root) {
+				continue
+			}
+			list.Agents = append(list.Agents, agentMCPSummary(record))
+		}
+		return nil, list, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "agent_status",
+		Description: "Report an agent's bootstrap state, its tracker tasks and whether its repo copy has uncommitted changes.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input agentMCPIDInput) (*mcp.CallToolResult, any, error) {
+		record, err := agentMCPRecord(input.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, agentMCPStatusFor(record), nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "send_prompt",
+		Description: "Type a message into an agent's AI pane and press Enter, as if the user had typed it.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input agentMCPPromptInput) (*mcp.CallToolResult, any, error) {
+		record, err := agentMCPRecord(input.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		prompt := strings.TrimSpace(input.Prompt)
+		if prompt == "" {
+			return nil, nil, fmt.Errorf("prompt is required")
+		}
+		if !windowAlive(record.TmuxSessionID, record.TmuxWindowID) || strings.TrimSpace(record.Panes.AI) == "" {
+			return nil, nil, fmt.Errorf("agent %s is not running; resume it first", record.ID)
+		}
+		if err := sendPromptToPane(record.Panes.AI, prompt); err != nil {
+			return nil, nil, err
+		}
+		return nil, agentMCPResult{Message: "Prompt sent to " + record.ID + "."}, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "destroy_agent",
+		Description: "Delete an agent's workspace, branch copy and tmux window. Refuses while the window has open todos, and needs confirm=\"destroy\" when the repo copy has uncommitted changes.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input agentMCPDestroyInput) (*mcp.CallToolResult, any, error) {
+		target, err := loadDestroyTarget(sanitizeFeatureName(input.ID))
+		if err != nil {
+			return nil, nil, err
+		}
+		if target.RequiresExplicitConfirm && strings.TrimSpace(input.Confirm) != "destroy" {
+			return nil, nil, fmt.Errorf("agent has uncommitted changes; call again with confirm=\"destroy\"")
+		}
+		if err := destroyAgent(target); err != nil {
+			return nil, nil, err
+		}
+		return nil, agentMCPResult{Message: "Destroyed " + target.Record.ID + "."}, nil
+	})
+
+	return server.Run(context.Background(), &mcp.StdioTransport{})
+}
+
+func agentMCPRecord(id string) (*agentRecord, error) {
+	reg, err := loadRegistry()
+	if err != nil {
+		return nil, err
+	}
+	record := reg.Agents[sanitizeFeatureName(id)]
+	if record == nil {
+		return nil, fmt.Errorf("unknown agent: %s", id)
+	}
+	return record, nil
+}
+
+func agentMCPSummary(record *agentRecord) agentMCPAgent {
+	state := "stopped"
+	if windowAlive(record.TmuxSessionID, record.TmuxWindowID) {
+		state = "running"
+	}
+	return agentMCPAgent{
+		ID:           record.ID,
+		State:        state,
+		Branch:       record.Branch,
+		SourceBranch: record.SourceBranch,
+		RepoCopyPath: record.RepoCopyPath,
+		WindowID:     record.TmuxWindowID,
+		AIPane:       record.Panes.AI,
+	}
+}
+
+func agentMCPStatusFor(record *agentRecord) agentMCPStatus {
+	status := agentMCPStatus{
+		agentMCPAgent: agentMCPSummary(record),
+		Bootstrap:     paletteBootstrapStatus(record),
+		Tasks:         []agentMCPTask{},
+		Git:           agentGitState(record.RepoCopyPath),
+	}
+	if status.State != "running" {
+		return status
+	}
+	env, err := trackerLoadState("", &ipc.Filter{SessionID: record.TmuxSessionID, WindowID: record.TmuxWindowID})
+	if err != nil {
+		status.TrackerError = err.Error()
+		return status
+	}
+	for _, task := range env.Tasks {
+		status.Tasks = append(status.Tasks, agentMCPTask{
+			Pane:           task.Pane,
+			Status:         task.Status,
+			Summary:        task.Summary,
+			StatusNote:     task.StatusNote,
+			CompletionNote: task.CompletionNote,
+			Acknowledged:   task.Acknowledged,
+			Stalled:        task.Stalled,
+		})
+	}
+	return status
+}
+
+func agentGitState(repoPath string) agentMCPGit {
+	var state agentMCPGit
+	if strings.TrimSpace(repoPath) == "" || !fileExists(repoPath) {
+		state.Error = "repo copy missing"
+		return state
+	}
+	cmd := exec.Command("git", "status", "--porcelain", "--branch")
+	cmd.Dir = repoPath
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		state.Error = strings.TrimSpace(firstNonEmpty(string(out), err.Error()))
+		return state
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		if branch, ok := strings.CutPrefix(line, "## "); ok {
+			state.Branch, _, _ = strings.Cut(branch, "...")
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			state.ChangedFiles = append(state.ChangedFiles, strings.TrimSpace(line))
+		}
+	}
+	state.Dirty = len(state.ChangedFiles) > 0
+	return state
+}
+
+// sendPromptToPane pastes prompt into paneID as one bracketed paste, so
+// multi-line prompts arrive whole, then submits it.
+func sendPromptToPane(paneID, prompt string) error {
+	buffer := fmt.Sprintf("agent-mcp-%d", time.Now().UnixNano())
+	cmd := exec.Command("tmux", "load-buffer", "-b", buffer, "-")
+	cmd.Stdin = strings.NewReader(prompt)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tmux load-buffer: %s", strings.TrimSpace(firstNonEmpty(string(out), err.Error())))
+	}
+	if err := runTmux("paste-buffer", "-d", "-p", "-b", buffer, "-t", paneID); err != nil {
+		return err
+	}
+	return runTmux("send-keys", "-t", paneID, "Enter")
+}
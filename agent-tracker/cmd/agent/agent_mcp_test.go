@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAgentGitState(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	if got := agentGitState(filepath.Join(t.TempDir(), "missing")); got.Error != "repo copy missing" {
+		t.Errorf("missing repo copy = %+v", got)
+	}
+
+	repo := t.TempDir()
+	testGit(t, repo, "init", "-q", "-b", "feature-login")
+	writeAgentTestFile(t, filepath.Join(repo, "main.go"), "package main\n")
+	testGit(t, repo, "add", "main.go")
+	testGit(t, repo, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init")
+	clean := agentGitState(repo)
+	if clean.Error != "" || clean.Branch != "feature-login" || clean.Dirty {
+		t.Errorf("clean repo = %+v, want clean feature-login", clean)
+	}
+
+	writeAgentTestFile(t, filepath.Join(repo, "main.go"), "package main\n\nfunc main() {}\n")
+	writeAgentTestFile(t, filepath.Join(repo, "new.go"), "package main\n")
+	dirty := agentGitState(repo)
+	if !dirty.Dirty || !slices.Equal(dirty.ChangedFiles, []string{"M main.go", "?? new.go"}) {
+		t.Errorf("dirty repo = %+v, want main.go modified and new.go untracked", dirty)
+	}
+}
+
+func TestAgentMCPRecord(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeAgentTestFile(t, registryPath(), `{"agents":{"login-page":{"id":"login-page","branch":"login-page"}}}`)
+	record, err := agentMCPRecord(" Login Page ")
+	if err != nil || record.ID != "login-page" {
+		t.Fatalf("agentMCPRecord = %+v, %v; want login-page", record, err)
+	}
+	if _, err := agentMCPRecord("checkout"); err == nil || !strings.Contains(err.Error(), "unknown agent") {
+		t.Errorf("agentMCPRecord(checkout) error = %v, want unknown agent", err)
+	}
+}
+
+func testGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+}
+
+func writeAgentTestFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
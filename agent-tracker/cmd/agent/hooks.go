@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// hookSummaryLimit caps summaries and notes taken from prompts and replies.
+const hookSummaryLimit = 600
+
+// hookAction is one tracker command an agent event turns into. Best-effort
+// actions, like heartbeats for a pane without a task, may fail quietly.
+type hookAction struct {
+	command    string
+	text       string
+	bestEffort bool
+}
+
+// runHook turns an agent's hook or notify payload into tracker commands for
+// the pane the agent runs in. The payload is read from stdin, or taken from
+// the last argument the way Codex passes its notify JSON.
+func runHook(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: agent hook <claude|codex|opencode> [payload]")
+	}
+	var payload []byte
+	if len(args) > 1 {
+		payload = []byte(args[len(args)-1])
+	} else {
+		data, err := io.ReadAll(io.LimitReader(os.Stdin, 4<<20))
+		if err != nil {
+			return err
+		}
+		payload = data
+	}
+	var actions []hookAction
+	var err error
+	switch args[0] {
+	case "claude":
+		actions, err = claudeHookActions(payload)
+	case "codex":
+		actions, err = codexHookActions(payload)
+	case "opencode":
+		actions, err = opencodeHookActions(payload)
+	default:
+		return fmt.Errorf("unknown hook source: %s", args[0])
+	}
+	if err != nil || len(actions) == 0 {
+		return err
+	}
+	// Outside tmux there is no pane to track, and falling back to the
+	// attached client's pane would credit the wrong one.
+	pane := strings.TrimSpace(os.Getenv("TMUX_PANE"))
+	if pane == "" {
+		return nil
+	}
+	ctx, err := resolveTrackerContext("", "", "", "", pane)
+	if err != nil {
+		return err
+	}
+	for _, action := range actions {
+		env := &ipc.Envelope{
+			Session:   ctx.SessionName,
+			SessionID: ctx.SessionID,
+			Window:    ctx.WindowName,
+			WindowID:  ctx.WindowID,
+			Pane:      ctx.PaneID,
+			Summary:   action.text,
+		}
+		if err := sendTrackerCommand(action.command, env); err != nil && !action.bestEffort {
+			return fmt.Errorf("%s: %w", action.command, err)
+		}
+	}
+	return nil
+}
+
+type claudeHookPayload struct {
+	Event            string `json:"hook_event_name"`
+	Prompt           string `json:"prompt"`
+	ToolName         string `json:"tool_name"`
+	Message          string `json:"message"`
+	NotificationType string `json:"notification_type"`
+	TranscriptPath   string `json:"transcript_path"`
+}
+
+// claudeHookActions maps Claude Code hook events: a submitted prompt starts a
+// task, a permission request waits for the user, tool results count as
+// progress, and Stop finishes with the last reply as the note.
+func claudeHookActions(payload []byte) ([]hookAction, error) {
+	var event claudeHookPayload
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid claude hook payload: %w", err)
+	}
+	switch event.Event {
+	case "UserPromptSubmit":
+		return []hookAction{{command: "start_task", text: firstNonEmpty(hookSummary(event.Prompt), "working...")}}, nil
+	case "PermissionRequest":
+		return []hookAction{{command: "wait_for_input", text: "Approval needed: " + firstNonEmpty(event.ToolName, "tool")}}, nil
+	case "PostToolUse":
+		return []hookAction{
+			{command: "resume_task", bestEffort: true},
+			{command: "heartbeat", bestEffort: true},
+		}, nil
+	case "Notification":
+		message := hookSummary(event.Message)
+		switch {
+		case event.NotificationType == "idle_prompt":
+			// Stop has already finished the task.
+			return nil, nil
+		case event.NotificationType == "permission_prompt" || strings.Contains(strings.ToLower(message), "permission"):
+			return []hookAction{{command: "wait_for_input", text: message}}, nil
+		case message != "":
+			return []hookAction{{command: "notify", text: message}}, nil
+		}
+		return nil, nil
+	case "Stop":
+		return []hookAction{{command: "finish_task", text: hookSummary(claudeLastReply(event.TranscriptPath))}}, nil
+	}
+	return nil, nil
+}
+
+// claudeLastReply returns the text of the last assistant message in a Claude
+// Code transcript, or "" when it cannot be read.
+func claudeLastReply(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return ""
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	last := ""
+	for scanner.Scan() {
+		var entry struct {
+			Type    string `json:"type"`
+			Message struct {
+				Content json.RawMessage `json:"content"`
+			} `json:"message"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.Type != "assistant" {
+			continue
+		}
+		var parts []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(entry.Message.Content, &parts) != nil {
+			continue
+		}
+		var texts []string
+		for _, part := range parts {
+			if part.Type == "text" && strings.TrimSpace(part.Text) != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+		if len(texts) > 0 {
+			last = strings.Join(texts, "\n")
+		}
+	}
+	return last
+}
+
+type codexNotifyPayload struct {
+	Type          string   `json:"type"`
+	LastMessage   string   `json:"last-assistant-message"`
+	InputMessages []string `json:"input-messages"`
+}
+
+// codexHookActions maps Codex notify events. Codex reports nothing when a
+// turn starts, so a finished turn also sets the summary from the prompt.
+func codexHookActions(payload []byte) ([]hookAction, error) {
+	var event codexNotifyPayload
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid codex notify payload: %w", err)
+	}
+	switch event.Type {
+	case "agent-turn-complete":
+		var actions []hookAction
+		if n := len(event.InputMessages); n > 0 {
+			if summary := hookSummary(event.InputMessages[n-1]); summary != "" {
+				actions = append(actions, hookAction{command: "update_task", text: summary, bestEffort: true})
+			}
+		}
+		return append(actions, hookAction{command: "finish_task", text: hookSummary(event.LastMessage)}), nil
+	case "approval-requested":
+		return []hookAction{{command: "wait_for_input", text: "Approval needed"}}, nil
+	}
+	return nil, nil
+}
+
+// opencodeHookPayload is an opencode plugin event as forwarded by
+// opencode/tui-plugins/tracker-notify.ts, with Text set to the prompt or
+// reply the plugin looked up for it.
+type opencodeHookPayload struct {
+	Type       string `json:"type"`
+	Text       string `json:"text"`
+	Properties struct {
+		Title  string `json:"title"`
+		Status struct {
+			Type string `json:"type"`
+		} `json:"status"`
+	} `json:"properties"`
+}
+
+func opencodeHookActions(payload []byte) ([]hookAction, error) {
+	var event opencodeHookPayload
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid opencode event: %w", err)
+	}
+	text := hookSummary(event.Text)
+	switch event.Type {
+	case "session.status":
+		switch event.Properties.Status.Type {
+		case "busy":
+			return []hookAction{{command: "start_task", text: firstNonEmpty(text, "working...")}}, nil
+		case "idle":
+			return []hookAction{{command: "finish_task", text: firstNonEmpty(text, "done")}}, nil
+		}
+	case "permission.asked", "permission.updated":
+		return []hookAction{{command: "wait_for_input", text: "Approval needed: " + firstNonEmpty(hookSummary(event.Properties.Title), "tool")}}, nil
+	case "question.asked":
+		return []hookAction{{command: "wait_for_input", text: firstNonEmpty(text, "Question pending")}}, nil
+	case "permission.replied", "question.replied", "question.rejected":
+		return []hookAction{{command: "resume_task", bestEffort: true}}, nil
+	}
+	return nil, nil
+}
+
+func hookSummary(text string) string {
+	text = strings.TrimSpace(text)
+	if runes := []rune(text); len(runes) > hookSummaryLimit {
+		text = strings.TrimSpace(string(runes[:hookSummaryLimit])) + "…"
+	}
+	return text
+}
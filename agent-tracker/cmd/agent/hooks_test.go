@@ -0,0 +1,253 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestClaudeHookActions(t *testing.T) {
+	transcript := filepath.Join(t.TempDir(), "00893aaf-19fa-41d2-8238-13269b9b3ca0.jsonl")
+	writeAgentTestFile(t, transcript, `{"parentUuid":null,"isSidechain":false,"type":"user","message":{"role":"user","content":"Fix the login redirect"},"uuid":"a1","timestamp":"2026-03-01T09:00:00.000Z"}
+{"parentUuid":"a1","type":"assistant","message":{"id":"msg_01","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"Fixed the redirect in auth.go."}],"stop_reason":"end_turn"},"uuid":"a2"}
+`)
+	tests := []struct {
+		name    string
+		payload string
+		want    []hookAction
+	}{
+		{
+			name:    "prompt submitted",
+			payload: `{"session_id":"abc123","transcript_path":"/home/me/.claude/projects/-home-me-app/abc123.jsonl","cwd":"/home/me/app","permission_mode":"default","hook_event_name":"UserPromptSubmit","prompt":"  Fix the login redirect\n"}`,
+			want:    []hookAction{{command: "start_task", text: "Fix the login redirect"}},
+		},
+		{
+			name:    "empty prompt",
+			payload: `{"session_id":"abc123","hook_event_name":"UserPromptSubmit","prompt":""}`,
+			want:    []hookAction{{command: "start_task", text: "working..."}},
+		},
+		{
+			name:    "permission request",
+			payload: `{"session_id":"abc123","hook_event_name":"PermissionRequest","tool_name":"Bash","tool_input":{"command":"rm -rf node_modules","description":"Remove node_modules"}}`,
+			want:    []hookAction{{command: "wait_for_input", text: "Approval needed: Bash"}},
+		},
+		{
+			name:    "tool finished",
+			payload: `{"session_id":"abc123","hook_event_name":"PostToolUse","tool_name":"Write","tool_input":{"file_path":"/home/me/app/auth.go","content":"package auth"},"tool_response":{"filePath":"/home/me/app/auth.go","success":true}}`,
+			want:    []hookAction{{command: "resume_task", bestEffort: true}, {command: "heartbeat", bestEffort: true}},
+		},
+		{
+			name:    "permission notification",
+			payload: `{"session_id":"abc123","hook_event_name":"Notification","message":"Claude needs your permission to use Bash","notification_type":"permission_prompt"}`,
+			want:    []hookAction{{command: "wait_for_input", text: "Claude needs your permission to use Bash"}},
+		},
+		{
+			name:    "permission notification without a type",
+			payload: `{"session_id":"abc123","hook_event_name":"Notification","message":"Claude needs your permission to use WebFetch"}`,
+			want:    []hookAction{{command: "wait_for_input", text: "Claude needs your permission to use WebFetch"}},
+		},
+		{
+			name:    "idle notification",
+			payload: `{"session_id":"abc123","hook_event_name":"Notification","message":"Claude is waiting for your input","notification_type":"idle_prompt"}`,
+		},
+		{
+			name:    "other notification",
+			payload: `{"session_id":"abc123","hook_event_name":"Notification","message":"Context is almost full"}`,
+			want:    []hookAction{{command: "notify", text: "Context is almost full"}},
+		},
+		{
+			name:    "stop with transcript",
+			payload: `{"session_id":"abc123","hook_event_name":"Stop","stop_hook_active":false,"transcript_path":"` + transcript + `"}`,
+			want:    []hookAction{{command: "finish_task", text: "Fixed the redirect in auth.go."}},
+		},
+		{
+			name:    "stop without transcript",
+			payload: `{"session_id":"abc123","hook_event_name":"Stop","transcript_path":"/nowhere.jsonl"}`,
+			want:    []hookAction{{command: "finish_task"}},
+		},
+		{
+			name:    "unhandled event",
+			payload: `{"session_id":"abc123","hook_event_name":"SessionStart","source":"startup"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := claudeHookActions([]byte(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("actions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if _, err := claudeHookActions([]byte("not json")); err == nil {
+		t.Error("invalid payload accepted")
+	}
+}
+
+func TestClaudeLastReply(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name       string
+		transcript string
+		want       string
+	}{
+		{
+			name: "last assistant text wins",
+			transcript: `{"type":"user","message":{"role":"user","content":"Add tests"}}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Looking at the code."}]}}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"toolu_01","name":"Bash","input":{"command":"go test ./..."}}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_01","content":"ok"}]}}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"thinking","thinking":"done"},{"type":"text","text":"Tests pass."},{"type":"text","text":"Added three cases."}]}}
+{"type":"system","subtype":"stop_hook_summary"}
+`,
+			want: "Tests pass.\nAdded three cases.",
+		},
+		{
+			name: "malformed lines are skipped",
+			transcript: `{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Done."}]}}
+{"type":"assistant","message":
+`,
+			want: "Done.",
+		},
+		{
+			name:       "no assistant reply",
+			transcript: `{"type":"user","message":{"role":"user","content":"hello"}}` + "\n",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.Repeat("t", i+1)+".jsonl")
+			writeAgentTestFile(t, path, tt.transcript)
+			if got := claudeLastReply(path); got != tt.want {
+				t.Errorf("claudeLastReply = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := claudeLastReply(" "); got != "" {
+		t.Errorf("claudeLastReply without a path = %q", got)
+	}
+}
+
+func TestCodexHookActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []hookAction
+	}{
+		{
+			name:    "turn complete",
+			payload: `{"type":"agent-turn-complete","thread-id":"b5f6c1c2-1111-2222-3333-444455556666","turn-id":"12345","cwd":"/home/me/app","input-messages":["Look around","Rename foo to bar and update the callsites."],"last-assistant-message":"Rename complete and verified cargo build succeeds."}`,
+			want: []hookAction{
+				{command: "update_task", text: "Rename foo to bar and update the callsites.", bestEffort: true},
+				{command: "finish_task", text: "Rename complete and verified cargo build succeeds."},
+			},
+		},
+		{
+			name:    "turn complete without input",
+			payload: `{"type":"agent-turn-complete","thread-id":"b5f6c1c2","turn-id":"1","input-messages":[],"last-assistant-message":"Done"}`,
+			want:    []hookAction{{command: "finish_task", text: "Done"}},
+		},
+		{
+			name:    "approval requested",
+			payload: `{"type":"approval-requested","thread-id":"b5f6c1c2","turn-id":"2"}`,
+			want:    []hookAction{{command: "wait_for_input", text: "Approval needed"}},
+		},
+		{
+			name:    "unknown type",
+			payload: `{"type":"session-configured"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := codexHookActions([]byte(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("actions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if _, err := codexHookActions([]byte("{")); err == nil {
+		t.Error("invalid payload accepted")
+	}
+}
+
+func TestOpencodeHookActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []hookAction
+	}{
+		{
+			name:    "busy",
+			payload: `{"type":"session.status","properties":{"sessionID":"ses_3f2a","status":{"type":"busy"}},"text":"Fix the flaky test"}`,
+			want:    []hookAction{{command: "start_task", text: "Fix the flaky test"}},
+		},
+		{
+			name:    "busy without prompt",
+			payload: `{"type":"session.status","properties":{"sessionID":"ses_3f2a","status":{"type":"busy"}}}`,
+			want:    []hookAction{{command: "start_task", text: "working..."}},
+		},
+		{
+			name:    "idle",
+			payload: `{"type":"session.status","properties":{"sessionID":"ses_3f2a","status":{"type":"idle"}},"text":"The test now waits for the server."}`,
+			want:    []hookAction{{command: "finish_task", text: "The test now waits for the server."}},
+		},
+		{
+			name:    "retry status",
+			payload: `{"type":"session.status","properties":{"sessionID":"ses_3f2a","status":{"type":"retry","attempt":1}}}`,
+		},
+		{
+			name:    "permission asked",
+			payload: `{"type":"permission.asked","properties":{"id":"per_9a1","sessionID":"ses_3f2a","type":"bash","title":"npm test","metadata":{}}}`,
+			want:    []hookAction{{command: "wait_for_input", text: "Approval needed: npm test"}},
+		},
+		{
+			name:    "question asked",
+			payload: `{"type":"question.asked","properties":{"sessionID":"ses_3f2a"},"text":"Which database?"}`,
+			want:    []hookAction{{command: "wait_for_input", text: "Which database?"}},
+		},
+		{
+			name:    "permission replied",
+			payload: `{"type":"permission.replied","properties":{"sessionID":"ses_3f2a","permissionID":"per_9a1","response":"once"}}`,
+			want:    []hookAction{{command: "resume_task", bestEffort: true}},
+		},
+		{
+			name:    "unrelated event",
+			payload: `{"type":"message.part.updated","properties":{}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := opencodeHookActions([]byte(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("actions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHookSummary(t *testing.T) {
+	long := strings.Repeat("é", hookSummaryLimit+10)
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "  done \n", want: "done"},
+		{text: "", want: ""},
+		{text: strings.Repeat("a", hookSummaryLimit), want: strings.Repeat("a", hookSummaryLimit)},
+		{text: long, want: strings.Repeat("é", hookSummaryLimit) + "…"},
+	}
+	for _, tt := range tests {
+		if got := hookSummary(tt.text); got != tt.want {
+			t.Errorf("hookSummary(%d runes) = %d runes, want %d", len([]rune(tt.text)), len([]rune(got)), len([]rune(tt.want)))
+		}
+	}
+}
@@ -54,6 +54,7 @@ type agentRecord struct {
 	UpdatedAt       time.Time  `json:"updated_at"`
 	LastFocusedAt   *time.Time `json:"last_focused_at,omitempty"`
 	LaunchWindowID  string     `json:"-"`
+	LaunchDetached  bool       `json:"-"`
 }
 
 type agentPanes struct {
@@ -67,10 +68,23 @@ type agentStartOptions struct {
 	KeepWorktree bool
 }
 
+// agentStartRequest is everything `agent start` and the start_agent MCP tool
+// can ask for. Empty fields take the repo defaults.
+type agentStartRequest struct {
+	Feature      string
+	Device       string
+	NoDevice     bool
+	KeepWorktree bool
+	SourceBranch string
+	Prompt       string
+	Detached     bool
+}
+
 type appConfig struct {
-	Keys        keyConfig          `json:"keys"`
-	Devices     []string           `json:"devices,omitempty"`
-	StatusRight *statusRightConfig `json:"status_right,omitempty"`
+	Keys        keyConfig             `json:"keys"`
+	Devices     []string              `json:"devices,omitempty"`
+	StatusRight *statusRightConfig    `json:"status_right,omitempty"`
+	UsagePrices map[string]usagePrice `json:"usage_prices,omitempty"`
 }
 
 type statusRightConfig struct {
@@ -159,7 +173,7 @@ func main() {
 
 func run(args []string) error {
 	if len(args) == 0 {
-		return fmt.Errorf("usage: agent <start|resume|list|destroy|init|config|setup|tmux|tracker|browser|feature>")
+		return fmt.Errorf("usage: agent <start|resume|list|destroy|init|config|setup|tmux|tracker|browser|feature|mcp|hook|usage>")
 	}
 	switch args[0] {
 	case "start":
@@ -188,6 +202,12 @@ func run(args []string) error {
 		return runFeatureCommand(args[1:])
 	case "bootstrap":
 		return runBootstrap(args[1:])
+	case "mcp":
+		return runAgentMCP(args[1:])
+	case "hook":
+		return runHook(args[1:])
+	case "usage":
+		return runUsage(args[1:])
 	default:
 		return fmt.Errorf("unknown subcommand: %s", args[0])
 	}
@@ -195,14 +215,11 @@ func run(args []string) error {
 
 func runStart(args []string) error {
 	fs := flag.NewFlagSet("agent start", flag.ContinueOnError)
-	var feature string
-	var device string
-	var noDevice bool
-	var keepWorktree bool
-	fs.StringVar(&feature, "name", "", "feature name")
-	fs.StringVar(&device, "d", "", "flutter device")
-	fs.BoolVar(&noDevice, "no-device", false, "leave the run pane idle until a device is chosen")
-	fs.BoolVar(&keepWorktree, "keep-worktree", false, "copy the current repo worktree into the new agent")
+	var req agentStartRequest
+	fs.StringVar(&req.Feature, "name", "", "feature name")
+	fs.StringVar(&req.Device, "d", "", "flutter device")
+	fs.BoolVar(&req.NoDevice, "no-device", false, "leave the run pane idle until a device is chosen")
+	fs.BoolVar(&req.KeepWorktree, "keep-worktree", false, "copy the current repo worktree into the new agent")
 	fs.SetOutput(os.Stderr)
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -211,45 +228,54 @@ func runStart(args []string) error {
 	if err != nil {
 		return fmt.Errorf("%w; run `agent init` in your repo to set up agent config", err)
 	}
-	isFlutter := fileExists(filepath.Join(repoRoot, "pubspec.yaml"))
-	if err := ensureGitExcludeEntries(repoRoot, []string{".agents"}); err != nil {
-		return err
+	if req.Feature == "" && fs.NArg() > 0 {
+		req.Feature = fs.Arg(0)
 	}
-	repoCfg, err := loadRepoConfig(repoRoot)
-	if err != nil {
-		return err
-	}
-	if feature == "" && fs.NArg() > 0 {
-		feature = fs.Arg(0)
-	}
-	feature = sanitizeFeatureName(feature)
-	if feature == "" {
+	req.Feature = sanitizeFeatureName(req.Feature)
+	if req.Feature == "" {
 		value, err := promptInput("Feature name: ")
 		if err != nil {
 			return err
 		}
-		feature = sanitizeFeatureName(value)
+		req.Feature = sanitizeFeatureName(value)
+	}
+	_, err = startAgent(repoRoot, req)
+	return err
+}
+
+// startAgent creates the workspace for a new agent in repoRoot, starts its
+// bootstrap and opens its tmux window.
+func startAgent(repoRoot string, req agentStartRequest) (*agentRecord, error) {
+	feature := sanitizeFeatureName(req.Feature)
+	device := req.Device
+	isFlutter := fileExists(filepath.Join(repoRoot, "pubspec.yaml"))
+	if err := ensureGitExcludeEntries(repoRoot, []string{".agents"}); err != nil {
+		return nil, err
+	}
+	repoCfg, err := loadRepoConfig(repoRoot)
+	if err != nil {
+		return nil, err
 	}
 	if feature == "" {
-		return fmt.Errorf("feature name is required")
+		return nil, fmt.Errorf("feature name is required")
 	}
 
 	reg, err := loadRegistry()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if _, exists := reg.Agents[feature]; exists {
-		return fmt.Errorf("agent %q already exists", feature)
+		return nil, fmt.Errorf("agent %q already exists", feature)
 	}
 
 	workspaceRoot := filepath.Join(repoRoot, ".agents", feature)
 	repoCopyPath := filepath.Join(workspaceRoot, "repo")
 	featureConfigPath := filepath.Join(workspaceRoot, "agent.json")
 	if err := os.MkdirAll(filepath.Join(workspaceRoot, "logs"), 0o755); err != nil {
-		return err
+		return nil, err
 	}
 	if err := os.MkdirAll(repoCopyPath, 0o755); err != nil {
-		return err
+		return nil, err
 	}
 	port := 0
 	url := ""
@@ -258,7 +284,7 @@ func runStart(args []string) error {
 	device = strings.TrimSpace(device)
 	if isFlutter {
 		runtime = "flutter"
-		if noDevice {
+		if req.NoDevice {
 			device = ""
 		} else if device == "" {
 			device = resolveDefaultDevice(repoCfg)
@@ -266,7 +292,7 @@ func runStart(args []string) error {
 		browserEnabled = device == "web-server"
 		port, err = allocatePort(repoRoot, 9100)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		url = fmt.Sprintf("http://localhost:%d", port)
 		if err := saveFeatureConfig(featureConfigPath, featureConfig{
@@ -277,21 +303,24 @@ func runStart(args []string) error {
 			IsFlutter: true,
 			Ready:     false,
 		}); err != nil {
-			return err
+			return nil, err
 		}
 		if device == "web-server" {
 			if _, err := ensureChromeForTestingAvailable(); err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
-	sourceBranch := resolveStartSourceBranch(repoRoot, repoCfg)
+	sourceBranch := strings.TrimSpace(req.SourceBranch)
+	if sourceBranch == "" {
+		sourceBranch = resolveStartSourceBranch(repoRoot, repoCfg)
+	}
 	if err := prepareAgentContext(repoRoot, repoCopyPath, repoCfg.AgentKeyPaths, false); err != nil {
-		return err
+		return nil, err
 	}
 	if isFlutter {
 		if err := writeFlutterHelperScripts(workspaceRoot, repoCopyPath, url, device); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -303,7 +332,7 @@ func runStart(args []string) error {
 		RepoCopyPath:   repoCopyPath,
 		Branch:         feature,
 		SourceBranch:   sourceBranch,
-		KeepWorktree:   keepWorktree,
+		KeepWorktree:   req.KeepWorktree,
 		Runtime:        runtime,
 		Device:         device,
 		FeatureConfig:  featureConfigPath,
@@ -314,17 +343,18 @@ func runStart(args []string) error {
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 		LaunchWindowID: strings.TrimSpace(os.Getenv("AGENT_TMUX_TARGET_WINDOW")),
+		LaunchDetached: req.Detached,
 	}
 	reg.Agents[record.ID] = record
 	if err := saveRegistry(reg); err != nil {
-		return err
+		return nil, err
 	}
 	bootstrapPID, err := spawnWorkspaceBootstrap(workspaceRoot)
 	if err != nil {
 		delete(reg.Agents, record.ID)
 		_ = saveRegistry(reg)
 		_ = os.RemoveAll(workspaceRoot)
-		return err
+		return nil, err
 	}
 
 	if err := launchAgentLayout(record); err != nil {
@@ -332,10 +362,10 @@ func runStart(args []string) error {
 		delete(reg.Agents, record.ID)
 		_ = saveRegistry(reg)
 		_ = os.RemoveAll(workspaceRoot)
-		return err
+		return nil, err
 	}
-	_ = primeAgentAIPane(record.Panes.AI)
-	return nil
+	_ = primeAgentAIPane(record.Panes.AI, req.Prompt)
+	return record, nil
 }
 
 func runInit(args []string) error {
@@ -895,8 +925,9 @@ func registryRecordForWorkspace(reg *registry, repoRoot, workspaceRoot, featureP
 
 func runList(args ...string) error {
 	fs := flag.NewFlagSet("agent list", flag.ContinueOnError)
-	var showAll bool
+	var showAll, showUsage bool
 	fs.BoolVar(&showAll, "all", false, "show agents across all repos")
+	fs.BoolVar(&showUsage, "usage", false, "add a column with each agent's token use and cost")
 	fs.SetOutput(os.Stderr)
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -915,16 +946,34 @@ func runList(args ...string) error {
 		}
 	}
 
+	var records []*agentRecord
 	for _, id := range sortedAgentIDs(reg) {
 		record := reg.Agents[id]
 		if repoScope != "" && filepath.Clean(record.RepoRoot) != filepath.Clean(repoScope) {
 			continue
 		}
+		records = append(records, record)
+	}
+	var ledger *usageLedger
+	if showUsage {
+		// Usage is informational; unreadable transcripts leave the column
+		// empty.
+		ledger, _ = collectUsage(records)
+	}
+	for _, record := range records {
 		state := "stopped"
 		if windowAlive(record.TmuxSessionID, record.TmuxWindowID) {
 			state = "running"
 		}
-		fmt.Printf("%s\t%s\t%s\n", id, state, record.RepoCopyPath)
+		if !showUsage {
+			fmt.Printf("%s\t%s\t%s\n", record.ID, state, record.RepoCopyPath)
+			continue
+		}
+		usage := ""
+		if ledger != nil {
+			usage = formatUsageSummary(ledger.agentTotal(record.ID))
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n", record.ID, state, record.RepoCopyPath, usage)
 	}
 	return nil
 }
@@ -956,6 +1005,13 @@ func runDestroy(args []string) error {
 	if target.RequiresExplicitConfirm && strings.TrimSpace(confirmText) != "destroy" {
 		return fmt.Errorf("agent has uncommitted changes; rerun with --confirm destroy")
 	}
+	return destroyAgent(target)
+}
+
+// destroyAgent removes an agent found by loadDestroyTarget: its registry
+// entry, bootstrap, workspace and tmux window. Callers enforce the confirm
+// rule first.
+func destroyAgent(target destroyTarget) error {
 	reg := target.Reg
 	record := target.Record
 	windowID := target.WindowID
@@ -963,6 +1019,7 @@ func runDestroy(args []string) error {
 	if record.URL != "" {
 		_ = closeChromeTab(record.URL)
 	}
+	agentID := record.ID
 	delete(reg.Agents, agentID)
 	if reg.FocusedAgentID == agentID {
 		reg.FocusedAgentID = ""
@@ -1277,11 +1334,13 @@ func runTmuxOnFocus(args []string) error {
 	if !tmuxWindowIsActive(sessionID, windowID) {
 		return nil
 	}
+	if strings.TrimSpace(paneID) != "" {
+		_ = trackerReportVisit(sessionID, windowID, paneID)
+	}
 	ctx, err := detectCurrentAgentFromTmux(windowID)
 	if err != nil {
 		return nil
 	}
-	_ = paneID
 	reg, err := loadRegistry()
 	if err != nil {
 		return err
@@ -1494,7 +1553,7 @@ func launchAgentLayout(record *agentRecord) (err error) {
 	previousWindowID := currentTmuxWindowID()
 	cleanupWindow := false
 	windowID := ""
-	windowID, sessionID, sessionName, attachAfter, err := createWindow(record.Name, record.RepoCopyPath, record.LaunchWindowID)
+	windowID, sessionID, sessionName, attachAfter, err := createWindow(record.Name, record.RepoCopyPath, record.LaunchWindowID, record.LaunchDetached)
 	if err != nil {
 		return err
 	}
@@ -1560,6 +1619,10 @@ func launchAgentLayout(record *agentRecord) (err error) {
 			return err
 		}
 	}
+	if record.LaunchDetached {
+		cleanupWindow = false
+		return nil
+	}
 	if attachAfter && canAttachTmux() {
 		if err := runTmux("attach-session", "-t", sessionID); err != nil {
 			return err
@@ -1577,7 +1640,9 @@ func launchAgentLayout(record *agentRecord) (err error) {
 	return nil
 }
 
-func primeAgentAIPane(paneID string) error {
+// primeAgentAIPane starts the coding agent in the AI pane, with prompt as its
+// first message when one is given.
+func primeAgentAIPane(paneID, prompt string) error {
 	paneID = strings.TrimSpace(paneID)
 	if paneID == "" {
 		return nil
@@ -1596,7 +1661,11 @@ func primeAgentAIPane(paneID string) error {
 		}
 		time.Sleep(100 * time.Millisecond)
 	}
-	if err := runTmux("send-keys", "-t", paneID, "-l", "op"); err != nil {
+	command := "op"
+	if prompt = strings.TrimSpace(prompt); prompt != "" {
+		command += " --prompt " + shellQuote(prompt)
+	}
+	if err := runTmux("send-keys", "-t", paneID, "-l", command); err != nil {
 		return err
 	}
 	return runTmux("send-keys", "-t", paneID, "Enter")
@@ -1632,12 +1701,12 @@ func canAttachTmux() bool {
 	return info.Mode()&os.ModeCharDevice != 0
 }
 
-func createWindow(feature, path string, targetWindowID string) (windowID, sessionID, sessionName string, attachAfter bool, err error) {
+func createWindow(feature, path string, targetWindowID string, detached bool) (windowID, sessionID, sessionName string, attachAfter bool, err error) {
 	targetWindowID = preferredNewWindowTarget(targetWindowID, os.Getenv("TMUX") != "", currentTmuxWindowID())
 	if targetWindowID != "" {
 		targetSessionID, targetSessionName, resolveErr := tmuxSessionForWindow(targetWindowID)
 		if resolveErr == nil && targetSessionID != "" {
-			windowID, err = runTmuxOutput(positionedNewWindowArgs(feature, path, targetWindowID)...)
+			windowID, err = runTmuxOutput(positionedNewWindowArgs(feature, path, targetWindowID, detached)...)
 			if err != nil {
 				return "", "", "", false, err
 			}
@@ -1645,7 +1714,7 @@ func createWindow(feature, path string, targetWindowID string) (windowID, sessio
 		}
 	}
 	if os.Getenv("TMUX") != "" {
-		windowID, err = runTmuxOutput(positionedNewWindowArgs(feature, path, "")...)
+		windowID, err = runTmuxOutput(positionedNewWindowArgs(feature, path, "", detached)...)
 		if err != nil {
 			return "", "", "", false, err
 		}
@@ -1660,7 +1729,11 @@ func createWindow(feature, path string, targetWindowID string) (windowID, sessio
 		desiredSessionLabel = "agents"
 	}
 	if existingSessionID, existingSessionName, ok := findTmuxSessionByLabel(desiredSessionLabel); ok {
-		windowID, err = runTmuxOutput("new-window", "-P", "-F", "#{window_id}", "-t", existingSessionID, "-n", feature, "-c", path)
+		newWindowArgs := []string{"new-window", "-P", "-F", "#{window_id}", "-t", existingSessionID, "-n", feature, "-c", path}
+		if detached {
+			newWindowArgs = append(newWindowArgs, "-d")
+		}
+		windowID, err = runTmuxOutput(newWindowArgs...)
 		if err != nil {
 			return "", "", "", false, err
 		}
@@ -1699,8 +1772,11 @@ func preferredNewWindowTarget(targetWindowID string, inTmux bool, currentWindowI
 	return strings.TrimSpace(currentWindowID)
 }
 
-func positionedNewWindowArgs(feature, path, targetWindowID string) []string {
+func positionedNewWindowArgs(feature, path, targetWindowID string, detached bool) []string {
 	args := []string{"new-window", "-P", "-F", "#{window_id}"}
+	if detached {
+		args = append(args, "-d")
+	}
 	if targetWindowID = strings.TrimSpace(targetWindowID); targetWindowID != "" {
 		args = append(args, "-a", "-t", targetWindowID)
 	}
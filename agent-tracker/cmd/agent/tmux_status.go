@@ -13,6 +13,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
 )
 
 const (
@@ -75,6 +77,9 @@ var statusNow = time.Now
 var statusHostname = os.Hostname
 var statusDetectCurrentAgentFromTmux = detectCurrentAgentFromTmux
 var statusLoadRegistry = loadRegistry
+var statusLoadTrackerState = func(windowID string) (*ipc.Envelope, error) {
+	return trackerLoadState("", &ipc.Filter{WindowID: windowID})
+}
 var statusMemoryCachePath = func() string { return "/tmp/tmux-mem-usage.json" }
 var statusMemoryCacheRefreshScript = func() string {
 	return filepath.Join(os.Getenv("HOME"), ".config", "tmux", "tmux-status", "mem_usage_cache.py")
@@ -193,7 +198,12 @@ func renderTmuxRightStatus(args tmuxRightStatusArgs) string {
 	}
 	if statusRightModuleEnabled(statusRightModuleAgent) {
 		if label := loadAgentStatusLabel(args.WindowID); label != "" {
-			segments = append(segments, statusSegment{FG: "#1d1f21", BG: "#81a1c1", Text: label, Bold: true})
+			bg := "#81a1c1"
+			if badge, color := loadAgentTrackerBadge(args.WindowID); badge != "" {
+				label = strings.TrimRight(label, " ") + " " + badge + " "
+				bg = color
+			}
+			segments = append(segments, statusSegment{FG: "#1d1f21", BG: bg, Text: label, Bold: true})
 		}
 	}
 	if statusRightModuleEnabled(statusRightModuleTodos) {
@@ -532,6 +542,52 @@ func loadAgentStatusLabel(windowID string) string {
 	return fmt.Sprintf(" %s %s ", statusIconAgent, device)
 }
 
+// loadAgentTrackerBadge describes the most urgent tracker task in the window
+// and the segment color that goes with it, followed by the unread mailbox
+// count. Running and settled tasks get no badge.
+func loadAgentTrackerBadge(windowID string) (string, string) {
+	env, err := statusLoadTrackerState(strings.TrimSpace(windowID))
+	if err != nil {
+		return "", ""
+	}
+	badge, color := agentTaskBadge(env.Tasks)
+	if unread := len(env.Messages); unread > 0 {
+		badge = strings.TrimSpace(badge + " ✉ " + strconv.Itoa(unread))
+		if color == "" {
+			color = "#b48ead"
+		}
+	}
+	return badge, color
+}
+
+func agentTaskBadge(tasks []ipc.Task) (string, string) {
+	if len(tasks) == 0 {
+		return "", ""
+	}
+	trackerSortTasks(tasks)
+	for _, task := range tasks {
+		switch task.Status {
+		case trackerTaskStatusWaitingInput:
+			return "? input", "#ebcb8b"
+		case trackerTaskStatusBlocked:
+			return "⊘ blocked", "#d08770"
+		case trackerTaskStatusInProgress:
+			if task.Stalled {
+				return "! stalled", "#d08770"
+			}
+		case trackerTaskStatusFailed:
+			if !task.Acknowledged {
+				return "✗ failed", "#bf616a"
+			}
+		case trackerTaskStatusCompleted:
+			if !task.Acknowledged {
+				return "⚑ review", "#a3be8c"
+			}
+		}
+	}
+	return "", ""
+}
+
 func refreshMemoryUsageCache() {
 	script := statusMemoryCacheRefreshScript()
 	if strings.TrimSpace(script) == "" || !fileExists(script) {
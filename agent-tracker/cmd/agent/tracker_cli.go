@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/david/agent-tracker/internal/ipc"
@@ -20,13 +21,27 @@ type trackerTmuxContext struct {
 
 func runTracker(args []string) error {
 	if len(args) == 0 {
-		return fmt.Errorf("usage: agent tracker <command|state>")
+		return fmt.Errorf("usage: agent tracker <command|state|history|show|report|answer|inbox|progress|checklist>")
 	}
 	switch args[0] {
 	case "command":
 		return runTrackerCommand(args[1:])
 	case "state":
 		return runTrackerState(args[1:])
+	case "history":
+		return runTrackerHistory(args[1:])
+	case "show":
+		return runTrackerShow(args[1:])
+	case "report":
+		return runTrackerReport(args[1:])
+	case "answer":
+		return runTrackerAnswer(args[1:])
+	case "inbox":
+		return runTrackerInbox(args[1:])
+	case "progress":
+		return runTrackerProgress(args[1:])
+	case "checklist":
+		return runTrackerChecklist(args[1:])
 	default:
 		return fmt.Errorf("unknown tracker subcommand: %s", args[0])
 	}
@@ -34,13 +49,14 @@ func runTracker(args []string) error {
 
 func runTrackerCommand(args []string) error {
 	fs := flag.NewFlagSet("agent tracker command", flag.ExitOnError)
-	var client, session, sessionID, window, windowID, pane, summary string
+	var client, session, sessionID, window, windowID, pane, taskID, summary string
 	fs.StringVar(&client, "client", "", "tmux client tty")
 	fs.StringVar(&session, "session", "", "tmux session name")
 	fs.StringVar(&sessionID, "session-id", "", "tmux session id")
 	fs.StringVar(&window, "window", "", "tmux window name")
 	fs.StringVar(&windowID, "window-id", "", "tmux window id")
 	fs.StringVar(&pane, "pane", "", "tmux pane id")
+	fs.StringVar(&taskID, "task-id", "", "task id from start_task or push_task (default: the pane's innermost open task)")
 	fs.StringVar(&summary, "summary", "", "summary or completion note")
 	fs.SetOutput(os.Stderr)
 	if err := fs.Parse(args); err != nil {
@@ -60,6 +76,7 @@ func runTrackerCommand(args []string) error {
 		Window:    strings.TrimSpace(window),
 		WindowID:  strings.TrimSpace(windowID),
 		Pane:      strings.TrimSpace(pane),
+		TaskID:    strings.TrimSpace(taskID),
 		Summary:   strings.TrimSpace(summary),
 	}
 	if env.Summary != "" {
@@ -67,7 +84,8 @@ func runTrackerCommand(args []string) error {
 	}
 	command := strings.TrimSpace(rest[0])
 	switch command {
-	case "start_task", "finish_task", "update_task", "acknowledge", "delete_task", "notify":
+	case "start_task", "push_task", "finish_task", "update_task", "acknowledge", "delete_task", "notify",
+		"wait_for_input", "block_task", "fail_task", "resume_task", "visit", "heartbeat", "mark_read":
 		ctx, err := resolveTrackerContext(env.Session, env.SessionID, env.Window, env.WindowID, env.Pane)
 		if err != nil {
 			return err
@@ -78,18 +96,123 @@ func runTrackerCommand(args []string) error {
 		env.WindowID = ctx.WindowID
 		env.Pane = ctx.PaneID
 	}
-	return sendTrackerCommand(command, &env)
+	reply, err := trackerCommandReply(command, &env)
+	if err != nil {
+		return err
+	}
+	// Print the new task's ID so scripts can finish it by ID later.
+	if reply.TaskID != "" {
+		fmt.Println(reply.TaskID)
+	}
+	return nil
+}
+
+// trackerReportVisit tells tracker-server the pane was on screen, which stops
+// reminders about its finished task.
+func trackerReportVisit(sessionID, windowID, paneID string) error {
+	return sendTrackerCommand("visit", &ipc.Envelope{SessionID: sessionID, WindowID: windowID, Pane: paneID})
+}
+
+// runTrackerAnswer replies to a question an agent asked with ask_user. Without
+// a target it answers the oldest open question.
+func runTrackerAnswer(args []string) error {
+	fs := flag.NewFlagSet("agent tracker answer", flag.ExitOnError)
+	var sessionID, windowID, pane string
+	var list bool
+	fs.StringVar(&sessionID, "session-id", "", "tmux session id")
+	fs.StringVar(&windowID, "window-id", "", "tmux window id")
+	fs.StringVar(&pane, "pane", "", "tmux pane id")
+	fs.BoolVar(&list, "list", false, "list open questions instead of answering")
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	env, err := trackerLoadState("", &ipc.Filter{SessionID: strings.TrimSpace(sessionID), WindowID: strings.TrimSpace(windowID)})
+	if err != nil {
+		return err
+	}
+	open := trackerOpenQuestions(env.Tasks, strings.TrimSpace(pane))
+	if list {
+		for _, task := range open {
+			fmt.Printf("%s  %s / %s  %s\n", task.Pane, task.Session, task.Window, task.Question.Text)
+			for idx, option := range task.Question.Options {
+				fmt.Printf("      %d. %s\n", idx+1, option)
+			}
+		}
+		return nil
+	}
+	answer := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if answer == "" {
+		return fmt.Errorf("usage: agent tracker answer [--pane id] <answer>")
+	}
+	if len(open) == 0 {
+		return fmt.Errorf("no open questions")
+	}
+	task := open[0]
+	if err := trackerAnswerQuestion(task, answer); err != nil {
+		return err
+	}
+	fmt.Printf("Answered %s / %s: %s\n", task.Session, task.Window, task.Question.Text)
+	return nil
+}
+
+// trackerOpenQuestions returns tasks with an unanswered question, oldest
+// question first. A non-empty pane keeps only that pane's task.
+func trackerOpenQuestions(tasks []ipc.Task, pane string) []ipc.Task {
+	var open []ipc.Task
+	for _, task := range tasks {
+		if task.Question == nil || task.Question.AnsweredAt != "" {
+			continue
+		}
+		if pane != "" && task.Pane != pane {
+			continue
+		}
+		open = append(open, task)
+	}
+	sort.SliceStable(open, func(i, j int) bool {
+		return open[i].Question.AskedAt < open[j].Question.AskedAt
+	})
+	return open
+}
+
+func trackerAnswerQuestion(task ipc.Task, answer string) error {
+	env := &ipc.Envelope{
+		SessionID: task.SessionID,
+		WindowID:  task.WindowID,
+		Pane:      task.Pane,
+		TaskID:    task.ID,
+		Message:   answer,
+	}
+	if task.Question != nil {
+		env.Question = &ipc.Question{ID: task.Question.ID}
+	}
+	return sendTrackerCommand("answer", env)
 }
 
 func runTrackerState(args []string) error {
 	fs := flag.NewFlagSet("agent tracker state", flag.ExitOnError)
-	var client string
+	var client, sessionID, windowID, statuses string
+	var unacknowledged bool
 	fs.StringVar(&client, "client", "", "tmux client tty")
+	fs.StringVar(&sessionID, "session-id", "", "only tasks in this tmux session id")
+	fs.StringVar(&windowID, "window-id", "", "only tasks in this tmux window id")
+	fs.StringVar(&statuses, "status", "", "comma-separated task statuses to include")
+	fs.BoolVar(&unacknowledged, "unacknowledged", false, "only tasks not yet acknowledged")
 	fs.SetOutput(os.Stderr)
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
-	env, err := trackerLoadState(client)
+	filter := &ipc.Filter{
+		SessionID:          strings.TrimSpace(sessionID),
+		WindowID:           strings.TrimSpace(windowID),
+		UnacknowledgedOnly: unacknowledged,
+	}
+	for _, status := range strings.Split(statuses, ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	env, err := trackerLoadState(client, filter)
 	if err != nil {
 		return err
 	}
@@ -124,6 +247,9 @@ func resolveTrackerContext(sessionName, sessionID, windowName, windowID, paneID
 			if target == "" {
 				return trackerTmuxContext{}, err
 			}
+			if ctx.SessionID == "" || ctx.WindowID == "" {
+				return trackerTmuxContext{}, fmt.Errorf("unknown tmux pane %s", target)
+			}
 			continue
 		}
 		ctx = ctx.merge(info)
@@ -167,6 +293,9 @@ func detectTrackerTmuxContext(target string) (trackerTmuxContext, error) {
 	if len(parts) != 5 {
 		return trackerTmuxContext{}, fmt.Errorf("unexpected tmux response: %s", strings.TrimSpace(out))
 	}
+	if strings.TrimSpace(parts[4]) == "" {
+		return trackerTmuxContext{}, fmt.Errorf("tmux target %q not found", target)
+	}
 	return trackerTmuxContext{
 		SessionName: strings.TrimSpace(parts[0]),
 		SessionID:   strings.TrimSpace(parts[1]),
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// trackerFeed mirrors the tracker task list and unread messages over a delta
+// subscription. It reconnects on its own and resumes from the last sequence
+// number it applied; while it is disconnected it reports the error instead of
+// the last state it saw.
+type trackerFeed struct {
+	mu       sync.Mutex
+	tasks    map[string]ipc.Task
+	messages []ipc.Message
+	seq      uint64
+	version  uint64
+	loaded   bool
+	err      error
+}
+
+func startTrackerFeed() *trackerFeed {
+	feed := &trackerFeed{tasks: map[string]ipc.Task{}}
+	go feed.run()
+	return feed
+}
+
+func (f *trackerFeed) run() {
+	for {
+		err := f.stream()
+		f.mu.Lock()
+		if f.loaded {
+			err = fmt.Errorf("tracker disconnected: %w", err)
+		}
+		f.loaded = false
+		f.err = err
+		f.version++
+		f.mu.Unlock()
+		time.Sleep(time.Second)
+	}
+}
+
+func (f *trackerFeed) stream() error {
+	conn, err := net.Dial("unix", trackerSocketPath())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	f.mu.Lock()
+	since := f.seq
+	f.mu.Unlock()
+	register := ipc.Envelope{Kind: "ui-register", Mode: ipc.SubscribeModeDelta, Since: since}
+	if err := json.NewEncoder(conn).Encode(&register); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var env ipc.Envelope
+		if err := dec.Decode(&env); err != nil {
+			return err
+		}
+		f.apply(env)
+	}
+}
+
+func (f *trackerFeed) apply(env ipc.Envelope) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch env.Kind {
+	case "snapshot":
+		f.tasks = make(map[string]ipc.Task, len(env.Tasks))
+		for _, task := range env.Tasks {
+			f.tasks[task.ID] = task
+		}
+		f.messages = env.Messages
+	case "task_upsert":
+		if env.Task == nil {
+			return
+		}
+		f.tasks[env.Task.ID] = *env.Task
+	case "task_remove":
+		delete(f.tasks, env.TaskID)
+	case "synced":
+		// A resume with nothing to catch up on gets only this.
+	case "messages":
+		// Mailbox changes carry no sequence number of their own.
+		f.messages = env.Messages
+		f.version++
+		return
+	default:
+		return
+	}
+	f.seq = env.Seq
+	f.loaded = true
+	f.err = nil
+	f.version++
+}
+
+// state returns the mirrored tasks and messages shaped like a "state"
+// envelope and the version it reflects, or the connection error while the
+// feed is not synced.
+func (f *trackerFeed) state() (*ipc.Envelope, uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.loaded {
+		return nil, f.version, f.err
+	}
+	tasks := make([]ipc.Task, 0, len(f.tasks))
+	for _, task := range f.tasks {
+		tasks = append(tasks, task)
+	}
+	return &ipc.Envelope{Kind: "state", Message: trackerStateSummary(tasks), Tasks: tasks, Messages: f.messages}, f.version, nil
+}
+
+func (f *trackerFeed) currentVersion() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.version
+}
+
+func trackerStateSummary(tasks []ipc.Task) string {
+	inProgress := 0
+	waiting := 0
+	for _, t := range tasks {
+		switch {
+		case t.Status == trackerTaskStatusInProgress:
+			inProgress++
+		case trackerTaskNeedsInput(t.Status):
+			waiting++
+		case trackerTaskIsFinal(t.Status):
+			if !t.Acknowledged {
+				waiting++
+			}
+		}
+	}
+	return fmt.Sprintf("Active %d · Waiting %d · %s", inProgress, waiting, time.Now().Format(time.Kitchen))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestTrackerFeedApply(t *testing.T) {
+	task := func(id, summary string) ipc.Task { return ipc.Task{ID: id, Summary: summary} }
+	tests := []struct {
+		name     string
+		events   []ipc.Envelope
+		want     map[string]string
+		messages int
+		loaded   bool
+	}{
+		{
+			name: "nothing received",
+			want: map[string]string{},
+		},
+		{
+			name:     "snapshot replaces everything",
+			events:   []ipc.Envelope{{Kind: "snapshot", Seq: 1, Tasks: []ipc.Task{task("a", "one"), task("b", "two")}, Messages: []ipc.Message{{ID: "m1"}}}},
+			want:     map[string]string{"a": "one", "b": "two"},
+			messages: 1,
+			loaded:   true,
+		},
+		{
+			name: "upserts and removes apply on top",
+			events: []ipc.Envelope{
+				{Kind: "snapshot", Seq: 1, Tasks: []ipc.Task{task("a", "one"), task("b", "two")}},
+				{Kind: "task_upsert", Seq: 2, Task: &ipc.Task{ID: "a", Summary: "changed"}},
+				{Kind: "task_remove", Seq: 3, TaskID: "b"},
+				{Kind: "task_upsert", Seq: 4},
+			},
+			want:   map[string]string{"a": "changed"},
+			loaded: true,
+		},
+		{
+			name: "messages replace the unread list",
+			events: []ipc.Envelope{
+				{Kind: "snapshot", Seq: 1, Messages: []ipc.Message{{ID: "m1"}}},
+				{Kind: "messages", Messages: []ipc.Message{{ID: "m1"}, {ID: "m2"}}},
+			},
+			want:     map[string]string{},
+			messages: 2,
+			loaded:   true,
+		},
+		{
+			name:   "unknown kinds are ignored",
+			events: []ipc.Envelope{{Kind: "ack"}},
+			want:   map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := &trackerFeed{tasks: map[string]ipc.Task{}}
+			for _, env := range tt.events {
+				feed.apply(env)
+			}
+			got := map[string]string{}
+			for id, task := range feed.tasks {
+				got[id] = task.Summary
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("tasks = %v, want %v", got, tt.want)
+			}
+			for id, summary := range tt.want {
+				if got[id] != summary {
+					t.Errorf("task %s = %q, want %q", id, got[id], summary)
+				}
+			}
+			if len(feed.messages) != tt.messages {
+				t.Errorf("messages = %d, want %d", len(feed.messages), tt.messages)
+			}
+			if feed.loaded != tt.loaded {
+				t.Errorf("loaded = %v, want %v", feed.loaded, tt.loaded)
+			}
+		})
+	}
+}
+
+func TestTrackerFeedStateWhileDisconnected(t *testing.T) {
+	feed := &trackerFeed{tasks: map[string]ipc.Task{}}
+	feed.apply(ipc.Envelope{Kind: "snapshot", Seq: 1, Tasks: []ipc.Task{{ID: "a"}}})
+	env, _, err := feed.state()
+	if err != nil || env == nil || len(env.Tasks) != 1 {
+		t.Fatalf("state = %v, %v; want one task", env, err)
+	}
+	feed.loaded, feed.err = false, errors.New("tracker disconnected: EOF")
+	if env, _, err := feed.state(); env != nil || err == nil {
+		t.Errorf("state while disconnected = %v, %v; want the error", env, err)
+	}
+}
+
+func TestTrackerFeedResumesOnSynced(t *testing.T) {
+	feed := &trackerFeed{tasks: map[string]ipc.Task{}}
+	feed.apply(ipc.Envelope{Kind: "snapshot", Seq: 4, Tasks: []ipc.Task{{ID: "a", Summary: "one"}}})
+	feed.loaded, feed.err = false, errors.New("tracker disconnected: EOF")
+
+	feed.apply(ipc.Envelope{Kind: "synced", Seq: 4})
+	env, _, err := feed.state()
+	if err != nil || env == nil || len(env.Tasks) != 1 || env.Tasks[0].Summary != "one" {
+		t.Fatalf("state after resuming = %v, %v; want the task kept", env, err)
+	}
+	if feed.seq != 4 {
+		t.Errorf("seq = %d, want 4", feed.seq)
+	}
+}
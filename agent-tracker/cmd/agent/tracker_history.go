@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+type trackerHistoryFilter struct {
+	Session string
+	Window  string
+	Since   time.Time
+	Until   time.Time
+	Text    string
+}
+
+func runTrackerHistory(args []string) error {
+	fs := flagSet("agent tracker history")
+	var session, window, since, until, text string
+	var asJSON bool
+	var limit int
+	fs.StringVar(&session, "session", "", "tmux session name or id")
+	fs.StringVar(&window, "window", "", "tmux window name or id")
+	fs.StringVar(&since, "since", "", "only runs finished at or after this date (YYYY-MM-DD, RFC3339, today, yesterday, or a duration like 48h)")
+	fs.StringVar(&until, "until", "", "only runs finished before this date (same formats as --since)")
+	fs.StringVar(&text, "grep", "", "case-insensitive text to match in summary or completion note")
+	fs.BoolVar(&asJSON, "json", false, "print JSON instead of a table")
+	fs.IntVar(&limit, "limit", 0, "show at most this many of the most recent runs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	now := time.Now()
+	filter := trackerHistoryFilter{
+		Session: strings.TrimSpace(session),
+		Window:  strings.TrimSpace(window),
+		Text:    strings.TrimSpace(strings.Join(append([]string{text}, fs.Args()...), " ")),
+	}
+	var err error
+	if filter.Since, err = trackerParseHistoryTime(since, now); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = trackerParseHistoryTime(until, now); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+	entries, err := trackerLoadHistory()
+	if err != nil {
+		return err
+	}
+	entries = trackerFilterHistory(entries, filter)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	if asJSON {
+		out := json.NewEncoder(os.Stdout)
+		out.SetEscapeHTML(false)
+		out.SetIndent("", "  ")
+		if entries == nil {
+			entries = []ipc.HistoryEntry{}
+		}
+		return out.Encode(entries)
+	}
+	return trackerWriteHistoryTable(os.Stdout, entries)
+}
+
+func trackerHistoryPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".config", "agent-tracker", "run", "history.jsonl")
+}
+
+// trackerLoadHistory reads the archive written by tracker-server, keeping the
+// last entry for each run ID, ordered by completion time.
+func trackerLoadHistory() ([]ipc.HistoryEntry, error) {
+	file, err := os.Open(trackerHistoryPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	byID := map[string]int{}
+	entries := []ipc.HistoryEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry ipc.HistoryEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+		if idx, ok := byID[entry.ID]; ok && entry.ID != "" {
+			entries[idx] = entry
+			continue
+		}
+		byID[entry.ID] = len(entries)
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CompletedAt.Before(entries[j].CompletedAt)
+	})
+	return entries, nil
+}
+
+func trackerFilterHistory(entries []ipc.HistoryEntry, filter trackerHistoryFilter) []ipc.HistoryEntry {
+	text := strings.ToLower(filter.Text)
+	result := make([]ipc.HistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if filter.Session != "" && !trackerHistoryNameMatches(filter.Session, entry.SessionID, entry.Session) {
+			continue
+		}
+		if filter.Window != "" && !trackerHistoryNameMatches(filter.Window, entry.WindowID, entry.Window) {
+			continue
+		}
+		if !filter.Since.IsZero() && entry.CompletedAt.Before(filter.Since) {
+			continue
+		}
+		if !filter.Until.IsZero() && !entry.CompletedAt.Before(filter.Until) {
+			continue
+		}
+		if text != "" && !strings.Contains(strings.ToLower(entry.Summary+"\n"+entry.CompletionNote), text) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func trackerHistoryNameMatches(query, id, name string) bool {
+	query = strings.TrimSpace(query)
+	if query == strings.TrimSpace(id) {
+		return true
+	}
+	return strings.EqualFold(query, strings.TrimSpace(name))
+}
+
+// trackerParseHistoryTime accepts dates, timestamps, the words today and
+// yesterday, or a duration measured back from now.
+func trackerParseHistoryTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(value) {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "now":
+		return now, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if ts, err := time.Parse(time.RFC3339, value); err == nil {
+		return ts, nil
+	}
+	if ts, err := time.ParseInLocation("2006-01-02 15:04", value, now.Location()); err == nil {
+		return ts, nil
+	}
+	if ts, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return ts, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+func trackerWriteHistoryTable(w io.Writer, entries []ipc.HistoryEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FINISHED\tSTATUS\tDURATION\tSESSION\tWINDOW\tPANE\tSUMMARY\tNOTE")
+	for _, entry := range entries {
+		note := firstPaletteLine(entry.CompletionNote)
+		if note == firstPaletteLine(entry.Summary) {
+			note = ""
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.CompletedAt.Local().Format("2006-01-02 15:04"),
+			trackerFirstNonEmpty(entry.Status, trackerTaskStatusCompleted),
+			trackerFormatDuration(entry.DurationSeconds),
+			entry.Session,
+			entry.Window,
+			entry.Pane,
+			truncate(firstPaletteLine(entry.Summary), 60),
+			truncate(note, 60),
+		)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestTrackerParseHistoryTime(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "", want: time.Time{}},
+		{value: "today", want: time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{value: "Yesterday", want: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{value: "now", want: now},
+		{value: "48h", want: now.Add(-48 * time.Hour)},
+		{value: "2026-03-01", want: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2026-03-01 09:15", want: time.Date(2026, 3, 1, 9, 15, 0, 0, time.UTC)},
+		{value: "2026-03-01T09:15:00+02:00", want: time.Date(2026, 3, 1, 7, 15, 0, 0, time.UTC)},
+		{value: "last week", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := trackerParseHistoryTime(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("trackerParseHistoryTime(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackerFilterHistory(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	entries := []ipc.HistoryEntry{
+		{ID: "1", SessionID: "$1", Session: "work", WindowID: "@1", Window: "api", Summary: "Fix login", CompletedAt: day.Add(9 * time.Hour)},
+		{ID: "2", SessionID: "$1", Session: "work", WindowID: "@2", Window: "web", Summary: "Add form", CompletionNote: "Login form done", CompletedAt: day.Add(26 * time.Hour)},
+		{ID: "3", SessionID: "$2", Session: "play", WindowID: "@3", Window: "api", Summary: "Tidy", CompletedAt: day.Add(50 * time.Hour)},
+	}
+	tests := []struct {
+		name   string
+		filter trackerHistoryFilter
+		want   []string
+	}{
+		{name: "no filter", want: []string{"1", "2", "3"}},
+		{name: "session by name", filter: trackerHistoryFilter{Session: "WORK"}, want: []string{"1", "2"}},
+		{name: "session by id", filter: trackerHistoryFilter{Session: "$2"}, want: []string{"3"}},
+		{name: "window name across sessions", filter: trackerHistoryFilter{Window: "api"}, want: []string{"1", "3"}},
+		{name: "since is inclusive", filter: trackerHistoryFilter{Since: day.Add(26 * time.Hour)}, want: []string{"2", "3"}},
+		{name: "until is exclusive", filter: trackerHistoryFilter{Until: day.Add(26 * time.Hour)}, want: []string{"1"}},
+		{name: "text matches summary and note", filter: trackerHistoryFilter{Text: "login"}, want: []string{"1", "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, entry := range trackerFilterHistory(entries, tt.filter) {
+				got = append(got, entry.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("trackerFilterHistory = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackerLoadHistory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := trackerHistoryPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"id":"b","summary":"second","completed_at":"2026-03-10T12:00:00Z"}
+{"id":"a","summary":"first","completed_at":"2026-03-10T10:00:00Z"}
+not json
+{"id":"b","summary":"second, acknowledged","completed_at":"2026-03-10T12:00:00Z"}
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := trackerLoadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Summary)
+	}
+	want := []string{"first", "second, acknowledged"}
+	if !slices.Equal(got, want) {
+		t.Errorf("trackerLoadHistory = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// runTrackerInbox lists unread mailbox messages for the current window, or
+// posts one with `agent tracker inbox send`.
+func runTrackerInbox(args []string) error {
+	if len(args) > 0 && args[0] == "send" {
+		return runTrackerInboxSend(args[1:])
+	}
+	fs := flagSet("agent tracker inbox")
+	var windowID string
+	var all, markRead bool
+	fs.StringVar(&windowID, "window-id", "", "tmux window id (default: the current window)")
+	fs.BoolVar(&all, "all", false, "list unread messages for every agent")
+	fs.BoolVar(&markRead, "mark-read", false, "mark the listed messages as read")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if all && markRead {
+		return fmt.Errorf("--mark-read works on one window; drop --all")
+	}
+	var ctx trackerTmuxContext
+	filter := &ipc.Filter{}
+	if !all {
+		var err error
+		ctx, err = resolveTrackerContext("", "", "", windowID, "")
+		if err != nil {
+			return err
+		}
+		filter.WindowID = ctx.WindowID
+	}
+	env, err := trackerLoadState("", filter)
+	if err != nil {
+		return err
+	}
+	if len(env.Messages) == 0 {
+		fmt.Println("No unread messages.")
+		return nil
+	}
+	for _, message := range env.Messages {
+		fmt.Println(trackerFormatMessageHeader(message, all))
+		for _, line := range strings.Split(message.Text, "\n") {
+			fmt.Println("    " + line)
+		}
+	}
+	if !markRead {
+		return nil
+	}
+	return trackerMarkMessagesRead(ctx.SessionID, ctx.WindowID, env.Messages)
+}
+
+func runTrackerInboxSend(args []string) error {
+	fs := flagSet("agent tracker inbox send")
+	var to, windowID string
+	fs.StringVar(&to, "to", "", "recipient agent id (its tmux window name)")
+	fs.StringVar(&windowID, "window-id", "", "recipient tmux window id, instead of --to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	text := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if text == "" || (strings.TrimSpace(to) == "" && strings.TrimSpace(windowID) == "") {
+		return fmt.Errorf("usage: agent tracker inbox send --to <agent> <message>")
+	}
+	env := &ipc.Envelope{Mail: &ipc.Message{To: strings.TrimSpace(to), WindowID: strings.TrimSpace(windowID), Text: text}}
+	// Sign the message with the sender's window when run inside tmux.
+	if sender, err := resolveTrackerContext("", "", "", "", ""); err == nil {
+		env.SessionID = sender.SessionID
+		env.WindowID = sender.WindowID
+		env.Pane = sender.PaneID
+	}
+	return sendTrackerCommand("post_message", env)
+}
+
+func trackerMarkMessagesRead(sessionID, windowID string, messages []ipc.Message) error {
+	ids := make([]ipc.Message, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, ipc.Message{ID: message.ID})
+	}
+	return sendTrackerCommand("mark_read", &ipc.Envelope{SessionID: sessionID, WindowID: windowID, Messages: ids})
+}
+
+func trackerFormatMessageHeader(message ipc.Message, withRecipient bool) string {
+	from := firstNonEmpty(message.From, "someone")
+	when := ""
+	if ts, ok := trackerParseTimestamp(message.PostedAt); ok {
+		when = ts.Local().Format("2006-01-02 15:04") + "  "
+	}
+	if withRecipient {
+		return fmt.Sprintf("%s%s → %s", when, from, firstNonEmpty(message.To, message.WindowID))
+	}
+	return when + from
+}
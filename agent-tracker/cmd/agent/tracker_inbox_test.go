@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestTrackerFormatMessageHeader(t *testing.T) {
+	tests := []struct {
+		name          string
+		message       ipc.Message
+		withRecipient bool
+		want          string
+	}{
+		{name: "sender only", message: ipc.Message{From: "web"}, want: "web"},
+		{name: "anonymous", message: ipc.Message{}, want: "someone"},
+		{name: "with recipient name", message: ipc.Message{From: "web", To: "api", WindowID: "@1"}, withRecipient: true, want: "web → api"},
+		{name: "with recipient window", message: ipc.Message{From: "web", WindowID: "@1"}, withRecipient: true, want: "web → @1"},
+	}
+	for _, tt := range tests {
+		if got := trackerFormatMessageHeader(tt.message, tt.withRecipient); got != tt.want {
+			t.Errorf("%s: header = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -17,15 +17,25 @@ import (
 )
 
 const (
-	trackerTaskStatusInProgress = "in_progress"
-	trackerTaskStatusCompleted  = "completed"
+	trackerTaskStatusInProgress   = "in_progress"
+	trackerTaskStatusWaitingInput = "waiting_input"
+	trackerTaskStatusBlocked      = "blocked"
+	trackerTaskStatusCompleted    = "completed"
+	trackerTaskStatusFailed       = "failed"
 )
 
 type trackerPanelTickMsg struct{}
 
 type trackerPanelStateMsg struct {
-	env *ipc.Envelope
-	err error
+	env     *ipc.Envelope
+	version uint64
+	err     error
+}
+
+type trackerPanelUsageMsg struct {
+	ledger *usageLedger
+	reg    *registry
+	err    error
 }
 
 type trackerPanelCommandMsg struct {
@@ -53,7 +63,10 @@ type trackerPanelModel struct {
 	width           int
 	height          int
 	taskList        trackerPanelListState
+	detailOffset    int
 	state           ipc.Envelope
+	feed            *trackerFeed
+	feedVersion     uint64
 	loaded          bool
 	message         string
 	refreshedAt     time.Time
@@ -61,8 +74,15 @@ type trackerPanelModel struct {
 	pendingRefresh  bool
 	showAltHints    bool
 	helpVisible     bool
+	answering       *ipc.Task
+	answerText      []rune
+	answerCursor    int
 	requestBack     bool
 	requestClose    bool
+	usage           *usageLedger
+	usageRegistry   *registry
+	usageLoadedAt   time.Time
+	usageInFlight   bool
 }
 
 func newTrackerPanelModel(runtime *paletteRuntime) *trackerPanelModel {
@@ -75,6 +95,9 @@ func (m *trackerPanelModel) activate() tea.Cmd {
 	m.requestBack = false
 	m.requestClose = false
 	m.syncCurrentContext()
+	if m.feed == nil {
+		m.feed = startTrackerFeed()
+	}
 	if !m.loaded {
 		m.message = "Loading tracker..."
 	}
@@ -93,14 +116,31 @@ func (m *trackerPanelModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case trackerPanelTickMsg:
 		cmds := []tea.Cmd{trackerPanelTickCmd()}
-		if !m.loaded || time.Since(m.refreshedAt) >= time.Second {
+		if !m.loaded || m.feed.currentVersion() != m.feedVersion {
 			cmds = append(cmds, m.requestRefreshCmd())
 		}
+		if !m.usageInFlight && time.Since(m.usageLoadedAt) >= trackerPanelUsageInterval {
+			m.usageInFlight = true
+			cmds = append(cmds, trackerPanelUsageCmd())
+		}
 		return m, tea.Batch(cmds...)
+	case trackerPanelUsageMsg:
+		m.usageInFlight = false
+		m.usageLoadedAt = time.Now()
+		if msg.err == nil {
+			m.usage = msg.ledger
+			m.usageRegistry = msg.reg
+		}
+		return m, nil
 	case trackerPanelStateMsg:
 		m.refreshInFlight = false
+		m.feedVersion = msg.version
 		if msg.err != nil {
+			// The feed lost the server; its last tasks may be gone.
 			m.message = msg.err.Error()
+			m.state.Tasks = nil
+			m.loaded = false
+			m.clampSelections()
 		} else if msg.env != nil {
 			m.state = *msg.env
 			m.loaded = true
@@ -135,6 +175,9 @@ func (m *trackerPanelModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.showAltHints = false
+		if m.answering != nil {
+			return m.updateAnswer(msg.String())
+		}
 		return m.updateNormal(msg.String())
 	}
 	return m, nil
@@ -159,16 +202,57 @@ func (m *trackerPanelModel) updateNormal(key string) (tea.Model, tea.Cmd) {
 	case "e", "down", "ctrl+e":
 		m.moveSelection(1)
 		return m, nil
+	case "U", "pgup":
+		m.detailOffset = maxInt(0, m.detailOffset-5)
+		return m, nil
+	case "E", "pgdown":
+		m.detailOffset += 5
+		return m, nil
 	case "enter", "p":
 		return m.runPrimaryAction()
 	case "c":
 		return m.toggleSelected()
+	case "a":
+		return m.startAnswer()
 	case "D":
 		return m.deleteSelected()
 	}
 	return m, nil
 }
 
+func (m *trackerPanelModel) startAnswer() (tea.Model, tea.Cmd) {
+	task := m.selectedTask()
+	if task == nil || task.Question == nil || task.Question.AnsweredAt != "" {
+		m.message = "No open question on this task"
+		return m, nil
+	}
+	taskCopy := *task
+	m.answering = &taskCopy
+	m.answerText = nil
+	m.answerCursor = 0
+	return m, nil
+}
+
+func (m *trackerPanelModel) updateAnswer(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "esc", "ctrl+c":
+		m.answering = nil
+		return m, nil
+	case "enter":
+		answer := strings.TrimSpace(string(m.answerText))
+		if answer == "" {
+			return m, nil
+		}
+		task := *m.answering
+		m.answering = nil
+		return m, trackerPanelCommandFunc(func() error {
+			return trackerAnswerQuestion(task, answer)
+		}, false)
+	}
+	applyPaletteInputKey(key, &m.answerText, &m.answerCursor, false)
+	return m, nil
+}
+
 func (m *trackerPanelModel) View() string {
 	return m.render(newPaletteStyles(), m.width, m.height)
 }
@@ -246,13 +330,16 @@ func (m *trackerPanelModel) renderTaskRow(styles paletteStyles, task ipc.Task, s
 	metaStyle := styles.itemSubtitle
 	indicator := trackerTaskIndicator(task, now)
 	indicatorStyle := styles.selectedLabel
-	if task.Status == trackerTaskStatusCompleted {
+	switch {
+	case trackerTaskIsFinal(task.Status):
 		indicatorStyle = styles.statusBad
 		titleStyle = styles.itemTitle.Copy().Foreground(lipgloss.Color("246"))
 		metaStyle = styles.itemSubtitle.Copy().Foreground(lipgloss.Color("246"))
-		if task.Acknowledged {
+		if task.Acknowledged && task.Status == trackerTaskStatusCompleted {
 			indicatorStyle = styles.todoCheckDone
 		}
+	case trackerTaskNeedsInput(task.Status), task.Stalled:
+		indicatorStyle = styles.statusBad.Copy().Bold(true)
 	}
 	padStyle := lipgloss.NewStyle()
 	if selected {
@@ -266,13 +353,35 @@ func (m *trackerPanelModel) renderTaskRow(styles paletteStyles, task ipc.Task, s
 	if strings.TrimSpace(task.Window) != "" {
 		meta += "  /  " + strings.TrimSpace(task.Window)
 	}
-	if task.Status == trackerTaskStatusCompleted && !task.Acknowledged {
+	switch {
+	case task.Status == trackerTaskStatusWaitingInput && task.Question != nil && task.Question.AnsweredAt == "":
+		meta += "  ·  question for you"
+	case task.Status == trackerTaskStatusWaitingInput:
+		meta += "  ·  waiting for input"
+	case task.Status == trackerTaskStatusBlocked:
+		meta += "  ·  blocked"
+	case task.Status == trackerTaskStatusFailed && !task.Acknowledged:
+		meta += "  ·  failed"
+	case task.Status == trackerTaskStatusCompleted && !task.Acknowledged:
 		meta += "  ·  awaiting review"
 	}
+	if task.Orphaned {
+		meta += "  ·  pane gone"
+	}
+	if task.Stalled {
+		meta += "  ·  stalled"
+	}
+	if fraction, text, ok := trackerTaskProgress(task); ok {
+		meta += "  ·  " + trackerProgressBar(fraction, 8) + " " + text
+	}
 	if duration != "" {
 		meta = strings.TrimSpace(meta + "  ·  " + duration)
 	}
-	titleText := truncate(firstPaletteLine(task.Summary), maxInt(1, rowWidth-4-lipgloss.Width(indicator)))
+	title := firstPaletteLine(task.Summary)
+	if task.ParentID != "" {
+		title = "↳ " + title
+	}
+	titleText := truncate(title, maxInt(1, rowWidth-4-lipgloss.Width(indicator)))
 	line1RawWidth := 1 + lipgloss.Width(indicator) + 1 + lipgloss.Width(titleText)
 	line1Pad := maxInt(0, rowWidth-line1RawWidth)
 	line1 := padStyle.Render(" ") + indicatorStyle.Render(indicator) + padStyle.Render(" ") + titleStyle.Render(titleText) + padStyle.Render(strings.Repeat(" ", line1Pad))
@@ -289,13 +398,26 @@ func (m *trackerPanelModel) renderTaskDetailSection(styles paletteStyles, width,
 		return trackerRenderSection(styles, "Selected", "Open a task to jump into its tmux pane", trackerEmptyState(styles, "Nothing is selected."), width, height)
 	}
 	status := "Live"
-	if task.Status == trackerTaskStatusCompleted {
+	switch task.Status {
+	case trackerTaskStatusWaitingInput:
+		status = "Waiting for input"
+	case trackerTaskStatusBlocked:
+		status = "Blocked"
+	case trackerTaskStatusFailed:
+		status = "Failed"
+	case trackerTaskStatusCompleted:
 		if task.Acknowledged {
 			status = "Done"
 		} else {
 			status = "Needs review"
 		}
 	}
+	if task.Orphaned {
+		status += " · pane gone"
+	}
+	if task.Stalled {
+		status += " · stalled"
+	}
 	meta := trackerFirstNonEmpty(strings.TrimSpace(task.Session), task.SessionID)
 	if strings.TrimSpace(task.Window) != "" {
 		meta += " / " + strings.TrimSpace(task.Window)
@@ -307,16 +429,75 @@ func (m *trackerPanelModel) renderTaskDetailSection(styles paletteStyles, width,
 		trackerDetailLine(styles, "window", meta, width),
 		trackerDetailLine(styles, "elapsed", trackerLiveDuration(*task, time.Now()), width),
 	}
+	if last, ok := trackerParseTimestamp(task.LastActivityAt); ok {
+		lines = append(lines, trackerDetailLine(styles, "activity", trackerFormatDuration(time.Since(last).Seconds())+" ago", width))
+	}
+	if record := usageAgentForWindow(m.usageRegistry, task.WindowID); record != nil && m.usage != nil {
+		if started, ok := trackerParseTimestamp(task.StartedAt); ok {
+			completed, _ := trackerParseTimestamp(task.CompletedAt)
+			lines = append(lines, trackerDetailLine(styles, "tokens", formatUsageSummary(m.usage.between(record.ID, started, completed)), width))
+		}
+		lines = append(lines, trackerDetailLine(styles, "agent", formatUsageSummary(m.usage.agentTotal(record.ID)), width))
+	}
+	if fraction, text, ok := trackerTaskProgress(*task); ok {
+		lines = append(lines, trackerDetailLine(styles, "progress", trackerProgressBar(fraction, 12)+" "+text, width))
+	}
+	if len(task.Checklist) > 0 {
+		lines = append(lines, "", styles.muted.Render("checklist"))
+		for _, step := range task.Checklist {
+			if step.Done {
+				lines = append(lines, trackerRenderWrappedText(styles.panelTextDone, "✓ "+step.Name, maxInt(10, width)))
+			} else {
+				lines = append(lines, trackerRenderWrappedText(styles.panelText, "○ "+step.Name, maxInt(10, width)))
+			}
+		}
+	}
+	if question := task.Question; question != nil {
+		lines = append(lines, "", styles.muted.Render("question"), trackerRenderWrappedText(styles.panelText, question.Text, maxInt(10, width)))
+		for idx, option := range question.Options {
+			lines = append(lines, trackerRenderWrappedText(styles.panelText, fmt.Sprintf("  %d. %s", idx+1, option), maxInt(10, width)))
+		}
+		if question.AnsweredAt != "" {
+			lines = append(lines, trackerDetailLine(styles, "answer", question.Answer, width))
+		}
+	} else if reason := strings.TrimSpace(task.StatusNote); reason != "" {
+		lines = append(lines, "", styles.muted.Render("reason"), trackerRenderWrappedText(styles.panelText, reason, maxInt(10, width)))
+	}
 	if note := strings.TrimSpace(task.CompletionNote); note != "" {
 		lines = append(lines, "", styles.muted.Render("note"), trackerRenderWrappedText(styles.panelTextDone, note, maxInt(10, width)))
 	}
-	return trackerRenderSection(styles, "Selected", "Enter opens the highlighted pane", strings.Join(lines, "\n"), width, height)
+	if len(task.Timeline) > 0 {
+		lines = append(lines, "", styles.muted.Render("timeline"))
+		if task.TimelineOmitted > 0 {
+			lines = append(lines, styles.muted.Render(fmt.Sprintf("%d earlier events, see agent tracker show", task.TimelineOmitted)))
+		}
+		now := time.Now()
+		for _, event := range task.Timeline {
+			when, label := trackerTimelineEvent(event, now)
+			lines = append(lines, styles.muted.Render(when+"  ")+styles.panelText.Render(label))
+			if text := firstPaletteLine(event.Text); text != "" {
+				lines = append(lines, trackerRenderWrappedText(styles.panelText, "  "+text, maxInt(10, width)))
+			}
+		}
+	}
+	// The detail scrolls on its own so a long timeline stays reachable.
+	rows := strings.Split(strings.Join(lines, "\n"), "\n")
+	visible := maxInt(1, height-3)
+	m.detailOffset = clampInt(m.detailOffset, 0, maxInt(0, len(rows)-visible))
+	hint := "Enter opens the highlighted pane"
+	if len(rows) > visible {
+		rows = rows[m.detailOffset:minInt(len(rows), m.detailOffset+visible)]
+		hint = "Shift-U/E scroll the detail"
+	}
+	return trackerRenderSection(styles, "Selected", hint, strings.Join(rows, "\n"), width, height)
 }
 
 func (m *trackerPanelModel) renderHelp(styles paletteStyles, width, height int) string {
 	lines := []string{
 		"u and e move through tasks. enter opens the highlighted tmux pane.",
 		"c settles a task. shift-d deletes it. esc returns to the command palette.",
+		"a answers the question an agent asked; type an option number or your own reply.",
+		"shift-u and shift-e scroll the selected task's details and timeline.",
 	}
 	styled := make([]string, 0, len(lines))
 	for _, line := range lines {
@@ -329,6 +510,15 @@ func (m *trackerPanelModel) renderFooter(styles paletteStyles, width int) string
 	renderSegments := func(pairs [][2]string) string {
 		return renderShortcutPairs(func(v string) string { return styles.shortcutKey.Render(v) }, func(v string) string { return styles.shortcutText.Render(v) }, "  ", pairs)
 	}
+	if m.answering != nil {
+		prompt := "Answer: "
+		if m.answering.Question != nil && len(m.answering.Question.Options) > 0 {
+			prompt = fmt.Sprintf("Answer (1-%d or text): ", len(m.answering.Question.Options))
+		}
+		hints := renderSegments([][2]string{{"Enter", "send"}, {"Esc", "cancel"}})
+		input := styles.shortcutText.Render(prompt) + renderInputValue(m.answerText, m.answerCursor, styles)
+		return lipgloss.NewStyle().Width(width).Render(input + "  " + hints)
+	}
 	footer := ""
 	if m.showAltHints {
 		footer = pickRenderedShortcutFooter(width, renderSegments,
@@ -337,7 +527,8 @@ func (m *trackerPanelModel) renderFooter(styles paletteStyles, width int) string
 		)
 	} else {
 		footer = pickRenderedShortcutFooter(width, renderSegments,
-			[][2]string{{"u/e", "move"}, {"Enter", "open"}, {"c", "settle"}, {"Shift-D", "delete"}, {"Esc", "back"}, {footerHintToggleKey, "more"}},
+			[][2]string{{"u/e", "move"}, {"Enter", "open"}, {"c", "settle"}, {"a", "answer"}, {"Shift-U/E", "scroll"}, {"Shift-D", "delete"}, {"Esc", "back"}, {footerHintToggleKey, "more"}},
+			[][2]string{{"u/e", "move"}, {"Enter", "open"}, {"c", "settle"}, {"a", "answer"}, {"Shift-D", "delete"}, {"Esc", "back"}, {footerHintToggleKey, "more"}},
 			[][2]string{{"u/e", "move"}, {"Enter", "open"}, {"c", "settle"}, {"Esc", "back"}, {footerHintToggleKey, "more"}},
 			[][2]string{{"Esc", "back"}, {footerHintToggleKey, "more"}},
 		)
@@ -417,17 +608,23 @@ func (m *trackerPanelModel) renderContextLine() string {
 
 func (m *trackerPanelModel) renderMetricsLine() string {
 	active := 0
+	waiting := 0
 	review := 0
 	for _, task := range m.state.Tasks {
-		switch task.Status {
-		case trackerTaskStatusInProgress:
+		switch {
+		case task.Status == trackerTaskStatusInProgress:
 			active++
-		case trackerTaskStatusCompleted:
+		case trackerTaskNeedsInput(task.Status):
+			waiting++
+		case trackerTaskIsFinal(task.Status):
 			if !task.Acknowledged {
 				review++
 			}
 		}
 	}
+	if waiting > 0 {
+		return fmt.Sprintf("%d waiting  ·  %d live  ·  %d review", waiting, active, review)
+	}
 	return fmt.Sprintf("%d live  ·  %d review", active, review)
 }
 
@@ -454,15 +651,20 @@ func (m *trackerPanelModel) requestRefreshCmd() tea.Cmd {
 		return nil
 	}
 	m.refreshInFlight = true
+	feed := m.feed
 	return func() tea.Msg {
-		env, err := trackerLoadState("")
-		return trackerPanelStateMsg{env: env, err: err}
+		env, version, err := feed.state()
+		return trackerPanelStateMsg{env: env, version: version, err: err}
 	}
 }
 
 func (m *trackerPanelModel) moveSelection(delta int) {
 	list := m.visibleTasks()
-	m.taskList.selected = clampInt(m.taskList.selected+delta, 0, maxInt(0, len(list)-1))
+	selected := clampInt(m.taskList.selected+delta, 0, maxInt(0, len(list)-1))
+	if selected != m.taskList.selected {
+		m.detailOffset = 0
+	}
+	m.taskList.selected = selected
 }
 
 func (m *trackerPanelModel) runPrimaryAction() (tea.Model, tea.Cmd) {
@@ -492,16 +694,16 @@ func (m *trackerPanelModel) deleteSelected() (tea.Model, tea.Cmd) {
 }
 
 func (m *trackerPanelModel) toggleTask(task ipc.Task) error {
-	env := ipc.Envelope{Session: task.Session, SessionID: task.SessionID, Window: task.Window, WindowID: task.WindowID, Pane: task.Pane}
+	env := ipc.Envelope{Session: task.Session, SessionID: task.SessionID, Window: task.Window, WindowID: task.WindowID, Pane: task.Pane, TaskID: task.ID}
 	command := "acknowledge"
-	if task.Status == trackerTaskStatusInProgress {
+	if !trackerTaskIsFinal(task.Status) {
 		command = "finish_task"
 	}
 	return sendTrackerCommand(command, &env)
 }
 
 func (m *trackerPanelModel) deleteTask(task ipc.Task) error {
-	env := ipc.Envelope{Session: task.Session, SessionID: task.SessionID, Window: task.Window, WindowID: task.WindowID, Pane: task.Pane}
+	env := ipc.Envelope{Session: task.Session, SessionID: task.SessionID, Window: task.Window, WindowID: task.WindowID, Pane: task.Pane, TaskID: task.ID}
 	return sendTrackerCommand("delete_task", &env)
 }
 
@@ -512,7 +714,38 @@ func (m *trackerPanelModel) clampSelections() {
 func (m *trackerPanelModel) visibleTasks() []ipc.Task {
 	result := append([]ipc.Task(nil), m.state.Tasks...)
 	trackerSortTasks(result)
-	return result
+	return trackerNestSubtasks(result)
+}
+
+// trackerNestSubtasks moves every sub-task right below its parent, keeping
+// the sorted order among siblings. Sub-tasks whose parent is gone stay where
+// they are.
+func trackerNestSubtasks(tasks []ipc.Task) []ipc.Task {
+	present := make(map[string]bool, len(tasks))
+	children := map[string][]ipc.Task{}
+	for _, task := range tasks {
+		present[task.ID] = true
+	}
+	var roots []ipc.Task
+	for _, task := range tasks {
+		if task.ParentID != "" && present[task.ParentID] {
+			children[task.ParentID] = append(children[task.ParentID], task)
+			continue
+		}
+		roots = append(roots, task)
+	}
+	nested := make([]ipc.Task, 0, len(tasks))
+	var add func(task ipc.Task)
+	add = func(task ipc.Task) {
+		nested = append(nested, task)
+		for _, child := range children[task.ID] {
+			add(child)
+		}
+	}
+	for _, task := range roots {
+		add(task)
+	}
+	return nested
 }
 
 func (m *trackerPanelModel) selectedTask() *ipc.Task {
@@ -555,6 +788,25 @@ func trackerPanelTickCmd() tea.Cmd {
 	return tea.Tick(120*time.Millisecond, func(time.Time) tea.Msg { return trackerPanelTickMsg{} })
 }
 
+// trackerPanelUsageInterval spaces out transcript scans, which check every
+// session log of the registered agents and read the ones that changed.
+const trackerPanelUsageInterval = 30 * time.Second
+
+func trackerPanelUsageCmd() tea.Cmd {
+	return func() tea.Msg {
+		reg, err := loadRegistry()
+		if err != nil {
+			return trackerPanelUsageMsg{err: err}
+		}
+		agents := make([]*agentRecord, 0, len(reg.Agents))
+		for _, id := range sortedAgentIDs(reg) {
+			agents = append(agents, reg.Agents[id])
+		}
+		ledger, err := collectUsage(agents)
+		return trackerPanelUsageMsg{ledger: ledger, reg: reg, err: err}
+	}
+}
+
 func trackerPanelCommandCmd(err error, message string) tea.Cmd {
 	return func() tea.Msg { return trackerPanelCommandMsg{message: message, err: err} }
 }
@@ -563,7 +815,19 @@ func trackerPanelCommandFunc(fn func() error, close bool) tea.Cmd {
 	return func() tea.Msg { return trackerPanelCommandMsg{err: fn(), close: close} }
 }
 
-func trackerLoadState(client string) (*ipc.Envelope, error) {
+// trackerLoadState fetches one state snapshot; a nil filter returns every
+// task. Timelines hold only their latest events.
+func trackerLoadState(client string, filter *ipc.Filter) (*ipc.Envelope, error) {
+	return trackerRequestState(&ipc.Envelope{Kind: "ui-register", Client: strings.TrimSpace(client), Filter: filter})
+}
+
+// trackerLoadDetailedState is trackerLoadState with every task's whole
+// timeline.
+func trackerLoadDetailedState(filter *ipc.Filter) (*ipc.Envelope, error) {
+	return trackerRequestState(&ipc.Envelope{Kind: "ui-register", Filter: filter, Detail: true})
+}
+
+func trackerRequestState(register *ipc.Envelope) (*ipc.Envelope, error) {
 	conn, err := net.Dial("unix", trackerSocketPath())
 	if err != nil {
 		return nil, err
@@ -571,7 +835,7 @@ func trackerLoadState(client string) (*ipc.Envelope, error) {
 	defer conn.Close()
 	enc := json.NewEncoder(conn)
 	dec := json.NewDecoder(bufio.NewReader(conn))
-	if err := enc.Encode(&ipc.Envelope{Kind: "ui-register", Client: strings.TrimSpace(client)}); err != nil {
+	if err := enc.Encode(register); err != nil {
 		return nil, err
 	}
 	for {
@@ -586,12 +850,19 @@ func trackerLoadState(client string) (*ipc.Envelope, error) {
 }
 
 func sendTrackerCommand(command string, env *ipc.Envelope) error {
+	_, err := trackerCommandReply(command, env)
+	return err
+}
+
+// trackerCommandReply sends command and returns the server's ack, which
+// carries the task ID for start_task and push_task.
+func trackerCommandReply(command string, env *ipc.Envelope) (*ipc.Envelope, error) {
 	conn, err := net.Dial("unix", trackerSocketPath())
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer conn.Close()
-	request := ipc.Envelope{Kind: "command", Command: strings.TrimSpace(command)}
+	request := ipc.Envelope{Kind: "command", ID: trackerRequestID(), Command: strings.TrimSpace(command)}
 	if env != nil {
 		request.Client = strings.TrimSpace(env.Client)
 		request.Session = strings.TrimSpace(env.Session)
@@ -599,25 +870,44 @@ func sendTrackerCommand(command string, env *ipc.Envelope) error {
 		request.Window = strings.TrimSpace(env.Window)
 		request.WindowID = strings.TrimSpace(env.WindowID)
 		request.Pane = strings.TrimSpace(env.Pane)
+		request.TaskID = strings.TrimSpace(env.TaskID)
 		request.Summary = strings.TrimSpace(env.Summary)
 		request.Message = strings.TrimSpace(env.Message)
+		request.Question = env.Question
+		request.Mail = env.Mail
+		request.Messages = env.Messages
+		request.Progress = env.Progress
+		request.Checklist = env.Checklist
 	}
 	enc := json.NewEncoder(conn)
 	if err := enc.Encode(&request); err != nil {
-		return err
+		return nil, err
 	}
 	dec := json.NewDecoder(bufio.NewReader(conn))
 	for {
 		var reply ipc.Envelope
 		if err := dec.Decode(&reply); err != nil {
-			return err
+			return nil, err
 		}
-		if reply.Kind == "ack" {
-			return nil
+		if reply.ID != "" && reply.ID != request.ID {
+			continue
+		}
+		switch reply.Kind {
+		case "ack":
+			return &reply, nil
+		case "error":
+			if reply.Error == nil {
+				return nil, fmt.Errorf("tracker rejected %s", request.Command)
+			}
+			return nil, reply.Error
 		}
 	}
 }
 
+func trackerRequestID() string {
+	return fmt.Sprintf("agent-%d-%d", os.Getpid(), time.Now().UnixNano())
+}
+
 func trackerSocketPath() string {
 	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
 		return filepath.Join(dir, "agent-tracker.sock")
@@ -633,9 +923,9 @@ func trackerSortTasks(tasks []ipc.Task) {
 			return leftRank < rightRank
 		}
 		switch left.Status {
-		case trackerTaskStatusInProgress:
+		case trackerTaskStatusInProgress, trackerTaskStatusWaitingInput, trackerTaskStatusBlocked:
 			return left.StartedAt < right.StartedAt
-		case trackerTaskStatusCompleted:
+		case trackerTaskStatusCompleted, trackerTaskStatusFailed:
 			if left.Acknowledged != right.Acknowledged {
 				return !left.Acknowledged && right.Acknowledged
 			}
@@ -660,17 +950,33 @@ func trackerSortTasks(tasks []ipc.Task) {
 	})
 }
 
+// trackerTaskStatusRank orders the panel: tasks waiting on a person first,
+// then running work, then finished runs.
 func trackerTaskStatusRank(status string) int {
 	switch status {
-	case trackerTaskStatusInProgress:
+	case trackerTaskStatusWaitingInput:
 		return 0
-	case trackerTaskStatusCompleted:
+	case trackerTaskStatusBlocked:
 		return 1
-	default:
+	case trackerTaskStatusInProgress:
 		return 2
+	case trackerTaskStatusFailed:
+		return 3
+	case trackerTaskStatusCompleted:
+		return 4
+	default:
+		return 5
 	}
 }
 
+func trackerTaskNeedsInput(status string) bool {
+	return status == trackerTaskStatusWaitingInput || status == trackerTaskStatusBlocked
+}
+
+func trackerTaskIsFinal(status string) bool {
+	return status == trackerTaskStatusCompleted || status == trackerTaskStatusFailed
+}
+
 func trackerParseTimestamp(value string) (time.Time, bool) {
 	value = strings.TrimSpace(value)
 	if value == "" {
@@ -688,6 +994,12 @@ func trackerTaskIndicator(task ipc.Task, now time.Time) string {
 	case trackerTaskStatusInProgress:
 		frames := []rune{'⠋', '⠙', '⠹', '⠸', '⠼', '⠴', '⠦', '⠧', '⠇', '⠏'}
 		return string(frames[int(now.UnixNano()/int64(100*time.Millisecond))%len(frames)])
+	case trackerTaskStatusWaitingInput:
+		return "?"
+	case trackerTaskStatusBlocked:
+		return "⊘"
+	case trackerTaskStatusFailed:
+		return "✗"
 	case trackerTaskStatusCompleted:
 		if task.Acknowledged {
 			return "✓"
@@ -703,7 +1015,7 @@ func trackerLiveDuration(task ipc.Task, now time.Time) string {
 	if !ok {
 		return trackerFormatDuration(task.DurationSeconds)
 	}
-	if task.Status == trackerTaskStatusCompleted {
+	if trackerTaskIsFinal(task.Status) {
 		if end, ok := trackerParseTimestamp(task.CompletedAt); ok {
 			return trackerFormatDuration(end.Sub(start).Seconds())
 		}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// runTrackerProgress reports how far the current task has come, as steps
+// ("4/7") or a percentage ("40%"); "clear" removes it.
+func runTrackerProgress(args []string) error {
+	fs := flagSet("agent tracker progress")
+	var pane, taskID, label string
+	fs.StringVar(&pane, "pane", "", "tmux pane id (default: the current pane)")
+	fs.StringVar(&taskID, "task-id", "", "task id (default: the pane's innermost open task)")
+	fs.StringVar(&label, "label", "", "what is counted, e.g. migrations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	rest := fs.Args()
+	if len(rest) == 0 {
+		return fmt.Errorf("usage: agent tracker progress [--label migrations] <done/total|percent%%|clear> [label]")
+	}
+	if len(rest) > 1 {
+		label = strings.Join(rest[1:], " ")
+	}
+	progress, err := trackerParseProgress(rest[0])
+	if err != nil {
+		return err
+	}
+	if progress != nil {
+		progress.Label = strings.TrimSpace(label)
+	}
+	env, err := trackerTaskEnvelope(pane, taskID)
+	if err != nil {
+		return err
+	}
+	env.Progress = progress
+	return sendTrackerCommand("set_progress", env)
+}
+
+// runTrackerChecklist replaces the current task's checklist or checks items
+// off it.
+func runTrackerChecklist(args []string) error {
+	usage := fmt.Errorf("usage: agent tracker checklist <set|check|uncheck> [--pane id] [--task-id id] <item>...")
+	if len(args) == 0 {
+		return usage
+	}
+	action := args[0]
+	fs := flagSet("agent tracker checklist " + action)
+	var pane, taskID string
+	fs.StringVar(&pane, "pane", "", "tmux pane id (default: the current pane)")
+	fs.StringVar(&taskID, "task-id", "", "task id (default: the pane's innermost open task)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	var steps []ipc.Step
+	for _, name := range fs.Args() {
+		steps = append(steps, ipc.Step{Name: name, Done: action == "check"})
+	}
+	command := "check_items"
+	switch action {
+	case "set":
+		command = "set_checklist"
+	case "check", "uncheck":
+		if len(steps) == 0 {
+			return usage
+		}
+	default:
+		return usage
+	}
+	env, err := trackerTaskEnvelope(pane, taskID)
+	if err != nil {
+		return err
+	}
+	env.Checklist = steps
+	return sendTrackerCommand(command, env)
+}
+
+// trackerTaskEnvelope addresses taskID, or the given or current pane.
+func trackerTaskEnvelope(pane, taskID string) (*ipc.Envelope, error) {
+	if taskID = strings.TrimSpace(taskID); taskID != "" {
+		return &ipc.Envelope{TaskID: taskID}, nil
+	}
+	ctx, err := resolveTrackerContext("", "", "", "", pane)
+	if err != nil {
+		return nil, err
+	}
+	return &ipc.Envelope{
+		Session:   ctx.SessionName,
+		SessionID: ctx.SessionID,
+		Window:    ctx.WindowName,
+		WindowID:  ctx.WindowID,
+		Pane:      ctx.PaneID,
+	}, nil
+}
+
+func trackerParseProgress(value string) (*ipc.Progress, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "clear") {
+		return nil, nil
+	}
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percentage %q", value)
+		}
+		return &ipc.Progress{Percent: p}, nil
+	}
+	doneText, totalText, ok := strings.Cut(value, "/")
+	if !ok {
+		return nil, fmt.Errorf("progress must look like 4/7 or 40%%, got %q", value)
+	}
+	done, err := strconv.Atoi(strings.TrimSpace(doneText))
+	if err != nil {
+		return nil, fmt.Errorf("invalid progress %q", value)
+	}
+	total, err := strconv.Atoi(strings.TrimSpace(totalText))
+	if err != nil {
+		return nil, fmt.Errorf("invalid progress %q", value)
+	}
+	return &ipc.Progress{Done: done, Total: total}, nil
+}
+
+// trackerTaskProgress returns the share of a task that is done and how to
+// say it, e.g. "4/7 migrations". Without reported progress the checklist is
+// counted instead.
+func trackerTaskProgress(task ipc.Task) (float64, string, bool) {
+	label := ""
+	if p := task.Progress; p != nil {
+		label = p.Label
+		switch {
+		case p.Total > 0:
+			return float64(p.Done) / float64(p.Total), strings.TrimSpace(fmt.Sprintf("%d/%d %s", p.Done, p.Total, label)), true
+		case p.Percent > 0 || len(task.Checklist) == 0:
+			return p.Percent / 100, strings.TrimSpace(fmt.Sprintf("%.0f%% %s", p.Percent, label)), true
+		}
+	}
+	if len(task.Checklist) == 0 {
+		return 0, "", false
+	}
+	done := 0
+	for _, step := range task.Checklist {
+		if step.Done {
+			done++
+		}
+	}
+	return float64(done) / float64(len(task.Checklist)), strings.TrimSpace(fmt.Sprintf("%d/%d %s", done, len(task.Checklist), label)), true
+}
+
+// trackerProgressBar draws fraction as a bar of width cells.
+func trackerProgressBar(fraction float64, width int) string {
+	fraction = math.Max(0, math.Min(1, fraction))
+	filled := int(math.Round(fraction * float64(width)))
+	return strings.Repeat("▰", filled) + strings.Repeat("▱", width-filled)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestTrackerParseProgress(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *ipc.Progress
+		wantErr bool
+	}{
+		{value: "4/7", want: &ipc.Progress{Done: 4, Total: 7}},
+		{value: " 4 / 7 ", want: &ipc.Progress{Done: 4, Total: 7}},
+		{value: "40%", want: &ipc.Progress{Percent: 40}},
+		{value: "12.5 %", want: &ipc.Progress{Percent: 12.5}},
+		{value: "Clear"},
+		{value: "four/7", wantErr: true},
+		{value: "4/", wantErr: true},
+		{value: "lots%", wantErr: true},
+		{value: "40", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := trackerParseProgress(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("trackerParseProgress(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("trackerParseProgress(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTrackerTaskProgress(t *testing.T) {
+	checklist := []ipc.Step{{Name: "build", Done: true}, {Name: "test"}, {Name: "deploy"}, {Name: "docs", Done: true}}
+	tests := []struct {
+		name     string
+		task     ipc.Task
+		fraction float64
+		text     string
+		ok       bool
+	}{
+		{name: "nothing reported"},
+		{name: "steps", task: ipc.Task{Progress: &ipc.Progress{Done: 4, Total: 7, Label: "migrations"}}, fraction: 4.0 / 7, text: "4/7 migrations", ok: true},
+		{name: "percent", task: ipc.Task{Progress: &ipc.Progress{Percent: 40}}, fraction: 0.4, text: "40%", ok: true},
+		{name: "checklist", task: ipc.Task{Checklist: checklist}, fraction: 0.5, text: "2/4", ok: true},
+		{name: "label only falls back to the checklist", task: ipc.Task{Progress: &ipc.Progress{Label: "steps"}, Checklist: checklist}, fraction: 0.5, text: "2/4 steps", ok: true},
+		{name: "reported steps win over the checklist", task: ipc.Task{Progress: &ipc.Progress{Done: 1, Total: 2}, Checklist: checklist}, fraction: 0.5, text: "1/2", ok: true},
+	}
+	for _, tt := range tests {
+		fraction, text, ok := trackerTaskProgress(tt.task)
+		if math.Abs(fraction-tt.fraction) > 1e-9 || text != tt.text || ok != tt.ok {
+			t.Errorf("%s: trackerTaskProgress = %v, %q, %v; want %v, %q, %v", tt.name, fraction, text, ok, tt.fraction, tt.text, tt.ok)
+		}
+	}
+}
+
+func TestTrackerProgressBar(t *testing.T) {
+	tests := []struct {
+		fraction float64
+		want     string
+	}{
+		{fraction: 0, want: "▱▱▱▱"},
+		{fraction: 0.5, want: "▰▰▱▱"},
+		{fraction: 1, want: "▰▰▰▰"},
+		{fraction: 1.5, want: "▰▰▰▰"},
+		{fraction: -1, want: "▱▱▱▱"},
+	}
+	for _, tt := range tests {
+		if got := trackerProgressBar(tt.fraction, 4); got != tt.want {
+			t.Errorf("trackerProgressBar(%v) = %q, want %q", tt.fraction, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// trackerReportRow is the time spent by one group in one period: Working is
+// time in progress, Waiting the part of a run spent waiting for input or
+// blocked, and Review the time finished runs waited to be acknowledged.
+type trackerReportRow struct {
+	Period         string  `json:"period"`
+	Group          string  `json:"group"`
+	Runs           int     `json:"runs"`
+	WorkingSeconds float64 `json:"working_seconds"`
+	WaitingSeconds float64 `json:"waiting_seconds"`
+	ReviewSeconds  float64 `json:"review_seconds"`
+}
+
+type trackerReportRun struct {
+	SessionID string
+	Session   string
+	WindowID  string
+	Window    string
+	StartedAt time.Time
+	Duration  float64
+	Waiting   float64
+	Review    float64
+}
+
+func runTrackerReport(args []string) error {
+	fs := flagSet("agent tracker report")
+	var by, period, since, until, format string
+	fs.StringVar(&by, "by", "window", "group by session, window or agent")
+	fs.StringVar(&period, "period", "day", "bucket by day or week")
+	fs.StringVar(&since, "since", "", "only runs started at or after this time (same formats as tracker history --since)")
+	fs.StringVar(&until, "until", "", "only runs started before this time")
+	fs.StringVar(&format, "format", "text", "output text, csv or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch by {
+	case "session", "window", "agent":
+	default:
+		return fmt.Errorf("invalid --by %q: use session, window or agent", by)
+	}
+	if period != "day" && period != "week" {
+		return fmt.Errorf("invalid --period %q: use day or week", period)
+	}
+	now := time.Now()
+	sinceTime, err := trackerParseHistoryTime(since, now)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	untilTime, err := trackerParseHistoryTime(until, now)
+	if err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+	history, err := trackerLoadHistory()
+	if err != nil {
+		return err
+	}
+	var live []ipc.Task
+	if env, err := trackerLoadDetailedState(nil); err == nil {
+		live = env.Tasks
+	}
+	var reg *registry
+	if by == "agent" {
+		if reg, err = loadRegistry(); err != nil {
+			return err
+		}
+	}
+	var runs []trackerReportRun
+	for _, run := range trackerReportRuns(history, live, now) {
+		if (!sinceTime.IsZero() && run.StartedAt.Before(sinceTime)) || (!untilTime.IsZero() && !run.StartedAt.Before(untilTime)) {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	rows := trackerReportRows(runs, by, period, reg)
+	switch format {
+	case "json":
+		out := json.NewEncoder(os.Stdout)
+		out.SetEscapeHTML(false)
+		out.SetIndent("", "  ")
+		if rows == nil {
+			rows = []trackerReportRow{}
+		}
+		return out.Encode(rows)
+	case "csv":
+		return trackerWriteReportCSV(os.Stdout, rows)
+	case "text":
+		return trackerWriteReportTable(os.Stdout, rows, by)
+	default:
+		return fmt.Errorf("invalid --format %q: use text, csv or json", format)
+	}
+}
+
+// trackerReportRuns turns archived runs and live tasks into runs to report.
+// Sub-tasks are left out because their time is already part of the task they
+// were pushed on. A finished run that is still unacknowledged has waited for
+// review until now; archives written before acknowledgements were recorded
+// count no review time.
+func trackerReportRuns(history []ipc.HistoryEntry, live []ipc.Task, now time.Time) []trackerReportRun {
+	unreviewed := map[string]time.Time{}
+	var runs []trackerReportRun
+	for _, task := range live {
+		if task.ParentID != "" {
+			continue
+		}
+		if trackerTaskIsFinal(task.Status) {
+			if completed, ok := trackerParseTimestamp(task.CompletedAt); ok && !task.Acknowledged {
+				unreviewed[task.ID] = completed
+			}
+			continue
+		}
+		started, ok := trackerParseTimestamp(task.StartedAt)
+		if !ok {
+			continue
+		}
+		runs = append(runs, trackerReportRun{
+			SessionID: task.SessionID,
+			Session:   task.Session,
+			WindowID:  task.WindowID,
+			Window:    task.Window,
+			StartedAt: started,
+			Duration:  now.Sub(started).Seconds(),
+			Waiting:   ipc.WaitingSeconds(task.Timeline, now),
+		})
+	}
+	for _, entry := range history {
+		key := entry.ID
+		if idx := strings.LastIndex(key, "|"); idx >= 0 {
+			key = key[:idx]
+		}
+		if strings.Contains(key, "#") {
+			continue
+		}
+		run := trackerReportRun{
+			SessionID: entry.SessionID,
+			Session:   entry.Session,
+			WindowID:  entry.WindowID,
+			Window:    entry.Window,
+			StartedAt: entry.StartedAt,
+			Duration:  entry.DurationSeconds,
+			Waiting:   entry.WaitingSeconds,
+		}
+		switch completed, ok := unreviewed[key]; {
+		case entry.AcknowledgedAt != nil:
+			run.Review = entry.AcknowledgedAt.Sub(entry.CompletedAt).Seconds()
+		case ok && completed.Equal(entry.CompletedAt.Truncate(time.Second)):
+			run.Review = now.Sub(entry.CompletedAt).Seconds()
+		}
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+func trackerReportRows(runs []trackerReportRun, by, period string, reg *registry) []trackerReportRow {
+	index := map[[2]string]int{}
+	var rows []trackerReportRow
+	for _, run := range runs {
+		group := trackerFirstNonEmpty(run.Window, run.WindowID)
+		switch by {
+		case "session":
+			group = trackerFirstNonEmpty(run.Session, run.SessionID)
+		case "agent":
+			if record := usageAgentForWindow(reg, run.WindowID); record != nil {
+				group = record.ID
+			}
+		}
+		id := [2]string{trackerReportPeriod(run.StartedAt, period), group}
+		idx, ok := index[id]
+		if !ok {
+			idx = len(rows)
+			index[id] = idx
+			rows = append(rows, trackerReportRow{Period: id[0], Group: id[1]})
+		}
+		row := &rows[idx]
+		waiting := math.Min(math.Max(run.Waiting, 0), math.Max(run.Duration, 0))
+		row.Runs++
+		row.WorkingSeconds += math.Max(run.Duration, 0) - waiting
+		row.WaitingSeconds += waiting
+		row.ReviewSeconds += math.Max(run.Review, 0)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Period != rows[j].Period {
+			return rows[i].Period < rows[j].Period
+		}
+		return rows[i].Group < rows[j].Group
+	})
+	return rows
+}
+
+// trackerReportPeriod names the local day, or the ISO week, that t falls in.
+func trackerReportPeriod(t time.Time, period string) string {
+	t = t.Local()
+	if period == "week" {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return t.Format("2006-01-02")
+}
+
+func trackerWriteReportTable(w io.Writer, rows []trackerReportRow, by string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "PERIOD\t%s\tRUNS\tWORKING\tWAITING\tREVIEW\n", strings.ToUpper(by))
+	var total trackerReportRow
+	for _, row := range rows {
+		total.Runs += row.Runs
+		total.WorkingSeconds += row.WorkingSeconds
+		total.WaitingSeconds += row.WaitingSeconds
+		total.ReviewSeconds += row.ReviewSeconds
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\n", row.Period, row.Group, row.Runs,
+			trackerReportDuration(row.WorkingSeconds), trackerReportDuration(row.WaitingSeconds), trackerReportDuration(row.ReviewSeconds))
+	}
+	if len(rows) > 1 {
+		fmt.Fprintf(tw, "total\t\t%d\t%s\t%s\t%s\n", total.Runs,
+			trackerReportDuration(total.WorkingSeconds), trackerReportDuration(total.WaitingSeconds), trackerReportDuration(total.ReviewSeconds))
+	}
+	return tw.Flush()
+}
+
+func trackerWriteReportCSV(w io.Writer, rows []trackerReportRow) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"period", "group", "runs", "working_seconds", "waiting_seconds", "review_seconds"}); err != nil {
+		return err
+	}
+	seconds := func(value float64) string {
+		return strconv.FormatInt(int64(value+0.5), 10)
+	}
+	for _, row := range rows {
+		record := []string{row.Period, row.Group, strconv.Itoa(row.Runs), seconds(row.WorkingSeconds), seconds(row.WaitingSeconds), seconds(row.ReviewSeconds)}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// trackerReportDuration is trackerFormatDuration without its 99 hour cap,
+// since weekly totals run past it.
+func trackerReportDuration(seconds float64) string {
+	d := time.Duration(math.Max(seconds, 0) * float64(time.Second)).Round(time.Second)
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", d/time.Hour, (d%time.Hour)/time.Minute)
+	}
+	return fmt.Sprintf("%dm%02ds", d/time.Minute, (d%time.Minute)/time.Second)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestTrackerReportRuns(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	completed := now.Add(-time.Hour)
+	acknowledged := completed.Add(10 * time.Minute)
+	live := []ipc.Task{
+		{
+			ID: "$1|@1|%1", SessionID: "$1", WindowID: "@1", Window: "api", Status: "in_progress",
+			StartedAt: now.Add(-30 * time.Minute).Format(time.RFC3339),
+			Timeline: []ipc.TimelineEvent{
+				{At: now.Add(-20 * time.Minute).Format(time.RFC3339), Kind: "waiting_input"},
+				{At: now.Add(-15 * time.Minute).Format(time.RFC3339), Kind: "answer"},
+			},
+		},
+		{ID: "$1|@1|%1#2", ParentID: "$1|@1|%1", WindowID: "@1", Status: "in_progress", StartedAt: now.Format(time.RFC3339)},
+		{ID: "$1|@2|%2", WindowID: "@2", Status: "completed", CompletedAt: completed.Format(time.RFC3339)},
+		{ID: "$1|@3|%3", WindowID: "@3", Status: "in_progress"},
+	}
+	history := []ipc.HistoryEntry{
+		{ID: "$1|@2|%2|1", WindowID: "@2", Window: "web", StartedAt: completed.Add(-time.Hour), CompletedAt: completed, DurationSeconds: 3600},
+		{ID: "$1|@4|%4|1", WindowID: "@4", Window: "docs", StartedAt: completed.Add(-time.Hour), CompletedAt: completed, DurationSeconds: 3600, WaitingSeconds: 60, AcknowledgedAt: &acknowledged},
+		{ID: "$1|@5|%5|1", WindowID: "@5", Window: "old", StartedAt: completed.Add(-time.Hour), CompletedAt: completed, DurationSeconds: 3600},
+		{ID: "$1|@1|%1#2|1", WindowID: "@1", StartedAt: completed.Add(-time.Hour), CompletedAt: completed, DurationSeconds: 3600},
+	}
+
+	runs := trackerReportRuns(history, live, now)
+	want := map[string]trackerReportRun{
+		"api":  {Duration: 1800, Waiting: 300},
+		"web":  {Duration: 3600, Review: 3600},
+		"docs": {Duration: 3600, Waiting: 60, Review: 600},
+		"old":  {Duration: 3600},
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("runs = %+v, want one each for %v", runs, want)
+	}
+	for _, run := range runs {
+		expected, ok := want[run.Window]
+		if !ok {
+			t.Errorf("unexpected run %+v", run)
+			continue
+		}
+		if run.Duration != expected.Duration || run.Waiting != expected.Waiting || run.Review != expected.Review {
+			t.Errorf("%s: duration %v, waiting %v, review %v; want %v, %v, %v",
+				run.Window, run.Duration, run.Waiting, run.Review, expected.Duration, expected.Waiting, expected.Review)
+		}
+	}
+}
+
+func TestTrackerReportRows(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local)
+	runs := []trackerReportRun{
+		{SessionID: "$1", Session: "work", WindowID: "@1", Window: "api", StartedAt: monday, Duration: 600, Waiting: 120, Review: 30},
+		{SessionID: "$1", Session: "work", WindowID: "@1", Window: "api", StartedAt: monday.Add(time.Hour), Duration: 300, Waiting: 900},
+		{SessionID: "$1", Session: "work", WindowID: "@2", StartedAt: monday.Add(-24 * time.Hour), Duration: 60, Review: -5},
+		{SessionID: "$2", WindowID: "@3", Window: "docs", StartedAt: monday.Add(24 * time.Hour), Duration: 100},
+	}
+
+	days := trackerReportRows(runs, "window", "day", nil)
+	want := []trackerReportRow{
+		{Period: "2026-03-01", Group: "@2", Runs: 1, WorkingSeconds: 60},
+		{Period: "2026-03-02", Group: "api", Runs: 2, WorkingSeconds: 480, WaitingSeconds: 420, ReviewSeconds: 30},
+		{Period: "2026-03-03", Group: "docs", Runs: 1, WorkingSeconds: 100},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("rows = %+v, want %+v", days, want)
+	}
+	for i := range want {
+		if days[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, days[i], want[i])
+		}
+	}
+
+	weeks := trackerReportRows(runs, "session", "week", nil)
+	want = []trackerReportRow{
+		{Period: "2026-W09", Group: "work", Runs: 1, WorkingSeconds: 60},
+		{Period: "2026-W10", Group: "$2", Runs: 1, WorkingSeconds: 100},
+		{Period: "2026-W10", Group: "work", Runs: 2, WorkingSeconds: 480, WaitingSeconds: 420, ReviewSeconds: 30},
+	}
+	if len(weeks) != len(want) {
+		t.Fatalf("rows = %+v, want %+v", weeks, want)
+	}
+	for i := range want {
+		if weeks[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, weeks[i], want[i])
+		}
+	}
+
+	reg := &registry{Agents: map[string]*agentRecord{"fix-login": {ID: "fix-login", TmuxWindowID: "@1"}}}
+	agents := trackerReportRows(runs[:3], "agent", "week", reg)
+	if len(agents) != 2 || agents[0].Group != "@2" || agents[1].Group != "fix-login" || agents[1].Runs != 2 {
+		t.Errorf("agent rows = %+v, want @2 and fix-login with 2 runs", agents)
+	}
+}
+
+func TestTrackerWriteReportCSV(t *testing.T) {
+	var out bytes.Buffer
+	rows := []trackerReportRow{{Period: "2026-03-02", Group: "api", Runs: 2, WorkingSeconds: 479.6, WaitingSeconds: 420, ReviewSeconds: 0.4}}
+	if err := trackerWriteReportCSV(&out, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := "period,group,runs,working_seconds,waiting_seconds,review_seconds\n2026-03-02,api,2,480,420,0\n"
+	if out.String() != want {
+		t.Errorf("csv = %q, want %q", out.String(), want)
+	}
+}
+
+func TestTrackerReportDuration(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{seconds: -3, want: "0m00s"},
+		{seconds: 59.6, want: "1m00s"},
+		{seconds: 754, want: "12m34s"},
+		{seconds: 3600, want: "1h00m"},
+		{seconds: 120 * 3600, want: "120h00m"},
+	}
+	for _, tt := range tests {
+		if got := trackerReportDuration(tt.seconds); got != tt.want {
+			t.Errorf("trackerReportDuration(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// trackerTimelineLabels names timeline event kinds for people.
+var trackerTimelineLabels = map[string]string{
+	"start":                       "started",
+	"update":                      "update",
+	"notify":                      "notified",
+	trackerTaskStatusWaitingInput: "waiting",
+	trackerTaskStatusBlocked:      "blocked",
+	trackerTaskStatusFailed:       "failed",
+	"resume":                      "resumed",
+	"answer":                      "answered",
+	"stalled":                     "stalled",
+	"finish":                      "finished",
+}
+
+// runTrackerShow prints a pane's tasks with the timeline of what each did.
+// The argument is a pane id, a task id or a window name; the current pane
+// is shown without one.
+func runTrackerShow(args []string) error {
+	fs := flagSet("agent tracker show")
+	var asJSON bool
+	fs.BoolVar(&asJSON, "json", false, "print the tasks as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if query == "" {
+		ctx, err := resolveTrackerContext("", "", "", "", "")
+		if err != nil {
+			return err
+		}
+		query = ctx.PaneID
+	}
+	env, err := trackerLoadDetailedState(nil)
+	if err != nil {
+		return err
+	}
+	tasks := trackerShowTasks(env.Tasks, query)
+	if len(tasks) == 0 {
+		return fmt.Errorf("no tasks for %s", query)
+	}
+	if asJSON {
+		out := json.NewEncoder(os.Stdout)
+		out.SetEscapeHTML(false)
+		out.SetIndent("", "  ")
+		return out.Encode(tasks)
+	}
+	return trackerWriteTimelines(os.Stdout, tasks, time.Now())
+}
+
+// trackerShowTasks picks the tasks query names, with their sub-tasks nested
+// below them.
+func trackerShowTasks(tasks []ipc.Task, query string) []ipc.Task {
+	var matched []ipc.Task
+	for _, task := range tasks {
+		if task.ID == query || task.Pane == query || task.WindowID == query || task.Window == query {
+			matched = append(matched, task)
+		}
+	}
+	trackerSortTasks(matched)
+	return trackerNestSubtasks(matched)
+}
+
+func trackerWriteTimelines(w io.Writer, tasks []ipc.Task, now time.Time) error {
+	for idx, task := range tasks {
+		if idx > 0 {
+			fmt.Fprintln(w)
+		}
+		title := firstPaletteLine(task.Summary)
+		if task.ParentID != "" {
+			title = "↳ " + title
+		}
+		fmt.Fprintln(w, title)
+		fmt.Fprintf(w, "  %s · %s / %s · %s · %s\n", trackerFirstNonEmpty(task.Status, trackerTaskStatusInProgress), task.Session, task.Window, task.Pane, trackerLiveDuration(task, now))
+		if task.PID > 0 {
+			fmt.Fprintf(w, "  reported by pid %d\n", task.PID)
+		}
+		if note := strings.TrimSpace(task.CompletionNote); note != "" {
+			fmt.Fprintf(w, "  note: %s\n", firstPaletteLine(note))
+		}
+		if len(task.Timeline) == 0 {
+			fmt.Fprintln(w, "  no timeline recorded")
+			continue
+		}
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, event := range task.Timeline {
+			when, label := trackerTimelineEvent(event, now)
+			if event.Text == "" {
+				fmt.Fprintf(tw, "  %s\t%s\n", when, label)
+				continue
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", when, label, event.Text)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trackerTimelineEvent returns when event happened, as a clock time or a
+// date for older events, and what happened.
+func trackerTimelineEvent(event ipc.TimelineEvent, now time.Time) (string, string) {
+	when := event.At
+	if at, ok := trackerParseTimestamp(event.At); ok {
+		at = at.Local()
+		if y, m, d := at.Date(); y == now.Year() && m == now.Month() && d == now.Day() {
+			when = at.Format("15:04:05")
+		} else {
+			when = at.Format("Jan 2 15:04")
+		}
+	}
+	return when, trackerFirstNonEmpty(trackerTimelineLabels[event.Kind], event.Kind)
+}
@@ -0,0 +1,608 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// usagePrice is what a model costs in USD per million tokens.
+type usagePrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+}
+
+// defaultUsagePrices are list prices keyed by model name. A date suffix on
+// the model ("claude-sonnet-4-5-20250929") is ignored when matching; models
+// not listed stay unpriced rather than guessed. agent-config.json can add or
+// replace entries under "usage_prices".
+var defaultUsagePrices = map[string]usagePrice{
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.5},
+	"claude-opus-4-1":   {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5},
+	"claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5},
+	"claude-sonnet-4-5": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.1},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+	"gpt-5":             {Input: 1.25, Output: 10, CacheRead: 0.125},
+	"gpt-5-codex":       {Input: 1.25, Output: 10, CacheRead: 0.125},
+	"gpt-5.1":           {Input: 1.25, Output: 10, CacheRead: 0.125},
+	"gpt-5.1-codex":     {Input: 1.25, Output: 10, CacheRead: 0.125},
+	"gpt-5.2":           {Input: 1.75, Output: 14, CacheRead: 0.175},
+	"gpt-5-mini":        {Input: 0.25, Output: 2, CacheRead: 0.025},
+	"codex-mini-latest": {Input: 1.5, Output: 6, CacheRead: 0.375},
+}
+
+type usageTokens struct {
+	Input      int64   `json:"input_tokens"`
+	Output     int64   `json:"output_tokens"`
+	CacheWrite int64   `json:"cache_write_tokens"`
+	CacheRead  int64   `json:"cache_read_tokens"`
+	Cost       float64 `json:"cost_usd"`
+}
+
+func (u *usageTokens) add(other usageTokens) {
+	u.Input += other.Input
+	u.Output += other.Output
+	u.CacheWrite += other.CacheWrite
+	u.CacheRead += other.CacheRead
+	u.Cost += other.Cost
+}
+
+func (u usageTokens) total() int64 {
+	return u.Input + u.Output + u.CacheWrite + u.CacheRead
+}
+
+// usageEntry is the usage of one model response, attributed to an agent by
+// the working directory it ran in.
+type usageEntry struct {
+	At     time.Time
+	Agent  string
+	Model  string
+	Tokens usageTokens
+}
+
+// usageLedger holds every entry found for the agents it was collected for,
+// oldest first per agent.
+type usageLedger struct {
+	byAgent map[string][]usageEntry
+}
+
+func (l *usageLedger) agentTotal(agentID string) usageTokens {
+	var total usageTokens
+	if l == nil {
+		return total
+	}
+	for _, entry := range l.byAgent[agentID] {
+		total.add(entry.Tokens)
+	}
+	return total
+}
+
+// between sums an agent's usage from start up to end; a zero end means now.
+func (l *usageLedger) between(agentID string, start, end time.Time) usageTokens {
+	var total usageTokens
+	if l == nil {
+		return total
+	}
+	for _, entry := range l.byAgent[agentID] {
+		if entry.At.Before(start) || (!end.IsZero() && entry.At.After(end)) {
+			continue
+		}
+		total.add(entry.Tokens)
+	}
+	return total
+}
+
+// usageCollector reads Claude Code and Codex transcripts and keeps the
+// entries whose cwd lies inside one of the agents' repo copies.
+type usageCollector struct {
+	agents []*agentRecord
+	prices map[string]usagePrice
+	since  time.Time
+	cache  *usageCache
+	ledger *usageLedger
+}
+
+func collectUsage(agents []*agentRecord) (*usageLedger, error) {
+	c := &usageCollector{
+		agents: agents,
+		prices: usagePrices(loadAppConfig()),
+		ledger: &usageLedger{byAgent: map[string][]usageEntry{}},
+	}
+	if len(agents) == 0 {
+		return c.ledger, nil
+	}
+	c.since = agents[0].CreatedAt
+	for _, record := range agents {
+		if record.CreatedAt.Before(c.since) {
+			c.since = record.CreatedAt
+		}
+	}
+	c.cache = loadUsageCache(usageCachePath())
+	if err := c.collectClaude(claudeProjectsDir()); err != nil {
+		return nil, err
+	}
+	if err := c.collectCodex(codexSessionsDir()); err != nil {
+		return nil, err
+	}
+	// A cache that cannot be written only costs a rescan next time.
+	_ = c.cache.save()
+	for id := range c.ledger.byAgent {
+		entries := c.ledger.byAgent[id]
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].At.Before(entries[j].At) })
+	}
+	return c.ledger, nil
+}
+
+func usagePrices(cfg appConfig) map[string]usagePrice {
+	prices := make(map[string]usagePrice, len(defaultUsagePrices)+len(cfg.UsagePrices))
+	for model, price := range defaultUsagePrices {
+		prices[model] = price
+	}
+	for model, price := range cfg.UsagePrices {
+		prices[usageModelKey(model)] = price
+	}
+	return prices
+}
+
+var usageModelDateSuffix = regexp.MustCompile(`-\d{8}$`)
+
+// usageModelKey is the name a model is priced under: lower case, without a
+// release date suffix.
+func usageModelKey(model string) string {
+	return usageModelDateSuffix.ReplaceAllString(strings.ToLower(strings.TrimSpace(model)), "")
+}
+
+func (c *usageCollector) priced(model string, tokens usageTokens) usageTokens {
+	price, ok := c.prices[usageModelKey(model)]
+	if !ok {
+		return tokens
+	}
+	tokens.Cost = (float64(tokens.Input)*price.Input +
+		float64(tokens.Output)*price.Output +
+		float64(tokens.CacheWrite)*price.CacheWrite +
+		float64(tokens.CacheRead)*price.CacheRead) / 1e6
+	return tokens
+}
+
+// agentFor returns the agent whose repo copy contains cwd, preferring the
+// deepest match.
+func (c *usageCollector) agentFor(cwd string) string {
+	cwd = filepath.Clean(strings.TrimSpace(cwd))
+	best, bestLen := "", 0
+	for _, record := range c.agents {
+		root := filepath.Clean(strings.TrimSpace(record.RepoCopyPath))
+		if root == "." || root == "" {
+			continue
+		}
+		if (cwd == root || strings.HasPrefix(cwd, root+string(filepath.Separator))) && len(root) > bestLen {
+			best, bestLen = record.ID, len(root)
+		}
+	}
+	return best
+}
+
+func (c *usageCollector) record(agentID, model string, at time.Time, tokens usageTokens) {
+	if agentID == "" || at.Before(c.since) || tokens.total() == 0 {
+		return
+	}
+	c.ledger.byAgent[agentID] = append(c.ledger.byAgent[agentID], usageEntry{
+		At:     at,
+		Agent:  agentID,
+		Model:  model,
+		Tokens: c.priced(model, tokens),
+	})
+}
+
+// readTranscript adds the responses in one transcript file, parsed with parse
+// unless the cache already holds them.
+func (c *usageCollector) readTranscript(path string, info fs.FileInfo, parse func(io.Reader) ([]usageRecord, error)) error {
+	records, err := c.cache.records(path, info, parse)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		c.record(c.agentFor(r.Cwd), r.Model, r.At, r.Tokens)
+	}
+	return nil
+}
+
+var claudeProjectNamePattern = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+func claudeProjectsDir() string {
+	if dir := strings.TrimSpace(os.Getenv("CLAUDE_CONFIG_DIR")); dir != "" {
+		return filepath.Join(dir, "projects")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".claude", "projects")
+}
+
+// collectClaude reads the project directories Claude Code keeps for the
+// agents' repo copies. Each directory is named after the session cwd with
+// every non-alphanumeric character replaced by "-".
+func (c *usageCollector) collectClaude(root string) error {
+	dirs, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var prefixes []string
+	for _, record := range c.agents {
+		if path := strings.TrimSpace(record.RepoCopyPath); path != "" {
+			prefixes = append(prefixes, claudeProjectNamePattern.ReplaceAllString(filepath.Clean(path), "-"))
+		}
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || !usageHasAnyPrefix(dir.Name(), prefixes) {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(root, dir.Name(), "*.jsonl"))
+		if err != nil {
+			return err
+		}
+		for _, path := range files {
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Before(c.since) {
+				continue
+			}
+			if err := c.readTranscript(path, info, parseClaudeTranscript); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseClaudeTranscript reads the usage of every response in a Claude Code
+// transcript.
+func parseClaudeTranscript(r io.Reader) ([]usageRecord, error) {
+	// A response is logged once per content block, each line repeating the
+	// same usage, so only the first line of every message counts.
+	seen := map[string]bool{}
+	var records []usageRecord
+	err := scanJSONLines(r, func(line []byte) {
+		var entry struct {
+			Type      string    `json:"type"`
+			Cwd       string    `json:"cwd"`
+			Timestamp time.Time `json:"timestamp"`
+			Message   struct {
+				ID    string `json:"id"`
+				Model string `json:"model"`
+				Usage *struct {
+					Input      int64 `json:"input_tokens"`
+					Output     int64 `json:"output_tokens"`
+					CacheWrite int64 `json:"cache_creation_input_tokens"`
+					CacheRead  int64 `json:"cache_read_input_tokens"`
+				} `json:"usage"`
+			} `json:"message"`
+		}
+		if json.Unmarshal(line, &entry) != nil || entry.Type != "assistant" || entry.Message.Usage == nil {
+			return
+		}
+		if id := entry.Message.ID; id != "" {
+			if seen[id] {
+				return
+			}
+			seen[id] = true
+		}
+		usage := entry.Message.Usage
+		records = appendUsageRecord(records, usageRecord{
+			At:    entry.Timestamp,
+			Cwd:   entry.Cwd,
+			Model: entry.Message.Model,
+			Tokens: usageTokens{
+				Input:      usage.Input,
+				Output:     usage.Output,
+				CacheWrite: usage.CacheWrite,
+				CacheRead:  usage.CacheRead,
+			},
+		})
+	})
+	return records, err
+}
+
+func codexSessionsDir() string {
+	if dir := strings.TrimSpace(os.Getenv("CODEX_HOME")); dir != "" {
+		return filepath.Join(dir, "sessions")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".codex", "sessions")
+}
+
+// collectCodex reads Codex rollout files, which log a token_count event with
+// the usage of the last request after every model call.
+func (c *usageCollector) collectCodex(root string) error {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".jsonl") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.ModTime().Before(c.since) {
+			return nil
+		}
+		return c.readTranscript(path, info, parseCodexRollout)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// parseCodexRollout reads the usage of every request in a Codex rollout
+// file. The cwd and model come from the session and turn context lines
+// before each count.
+func parseCodexRollout(r io.Reader) ([]usageRecord, error) {
+	cwd, model := "", ""
+	var lastTotal int64 = -1
+	var records []usageRecord
+	err := scanJSONLines(r, func(line []byte) {
+		var entry struct {
+			Type      string    `json:"type"`
+			Timestamp time.Time `json:"timestamp"`
+			Payload   struct {
+				Type  string `json:"type"`
+				Cwd   string `json:"cwd"`
+				Model string `json:"model"`
+				Info  *struct {
+					Total struct {
+						Total int64 `json:"total_tokens"`
+					} `json:"total_token_usage"`
+					Last struct {
+						Input     int64 `json:"input_tokens"`
+						CacheRead int64 `json:"cached_input_tokens"`
+						Output    int64 `json:"output_tokens"`
+					} `json:"last_token_usage"`
+				} `json:"info"`
+			} `json:"payload"`
+		}
+		if json.Unmarshal(line, &entry) != nil {
+			return
+		}
+		switch entry.Type {
+		case "session_meta", "turn_context":
+			if entry.Payload.Cwd != "" {
+				cwd = entry.Payload.Cwd
+			}
+			if entry.Payload.Model != "" {
+				model = entry.Payload.Model
+			}
+		case "event_msg":
+			info := entry.Payload.Info
+			if entry.Payload.Type != "token_count" || info == nil || cwd == "" {
+				return
+			}
+			// The same count is sometimes logged twice; a request always
+			// moves the running total.
+			if info.Total.Total == lastTotal {
+				return
+			}
+			lastTotal = info.Total.Total
+			// Codex counts cached input inside input_tokens.
+			records = appendUsageRecord(records, usageRecord{
+				At:    entry.Timestamp,
+				Cwd:   cwd,
+				Model: model,
+				Tokens: usageTokens{
+					Input:     info.Last.Input - info.Last.CacheRead,
+					Output:    info.Last.Output,
+					CacheRead: info.Last.CacheRead,
+				},
+			})
+		}
+	})
+	return records, err
+}
+
+func appendUsageRecord(records []usageRecord, r usageRecord) []usageRecord {
+	if r.Tokens.total() == 0 {
+		return records
+	}
+	return append(records, r)
+}
+
+func scanJSONLines(r io.Reader, fn func([]byte)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 32*1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(line) > 0 && line[0] == '{' {
+			fn(line)
+		}
+	}
+	return scanner.Err()
+}
+
+func usageHasAnyPrefix(value string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// usageAgentForWindow returns the registered agent that owns a tmux window.
+func usageAgentForWindow(reg *registry, windowID string) *agentRecord {
+	if reg == nil || strings.TrimSpace(windowID) == "" {
+		return nil
+	}
+	for _, record := range reg.Agents {
+		if record.TmuxWindowID == windowID {
+			return record
+		}
+	}
+	return nil
+}
+
+func formatUsageTokens(count int64) string {
+	switch {
+	case count >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(count)/1e6)
+	case count >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(count)/1e3)
+	}
+	return fmt.Sprintf("%d", count)
+}
+
+func formatUsageCost(cost float64) string {
+	return fmt.Sprintf("$%.2f", cost)
+}
+
+// formatUsageSummary renders usage as "1.2M tok · $3.40".
+func formatUsageSummary(u usageTokens) string {
+	return formatUsageTokens(u.total()) + " tok · " + formatUsageCost(u.Cost)
+}
+
+type usageReportAgent struct {
+	ID    string            `json:"id"`
+	Usage usageTokens       `json:"usage"`
+	Tasks []usageReportTask `json:"tasks,omitempty"`
+}
+
+type usageReportTask struct {
+	Summary     string      `json:"summary"`
+	StartedAt   time.Time   `json:"started_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+	Usage       usageTokens `json:"usage"`
+}
+
+// runUsage prints token use and estimated cost per agent, most expensive
+// first, with a breakdown per tracker task on request.
+func runUsage(args []string) error {
+	fs := flagSet("agent usage")
+	var showAll, showTasks, asJSON bool
+	var since string
+	fs.BoolVar(&showAll, "all", false, "include agents from every repo")
+	fs.BoolVar(&showTasks, "tasks", false, "break usage down by tracker task")
+	fs.BoolVar(&asJSON, "json", false, "print JSON instead of a table")
+	fs.StringVar(&since, "since", "", "only count usage at or after this time (same formats as tracker history --since)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	sinceTime, err := trackerParseHistoryTime(since, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	reg, err := loadRegistry()
+	if err != nil {
+		return err
+	}
+	repoScope := ""
+	if !showAll {
+		if repoScope, err = repoRoot(); err != nil {
+			return fmt.Errorf("agent usage defaults to the current repo; run inside a git repo or use --all")
+		}
+	}
+	var agents []*agentRecord
+	for _, id := range sortedAgentIDs(reg) {
+		record := reg.Agents[id]
+		if repoScope != "" && filepath.Clean(record.RepoRoot) != filepath.Clean(repoScope) {
+			continue
+		}
+		agents = append(agents, record)
+	}
+	ledger, err := collectUsage(agents)
+	if err != nil {
+		return err
+	}
+	var history []ipc.HistoryEntry
+	if showTasks {
+		if history, err = trackerLoadHistory(); err != nil {
+			return err
+		}
+	}
+	report := make([]usageReportAgent, 0, len(agents))
+	for _, record := range agents {
+		row := usageReportAgent{ID: record.ID, Usage: ledger.between(record.ID, sinceTime, time.Time{})}
+		if showTasks {
+			row.Tasks = usageTasksFor(ledger, record, history, sinceTime)
+		}
+		report = append(report, row)
+	}
+	sort.SliceStable(report, func(i, j int) bool { return report[i].Usage.Cost > report[j].Usage.Cost })
+	if asJSON {
+		out := json.NewEncoder(os.Stdout)
+		out.SetEscapeHTML(false)
+		out.SetIndent("", "  ")
+		return out.Encode(report)
+	}
+	return writeUsageTable(os.Stdout, report)
+}
+
+// usageTasksFor attributes usage to the agent's tracker tasks: archived runs
+// from history plus whatever is live in the tracker now.
+func usageTasksFor(ledger *usageLedger, record *agentRecord, history []ipc.HistoryEntry, since time.Time) []usageReportTask {
+	var tasks []usageReportTask
+	for _, entry := range history {
+		if entry.WindowID != record.TmuxWindowID || entry.StartedAt.Before(record.CreatedAt) || entry.CompletedAt.Before(since) {
+			continue
+		}
+		completed := entry.CompletedAt
+		tasks = append(tasks, usageReportTask{
+			Summary:     firstPaletteLine(entry.Summary),
+			StartedAt:   entry.StartedAt,
+			CompletedAt: &completed,
+			Usage:       ledger.between(record.ID, entry.StartedAt, entry.CompletedAt),
+		})
+	}
+	if env, err := trackerLoadState("", &ipc.Filter{WindowID: record.TmuxWindowID}); err == nil {
+		for _, task := range env.Tasks {
+			started, ok := trackerParseTimestamp(task.StartedAt)
+			if !ok || trackerTaskIsFinal(task.Status) {
+				continue
+			}
+			tasks = append(tasks, usageReportTask{
+				Summary:   firstPaletteLine(task.Summary),
+				StartedAt: started,
+				Usage:     ledger.between(record.ID, started, time.Time{}),
+			})
+		}
+	}
+	return tasks
+}
+
+func writeUsageTable(w io.Writer, report []usageReportAgent) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGENT\tINPUT\tOUTPUT\tCACHE WRITE\tCACHE READ\tCOST")
+	var total usageTokens
+	for _, row := range report {
+		total.add(row.Usage)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", row.ID,
+			formatUsageTokens(row.Usage.Input), formatUsageTokens(row.Usage.Output),
+			formatUsageTokens(row.Usage.CacheWrite), formatUsageTokens(row.Usage.CacheRead),
+			formatUsageCost(row.Usage.Cost))
+		for _, task := range row.Tasks {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", truncate(task.Summary, 50),
+				formatUsageTokens(task.Usage.Input), formatUsageTokens(task.Usage.Output),
+				formatUsageTokens(task.Usage.CacheWrite), formatUsageTokens(task.Usage.CacheRead),
+				formatUsageCost(task.Usage.Cost))
+		}
+	}
+	if len(report) > 1 {
+		fmt.Fprintf(tw, "total\t%s\t%s\t%s\t%s\t%s\n",
+			formatUsageTokens(total.Input), formatUsageTokens(total.Output),
+			formatUsageTokens(total.CacheWrite), formatUsageTokens(total.CacheRead),
+			formatUsageCost(total.Cost))
+	}
+	return tw.Flush()
+}
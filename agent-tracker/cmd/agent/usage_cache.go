@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// usageRecord is one model response as read from a transcript, before it is
+// attributed to an agent and priced.
+type usageRecord struct {
+	At     time.Time   `json:"at"`
+	Cwd    string      `json:"cwd,omitempty"`
+	Model  string      `json:"model,omitempty"`
+	Tokens usageTokens `json:"tokens"`
+}
+
+// usageCache keeps the records parsed from each transcript, keyed by path and
+// checked against the file's size and modification time, so only transcripts
+// that changed since the last run are read again.
+type usageCache struct {
+	path  string
+	Files map[string]usageCachedFile `json:"files"`
+	dirty bool
+}
+
+type usageCachedFile struct {
+	Size    int64         `json:"size"`
+	ModTime time.Time     `json:"mod_time"`
+	Records []usageRecord `json:"records,omitempty"`
+}
+
+func usageCachePath() string {
+	return filepath.Join(os.Getenv("HOME"), ".cache", "agent", "usage.json")
+}
+
+// loadUsageCache reads the cache at path. A missing or unreadable cache
+// starts empty.
+func loadUsageCache(path string) *usageCache {
+	cache := &usageCache{path: path}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, cache)
+	}
+	if cache.Files == nil {
+		cache.Files = map[string]usageCachedFile{}
+	}
+	return cache
+}
+
+// records returns the cached records for path while info still matches the
+// cached file, and otherwise parses the file and caches the result.
+func (c *usageCache) records(path string, info fs.FileInfo, parse func(io.Reader) ([]usageRecord, error)) ([]usageRecord, error) {
+	if cached, ok := c.Files[path]; ok && cached.Size == info.Size() && cached.ModTime.Equal(info.ModTime()) {
+		return cached.Records, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	records, err := parse(file)
+	if err != nil {
+		return nil, err
+	}
+	c.Files[path] = usageCachedFile{Size: info.Size(), ModTime: info.ModTime(), Records: records}
+	c.dirty = true
+	return records, nil
+}
+
+// save drops entries for transcripts that no longer exist and writes the
+// cache back when anything changed.
+func (c *usageCache) save() error {
+	for path := range c.Files {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(c.Files, path)
+			c.dirty = true
+		}
+	}
+	if !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	// The panel and the CLI may save at the same time; each writes its own
+	// temp file and the last rename wins.
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), "usage-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	c.dirty = false
+	return nil
+}
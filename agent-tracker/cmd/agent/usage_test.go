@@ -0,0 +1,206 @@
+package main
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageCollectorAgentFor(t *testing.T) {
+	c := &usageCollector{agents: []*agentRecord{
+		{ID: "api", RepoCopyPath: "/work/.agents/api/repo"},
+		{ID: "api-docs", RepoCopyPath: "/work/.agents/api/repo/docs"},
+		{ID: "web", RepoCopyPath: "/work/.agents/web/repo/"},
+		{ID: "empty"},
+	}}
+	tests := map[string]string{
+		"/work/.agents/api/repo":             "api",
+		"/work/.agents/api/repo/cmd/server":  "api",
+		"/work/.agents/api/repo/docs/guides": "api-docs",
+		"/work/.agents/api/repository":       "",
+		"/work/.agents/web/repo":             "web",
+		"":                                   "",
+		"/elsewhere":                         "",
+	}
+	for cwd, want := range tests {
+		if got := c.agentFor(cwd); got != want {
+			t.Errorf("agentFor(%q) = %q, want %q", cwd, got, want)
+		}
+	}
+}
+
+func TestUsageCollectorPriced(t *testing.T) {
+	c := &usageCollector{prices: usagePrices(appConfig{UsagePrices: map[string]usagePrice{
+		" My-Model ":        {Input: 2},
+		"claude-sonnet-4-5": {Input: 1, Output: 1},
+	}})}
+	tokens := usageTokens{Input: 1_000_000, Output: 1_000_000, CacheWrite: 1_000_000, CacheRead: 1_000_000}
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{model: "claude-opus-4-5-20251101", want: 5 + 25 + 6.25 + 0.5},
+		{model: "claude-opus-4-1-20250805", want: 15 + 75 + 18.75 + 1.5},
+		{model: "claude-haiku-4-5", want: 1 + 5 + 1.25 + 0.1},
+		{model: "gpt-5-codex", want: 1.25 + 10 + 0.125},
+		{model: "claude-sonnet-4-5-20250929", want: 2},
+		{model: "my-model", want: 2},
+		{model: "claude-opus-9", want: 0},
+		{model: "gpt-5-nano", want: 0},
+		{model: "", want: 0},
+	}
+	for _, tt := range tests {
+		got := c.priced(tt.model, tokens)
+		if math.Abs(got.Cost-tt.want) > 1e-9 {
+			t.Errorf("priced(%q) cost = %v, want %v", tt.model, got.Cost, tt.want)
+		}
+		if got.total() != tokens.total() {
+			t.Errorf("priced(%q) changed the token counts", tt.model)
+		}
+	}
+}
+
+func TestParseClaudeTranscript(t *testing.T) {
+	transcript := `{"type":"user","cwd":"/work/api","message":{"role":"user","content":"hi"},"timestamp":"2026-03-01T09:00:00Z"}
+{"type":"assistant","cwd":"/work/api","timestamp":"2026-03-01T09:00:05Z","message":{"id":"msg_01","model":"claude-sonnet-4-5-20250929","content":[{"type":"thinking"}],"usage":{"input_tokens":10,"output_tokens":20,"cache_creation_input_tokens":30,"cache_read_input_tokens":40}}}
+{"type":"assistant","cwd":"/work/api","timestamp":"2026-03-01T09:00:06Z","message":{"id":"msg_01","model":"claude-sonnet-4-5-20250929","content":[{"type":"text"}],"usage":{"input_tokens":10,"output_tokens":20,"cache_creation_input_tokens":30,"cache_read_input_tokens":40}}}
+{"type":"assistant","cwd":"/work/api","timestamp":"2026-03-01T09:01:00Z","message":{"id":"msg_02","model":"<synthetic>","usage":{"input_tokens":0,"output_tokens":0}}}
+{"type":"assistant","cwd":"/work/api/sub","timestamp":"2026-03-01T09:02:00Z","message":{"id":"msg_03","model":"claude-sonnet-4-5-20250929","usage":{"input_tokens":1,"output_tokens":2}}}
+`
+	records, err := parseClaudeTranscript(strings.NewReader(transcript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %+v, want two", records)
+	}
+	if got := records[0]; got.Cwd != "/work/api" || got.Tokens.total() != 100 || !got.At.Equal(time.Date(2026, 3, 1, 9, 0, 5, 0, time.UTC)) {
+		t.Errorf("first record = %+v", got)
+	}
+	if got := records[1]; got.Cwd != "/work/api/sub" || got.Tokens.Output != 2 {
+		t.Errorf("second record = %+v", got)
+	}
+}
+
+func TestParseCodexRollout(t *testing.T) {
+	rollout := `{"timestamp":"2026-03-01T09:00:00Z","type":"session_meta","payload":{"id":"0199","cwd":"/work/api","originator":"codex_cli_rs"}}
+{"timestamp":"2026-03-01T09:00:01Z","type":"event_msg","payload":{"type":"token_count","info":null}}
+{"timestamp":"2026-03-01T09:00:02Z","type":"turn_context","payload":{"cwd":"/work/api","model":"gpt-5-codex"}}
+{"timestamp":"2026-03-01T09:00:10Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"total_tokens":150},"last_token_usage":{"input_tokens":100,"cached_input_tokens":60,"output_tokens":50}}}}
+{"timestamp":"2026-03-01T09:00:11Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"total_tokens":150},"last_token_usage":{"input_tokens":100,"cached_input_tokens":60,"output_tokens":50}}}}
+{"timestamp":"2026-03-01T09:01:00Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"total_tokens":180},"last_token_usage":{"input_tokens":20,"cached_input_tokens":0,"output_tokens":10}}}}
+`
+	records, err := parseCodexRollout(strings.NewReader(rollout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %+v, want two", records)
+	}
+	first := records[0]
+	if first.Cwd != "/work/api" || first.Model != "gpt-5-codex" || first.Tokens.Input != 40 || first.Tokens.CacheRead != 60 || first.Tokens.Output != 50 {
+		t.Errorf("first record = %+v, want cached input split out", first)
+	}
+}
+
+func TestUsageCacheRecords(t *testing.T) {
+	dir := t.TempDir()
+	transcript := filepath.Join(dir, "session.jsonl")
+	writeAgentTestFile(t, transcript, "one\n")
+	parses := 0
+	parse := func(r io.Reader) ([]usageRecord, error) {
+		parses++
+		data, err := io.ReadAll(r)
+		return []usageRecord{{Model: strings.TrimSpace(string(data)), Tokens: usageTokens{Input: 1}}}, err
+	}
+	read := func(cache *usageCache) string {
+		t.Helper()
+		info, err := os.Stat(transcript)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := cache.records(transcript, info, parse)
+		if err != nil || len(records) != 1 {
+			t.Fatalf("records = %+v, %v", records, err)
+		}
+		return records[0].Model
+	}
+
+	cachePath := filepath.Join(dir, "cache", "usage.json")
+	cache := loadUsageCache(cachePath)
+	if got := read(cache); got != "one" || parses != 1 {
+		t.Fatalf("first read = %q after %d parses", got, parses)
+	}
+	if err := cache.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cache = loadUsageCache(cachePath)
+	if got := read(cache); got != "one" || parses != 1 {
+		t.Errorf("unchanged file = %q after %d parses, want it served from the cache", got, parses)
+	}
+
+	writeAgentTestFile(t, transcript, "two, longer\n")
+	if got := read(cache); got != "two, longer" || parses != 2 {
+		t.Errorf("changed file = %q after %d parses, want it read again", got, parses)
+	}
+
+	if err := os.Remove(transcript); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.save(); err != nil {
+		t.Fatal(err)
+	}
+	if files := loadUsageCache(cachePath).Files; len(files) != 0 {
+		t.Errorf("cache after the transcript was deleted = %v, want empty", files)
+	}
+}
+
+func TestUsageLedgerBetween(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2026, 3, 1, 9, minute, 0, 0, time.UTC) }
+	ledger := &usageLedger{byAgent: map[string][]usageEntry{"api": {
+		{At: at(0), Tokens: usageTokens{Input: 1, Cost: 1}},
+		{At: at(10), Tokens: usageTokens{Input: 2, Cost: 2}},
+		{At: at(20), Tokens: usageTokens{Input: 4, Cost: 4}},
+	}}}
+	tests := []struct {
+		start, end time.Time
+		want       int64
+	}{
+		{start: at(0), want: 7},
+		{start: at(5), end: at(15), want: 2},
+		{start: at(10), end: at(20), want: 6},
+		{start: at(30), want: 0},
+	}
+	for _, tt := range tests {
+		if got := ledger.between("api", tt.start, tt.end); got.Input != tt.want {
+			t.Errorf("between(%v, %v) = %d, want %d", tt.start, tt.end, got.Input, tt.want)
+		}
+	}
+	if got := ledger.agentTotal("api"); got.Cost != 7 {
+		t.Errorf("agentTotal cost = %v, want 7", got.Cost)
+	}
+	if got := (*usageLedger)(nil).agentTotal("api"); got.total() != 0 {
+		t.Errorf("nil ledger total = %+v", got)
+	}
+}
+
+func TestFormatUsageSummary(t *testing.T) {
+	tests := []struct {
+		usage usageTokens
+		want  string
+	}{
+		{usage: usageTokens{Input: 999}, want: "999 tok · $0.00"},
+		{usage: usageTokens{Input: 1000, Output: 500, Cost: 0.125}, want: "1.5k tok · $0.12"},
+		{usage: usageTokens{CacheRead: 1_200_000, Cost: 3.4}, want: "1.2M tok · $3.40"},
+	}
+	for _, tt := range tests {
+		if got := formatUsageSummary(tt.usage); got != tt.want {
+			t.Errorf("formatUsageSummary(%+v) = %q, want %q", tt.usage, got, tt.want)
+		}
+	}
+}
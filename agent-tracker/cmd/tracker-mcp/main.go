@@ -36,7 +36,15 @@ func newTrackerClient() *trackerClient {
 }
 
 func (c *trackerClient) sendCommand(ctx context.Context, env ipc.Envelope) error {
+	_, err := c.request(ctx, env)
+	return err
+}
+
+// request sends a command and returns the server's ack, which carries the
+// task ID for start_task and push_task.
+func (c *trackerClient) request(ctx context.Context, env ipc.Envelope) (ipc.Envelope, error) {
 	env.Kind = "command"
+	env.ID = fmt.Sprintf("mcp-%d-%d", os.Getpid(), time.Now().UnixNano())
 	d := net.Dialer{}
 	if _, ok := ctx.Deadline(); !ok {
 		var cancel context.CancelFunc
@@ -45,13 +53,13 @@ func (c *trackerClient) sendCommand(ctx context.Context, env ipc.Envelope) error
 	}
 	conn, err := d.DialContext(ctx, "unix", c.socket)
 	if err != nil {
-		return err
+		return ipc.Envelope{}, err
 	}
 	defer conn.Close()
 
 	if deadline, ok := ctx.Deadline(); ok {
 		if err := conn.SetDeadline(deadline); err != nil {
-			return err
+			return ipc.Envelope{}, err
 		}
 	}
 
@@ -59,65 +67,523 @@ func (c *trackerClient) sendCommand(ctx context.Context, env ipc.Envelope) error
 	dec := json.NewDecoder(conn)
 
 	if err := enc.Encode(&env); err != nil {
-		return err
+		return ipc.Envelope{}, err
 	}
 
 	for {
 		var reply ipc.Envelope
 		if err := dec.Decode(&reply); err != nil {
 			if errors.Is(err, net.ErrClosed) {
-				return fmt.Errorf("tracker server disconnected")
+				return ipc.Envelope{}, fmt.Errorf("tracker server disconnected")
+			}
+			return ipc.Envelope{}, err
+		}
+		if reply.ID != "" && reply.ID != env.ID {
+			continue
+		}
+		switch reply.Kind {
+		case "ack":
+			return reply, nil
+		case "error":
+			if reply.Error == nil {
+				return ipc.Envelope{}, fmt.Errorf("tracker rejected %s", env.Command)
+			}
+			return ipc.Envelope{}, reply.Error
+		}
+	}
+}
+
+// watchState follows the tracker state narrowed by filter, calling fn with
+// each snapshot until fn reports done, fn fails or ctx ends.
+func (c *trackerClient) watchState(ctx context.Context, filter *ipc.Filter, fn func(ipc.Envelope) (bool, error)) error {
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, "unix", c.socket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	register := ipc.Envelope{
+		Kind:   "ui-register",
+		Client: fmt.Sprintf("mcp-%d", os.Getpid()),
+		Filter: filter,
+	}
+	if err := json.NewEncoder(conn).Encode(&register); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(conn)
+	for {
+		var env ipc.Envelope
+		if err := dec.Decode(&env); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
 			}
+			return fmt.Errorf("tracker server disconnected")
+		}
+		if env.Kind != "state" {
+			continue
+		}
+		done, err := fn(env)
+		if err != nil || done {
 			return err
 		}
-		if reply.Kind == "ack" {
-			return nil
+	}
+}
+
+// currentTask returns the task target's pane reports to, or nil.
+func (c *trackerClient) currentTask(ctx context.Context, target tmuxContext) (*ipc.Task, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+	}
+	var found *ipc.Task
+	err := c.watchState(ctx, windowFilter(target), func(env ipc.Envelope) (bool, error) {
+		found = paneTask(env.Tasks, target.PaneID)
+		return true, nil
+	})
+	return found, err
+}
+
+// waitForAnswer blocks until the question with questionID is answered,
+// withdrawn or ctx ends.
+func (c *trackerClient) waitForAnswer(ctx context.Context, target tmuxContext, questionID string) (string, error) {
+	var answer string
+	err := c.watchState(ctx, windowFilter(target), func(env ipc.Envelope) (bool, error) {
+		task := questionTask(env.Tasks, questionID)
+		if task == nil {
+			return false, fmt.Errorf("question was withdrawn")
+		}
+		if task.Question.AnsweredAt == "" {
+			return false, nil
 		}
+		answer = task.Question.Answer
+		return true, nil
+	})
+	return answer, err
+}
+
+// unreadMessages returns the mailbox messages waiting for target's window.
+func (c *trackerClient) unreadMessages(ctx context.Context, target tmuxContext) ([]ipc.Message, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
 	}
+	var messages []ipc.Message
+	err := c.watchState(ctx, windowFilter(target), func(env ipc.Envelope) (bool, error) {
+		messages = env.Messages
+		return true, nil
+	})
+	return messages, err
+}
+
+func windowFilter(target tmuxContext) *ipc.Filter {
+	return &ipc.Filter{SessionID: target.SessionID, WindowID: target.WindowID}
+}
+
+// paneTask mirrors the server's choice of task for a command that names
+// none: the pane's most recently started open sub-task, else its root task.
+func paneTask(tasks []ipc.Task, paneID string) *ipc.Task {
+	var root, top *ipc.Task
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Pane != paneID {
+			continue
+		}
+		if task.ParentID == "" {
+			root = task
+			continue
+		}
+		if isFinalStatus(task.Status) {
+			continue
+		}
+		// Timestamps on the wire have second precision; sub-task IDs end in
+		// the start time in nanoseconds and break ties.
+		if top == nil || task.StartedAt > top.StartedAt || (task.StartedAt == top.StartedAt && task.ID > top.ID) {
+			top = task
+		}
+	}
+	if top != nil {
+		return top
+	}
+	return root
+}
+
+func questionTask(tasks []ipc.Task, questionID string) *ipc.Task {
+	for i := range tasks {
+		if tasks[i].Question != nil && tasks[i].Question.ID == questionID {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+func isFinalStatus(status string) bool {
+	return status == "completed" || status == "failed"
 }
 
 type startInput struct {
 	Summary string `json:"summary"`
-	TmuxID  string `json:"tmux_id"`
+	Subtask bool   `json:"subtask,omitempty" jsonschema:"push a sub-task on top of the pane's current task, which stays in progress, instead of replacing it"`
+	paneTarget
+}
+
+type startOutput struct {
+	TaskID string `json:"task_id" jsonschema:"pass as task_id to finish or update this task"`
+}
+
+type finishInput struct {
+	Note   string `json:"note,omitempty" jsonschema:"what was done, shown with the completion notification"`
+	TaskID string `json:"task_id,omitempty" jsonschema:"the id tracker_mark_start_working returned; the pane's innermost open task when omitted"`
+	paneTarget
+}
+
+type updateInput struct {
+	Summary string `json:"summary" jsonschema:"the new one-line description of the task"`
+	TaskID  string `json:"task_id,omitempty" jsonschema:"the id tracker_mark_start_working returned; the pane's innermost open task when omitted"`
+	paneTarget
+}
+
+type progressInput struct {
+	Done    int     `json:"done,omitempty" jsonschema:"how many steps are done"`
+	Total   int     `json:"total,omitempty" jsonschema:"how many steps there are in all"`
+	Percent float64 `json:"percent,omitempty" jsonschema:"share done from 0 to 100, for work that is not counted in steps"`
+	Label   string  `json:"label,omitempty" jsonschema:"what is being counted, for example migrations"`
+	TaskID  string  `json:"task_id,omitempty" jsonschema:"the id tracker_mark_start_working returned; the pane's innermost open task when omitted"`
+	paneTarget
+}
+
+type checklistInput struct {
+	Items  []string `json:"items" jsonschema:"the step names in order"`
+	TaskID string   `json:"task_id,omitempty" jsonschema:"the id tracker_mark_start_working returned; the pane's innermost open task when omitted"`
+	paneTarget
+}
+
+type checkItemsInput struct {
+	Items   []string `json:"items" jsonschema:"names of the steps to check off; unknown names are added to the checklist"`
+	Uncheck bool     `json:"uncheck,omitempty" jsonschema:"mark the steps not done instead"`
+	TaskID  string   `json:"task_id,omitempty" jsonschema:"the id tracker_mark_start_working returned; the pane's innermost open task when omitted"`
+	paneTarget
+}
+
+type notifyInput struct {
+	Message string `json:"message" jsonschema:"the notification text"`
+	paneTarget
+}
+
+// paneTarget names the pane a tool acts for; every tool input embeds it.
+type paneTarget struct {
+	TmuxID string `json:"tmux_id,omitempty" jsonschema:"session_id::window_id::pane_id of the agent's pane (for example, $3::@12::%30); detected from the environment when omitted"`
+}
+
+type targetInput struct {
+	paneTarget
+}
+
+type askInput struct {
+	Question       string   `json:"question" jsonschema:"the question to put to the user"`
+	Options        []string `json:"options,omitempty" jsonschema:"suggested answers; the user may still reply in their own words"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty" jsonschema:"how long to wait for an answer (default 600)"`
+	paneTarget
+}
+
+type postMessageInput struct {
+	To   string `json:"to" jsonschema:"recipient agent id, which is also its tmux window name (for example, backend)"`
+	Text string `json:"text" jsonschema:"the message"`
+	paneTarget
+}
+
+type listMessagesInput struct {
+	MarkRead bool `json:"mark_read,omitempty" jsonschema:"mark the returned messages as read"`
+	paneTarget
+}
+
+type markReadInput struct {
+	IDs []string `json:"ids,omitempty" jsonschema:"message ids to mark read; all unread messages when omitted"`
+	paneTarget
+}
+
+const defaultAskTimeout = 10 * time.Minute
+
+func textResult(text string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+	}
+}
+
+// sendForPane resolves tmuxID and sends command for that pane.
+func (c *trackerClient) sendForPane(ctx context.Context, tmuxID, command string, env ipc.Envelope) error {
+	_, err := c.requestForPane(ctx, tmuxID, command, env)
+	return err
+}
+
+func (c *trackerClient) requestForPane(ctx context.Context, tmuxID, command string, env ipc.Envelope) (ipc.Envelope, error) {
+	target, err := resolveContext(tmuxID)
+	if err != nil {
+		return ipc.Envelope{}, err
+	}
+	env.Command = command
+	env.SessionID = target.SessionID
+	env.WindowID = target.WindowID
+	env.Pane = target.PaneID
+	return c.request(ctx, env)
 }
 
 func main() {
 	log.SetFlags(0)
 	client := newTrackerClient()
 
-	server := mcp.NewServer(&mcp.Implementation{Name: implementationName, Version: implementationVersion}, nil)
+	server := mcp.NewServer(&mcp.Implementation{Name: implementationName, Version: implementationVersion}, &mcp.ServerOptions{
+		SubscribeHandler:   func(context.Context, *mcp.SubscribeRequest) error { return nil },
+		UnsubscribeHandler: func(context.Context, *mcp.UnsubscribeRequest) error { return nil },
+	})
+	resources := newResourceFeed(client, server)
+	resources.register()
+	go resources.follow(context.Background())
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "tracker_mark_start_working",
-		Description: "Record that work has started for the specified tmux session/window/pane.",
-	}, func(ctx context.Context, _ *mcp.CallToolRequest, input startInput) (*mcp.CallToolResult, any, error) {
-		tmuxID := strings.TrimSpace(input.TmuxID)
-		if tmuxID == "" {
-			return nil, nil, fmt.Errorf("tmux_id is required; pass session_id::window_id::pane_id (for example, $3::@12::%%30)")
+		Description: "Record that work has started for the specified tmux session/window/pane and return the task id. A sub-agent sharing the pane sets subtask so its work does not overwrite the parent task.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input startInput) (*mcp.CallToolResult, startOutput, error) {
+		summary := strings.TrimSpace(input.Summary)
+		if summary == "" {
+			return nil, startOutput{}, fmt.Errorf("summary is required")
+		}
+		command := "start_task"
+		if input.Subtask {
+			command = "push_task"
 		}
-		target, err := determineContext(tmuxID)
+		reply, err := client.requestForPane(ctx, input.TmuxID, command, ipc.Envelope{Summary: summary})
 		if err != nil {
+			return nil, startOutput{}, err
+		}
+		return nil, startOutput{TaskID: reply.TaskID}, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_mark_finished",
+		Description: "Record that the current task is done, with an optional completion note. Call this at the end of every turn that started a task.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input finishInput) (*mcp.CallToolResult, any, error) {
+		if err := client.sendForPane(ctx, input.TmuxID, "finish_task", ipc.Envelope{TaskID: strings.TrimSpace(input.TaskID), Summary: strings.TrimSpace(input.Note)}); err != nil {
 			return nil, nil, err
 		}
+		return textResult("Task marked finished."), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_update_summary",
+		Description: "Replace the summary of the current task when the work changes direction. Also counts as progress for stall detection.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input updateInput) (*mcp.CallToolResult, any, error) {
 		summary := strings.TrimSpace(input.Summary)
 		if summary == "" {
 			return nil, nil, fmt.Errorf("summary is required")
 		}
+		if err := client.sendForPane(ctx, input.TmuxID, "update_task", ipc.Envelope{TaskID: strings.TrimSpace(input.TaskID), Summary: summary}); err != nil {
+			return nil, nil, err
+		}
+		return textResult("Summary updated."), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_set_progress",
+		Description: "Report how far the current task has come, as done out of total steps or as a percent. Send all zeros to clear it. Also counts as progress for stall detection.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input progressInput) (*mcp.CallToolResult, any, error) {
+		progress := &ipc.Progress{Done: input.Done, Total: input.Total, Percent: input.Percent, Label: strings.TrimSpace(input.Label)}
+		if err := client.sendForPane(ctx, input.TmuxID, "set_progress", ipc.Envelope{TaskID: strings.TrimSpace(input.TaskID), Progress: progress}); err != nil {
+			return nil, nil, err
+		}
+		return textResult("Progress updated."), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_set_checklist",
+		Description: "Replace the current task's checklist with the given steps, all not done. An empty list clears it.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input checklistInput) (*mcp.CallToolResult, any, error) {
+		steps := make([]ipc.Step, 0, len(input.Items))
+		for _, item := range input.Items {
+			steps = append(steps, ipc.Step{Name: item})
+		}
+		if err := client.sendForPane(ctx, input.TmuxID, "set_checklist", ipc.Envelope{TaskID: strings.TrimSpace(input.TaskID), Checklist: steps}); err != nil {
+			return nil, nil, err
+		}
+		return textResult("Checklist set."), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_check_items",
+		Description: "Check off steps on the current task's checklist as they finish.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input checkItemsInput) (*mcp.CallToolResult, any, error) {
+		if len(input.Items) == 0 {
+			return nil, nil, fmt.Errorf("items is required")
+		}
+		steps := make([]ipc.Step, 0, len(input.Items))
+		for _, item := range input.Items {
+			steps = append(steps, ipc.Step{Name: item, Done: !input.Uncheck})
+		}
+		if err := client.sendForPane(ctx, input.TmuxID, "check_items", ipc.Envelope{TaskID: strings.TrimSpace(input.TaskID), Checklist: steps}); err != nil {
+			return nil, nil, err
+		}
+		return textResult("Checklist updated."), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_notify",
+		Description: "Send the user a notification about this pane without changing the task.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input notifyInput) (*mcp.CallToolResult, any, error) {
+		message := strings.TrimSpace(input.Message)
+		if message == "" {
+			return nil, nil, fmt.Errorf("message is required")
+		}
+		if err := client.sendForPane(ctx, input.TmuxID, "notify", ipc.Envelope{Summary: message}); err != nil {
+			return nil, nil, err
+		}
+		return textResult("Notification sent."), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_acknowledge",
+		Description: "Clear the needs-review flag on this pane's finished task.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input targetInput) (*mcp.CallToolResult, any, error) {
+		if err := client.sendForPane(ctx, input.TmuxID, "acknowledge", ipc.Envelope{}); err != nil {
+			return nil, nil, err
+		}
+		return textResult("Task acknowledged."), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_current_task",
+		Description: "Read the task the tracker holds for this pane: status, summary, notes and timing, as JSON.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input targetInput) (*mcp.CallToolResult, any, error) {
+		target, err := resolveContext(input.TmuxID)
+		if err != nil {
+			return nil, nil, err
+		}
+		task, err := client.currentTask(ctx, target)
+		if err != nil {
+			return nil, nil, err
+		}
+		if task == nil {
+			return textResult("No task is tracked for this pane."), nil, nil
+		}
+		data, err := json.MarshalIndent(task, "", "  ")
+		if err != nil {
+			return nil, nil, err
+		}
+		return textResult(string(data)), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_ask_user",
+		Description: "Ask the user a question and wait for the answer. The task shows as waiting for input in the tracker until the user replies from the tracker panel or `agent tracker answer`. Use this instead of printing questions into the terminal.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input askInput) (*mcp.CallToolResult, any, error) {
+		target, err := resolveContext(input.TmuxID)
+		if err != nil {
+			return nil, nil, err
+		}
+		text := strings.TrimSpace(input.Question)
+		if text == "" {
+			return nil, nil, fmt.Errorf("question is required")
+		}
+		timeout := defaultAskTimeout
+		if input.TimeoutSeconds > 0 {
+			timeout = time.Duration(input.TimeoutSeconds) * time.Second
+		}
+		questionID := fmt.Sprintf("mcp-%d-%d", os.Getpid(), time.Now().UnixNano())
 		env := ipc.Envelope{
-			Command:   "start_task",
+			Command:   "ask_user",
 			SessionID: target.SessionID,
 			WindowID:  target.WindowID,
 			Pane:      target.PaneID,
-			Summary:   summary,
+			Question:  &ipc.Question{ID: questionID, Text: text, Options: input.Options},
 		}
 		if err := client.sendCommand(ctx, env); err != nil {
 			return nil, nil, err
 		}
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{Text: "Status recorded."},
-			},
-		}, nil, nil
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		answer, err := client.waitForAnswer(waitCtx, target, questionID)
+		if err != nil {
+			withdraw := ipc.Envelope{
+				Command:   "cancel_question",
+				SessionID: target.SessionID,
+				WindowID:  target.WindowID,
+				Pane:      target.PaneID,
+				Question:  &ipc.Question{ID: questionID},
+			}
+			if cancelErr := client.sendCommand(context.Background(), withdraw); cancelErr != nil {
+				log.Printf("cancel question: %v", cancelErr)
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				return textResult(fmt.Sprintf("No answer after %s. Continue with your best judgement or ask again later.", timeout)), nil, nil
+			}
+			return nil, nil, err
+		}
+		return textResult("User answered: " + answer), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_post_message",
+		Description: "Leave a message for another agent, for example to say an API contract changed. It waits in their inbox until they read it, even if they are not running yet.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input postMessageInput) (*mcp.CallToolResult, any, error) {
+		to := strings.TrimSpace(input.To)
+		text := strings.TrimSpace(input.Text)
+		if to == "" || text == "" {
+			return nil, nil, fmt.Errorf("to and text are required")
+		}
+		if err := client.sendForPane(ctx, input.TmuxID, "post_message", ipc.Envelope{Mail: &ipc.Message{To: to, Text: text}}); err != nil {
+			return nil, nil, err
+		}
+		return textResult("Message posted to " + to + "."), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_list_messages",
+		Description: "List unread messages other agents left for this agent, as JSON. Check this when starting work and before changing shared interfaces.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input listMessagesInput) (*mcp.CallToolResult, any, error) {
+		target, err := resolveContext(input.TmuxID)
+		if err != nil {
+			return nil, nil, err
+		}
+		messages, err := client.unreadMessages(ctx, target)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(messages) == 0 {
+			return textResult("No unread messages."), nil, nil
+		}
+		if input.MarkRead {
+			ids := make([]ipc.Message, 0, len(messages))
+			for _, message := range messages {
+				ids = append(ids, ipc.Message{ID: message.ID})
+			}
+			if err := client.sendForPane(ctx, input.TmuxID, "mark_read", ipc.Envelope{Messages: ids}); err != nil {
+				return nil, nil, err
+			}
+		}
+		data, err := json.MarshalIndent(messages, "", "  ")
+		if err != nil {
+			return nil, nil, err
+		}
+		return textResult(string(data)), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tracker_mark_messages_read",
+		Description: "Mark messages in this agent's inbox as read.",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, input markReadInput) (*mcp.CallToolResult, any, error) {
+		ids := make([]ipc.Message, 0, len(input.IDs))
+		for _, id := range input.IDs {
+			ids = append(ids, ipc.Message{ID: id})
+		}
+		if err := client.sendForPane(ctx, input.TmuxID, "mark_read", ipc.Envelope{Messages: ids}); err != nil {
+			return nil, nil, err
+		}
+		return textResult("Messages marked read."), nil, nil
 	})
 
 	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
@@ -131,6 +597,19 @@ type tmuxContext struct {
 	PaneID    string
 }
 
+// resolveContext uses tmuxID when given and otherwise detects the pane the
+// MCP server was started from.
+func resolveContext(tmuxID string) (tmuxContext, error) {
+	if strings.TrimSpace(tmuxID) == "" {
+		target, err := autodetectContext()
+		if err != nil {
+			return tmuxContext{}, fmt.Errorf("%w; pass tmux_id as session_id::window_id::pane_id (for example, $3::@12::%%30)", err)
+		}
+		return target, nil
+	}
+	return determineContext(tmuxID)
+}
+
 func determineContext(tmuxID string) (tmuxContext, error) {
 	parts := strings.Split(strings.TrimSpace(tmuxID), "::")
 	if len(parts) != 3 {
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestDetermineContext(t *testing.T) {
+	tests := []struct {
+		tmuxID  string
+		want    tmuxContext
+		wantErr bool
+	}{
+		{tmuxID: "$3::@12::%30", want: tmuxContext{SessionID: "$3", WindowID: "@12", PaneID: "%30"}},
+		{tmuxID: " $3 :: @12 :: %30 ", want: tmuxContext{SessionID: "$3", WindowID: "@12", PaneID: "%30"}},
+		{tmuxID: "$3::@12", wantErr: true},
+		{tmuxID: "$3::::%30", wantErr: true},
+		{tmuxID: "$3::@12::%30::x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := determineContext(tt.tmuxID)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("determineContext(%q) = %+v, %v; want %+v, error %v", tt.tmuxID, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPaneTask(t *testing.T) {
+	tests := []struct {
+		name  string
+		tasks []ipc.Task
+		want  string
+	}{
+		{name: "no task", tasks: []ipc.Task{{ID: "other", Pane: "%2"}}},
+		{name: "root only", tasks: []ipc.Task{{ID: "root", Pane: "%1", Status: "completed"}}, want: "root"},
+		{
+			name: "newest open sub-task",
+			tasks: []ipc.Task{
+				{ID: "root", Pane: "%1", Status: "in_progress"},
+				{ID: "sub-1", Pane: "%1", ParentID: "root", Status: "in_progress", StartedAt: "2026-01-01T12:00:00Z"},
+				{ID: "sub-2", Pane: "%1", ParentID: "sub-1", Status: "in_progress", StartedAt: "2026-01-01T12:05:00Z"},
+				{ID: "sub-3", Pane: "%1", ParentID: "root", Status: "completed", StartedAt: "2026-01-01T12:10:00Z"},
+			},
+			want: "sub-2",
+		},
+		{
+			name: "ties broken by ID",
+			tasks: []ipc.Task{
+				{ID: "root", Pane: "%1"},
+				{ID: "sub-200", Pane: "%1", ParentID: "root", Status: "in_progress", StartedAt: "2026-01-01T12:00:00Z"},
+				{ID: "sub-100", Pane: "%1", ParentID: "root", Status: "in_progress", StartedAt: "2026-01-01T12:00:00Z"},
+			},
+			want: "sub-200",
+		},
+		{
+			name: "finished sub-tasks fall back to the root",
+			tasks: []ipc.Task{
+				{ID: "root", Pane: "%1", Status: "in_progress"},
+				{ID: "sub-1", Pane: "%1", ParentID: "root", Status: "failed"},
+			},
+			want: "root",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if task := paneTask(tt.tasks, "%1"); task != nil {
+				got = task.ID
+			}
+			if got != tt.want {
+				t.Errorf("paneTask = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuestionTask(t *testing.T) {
+	tasks := []ipc.Task{
+		{ID: "a"},
+		{ID: "b", Question: &ipc.Question{ID: "q-1"}},
+	}
+	if task := questionTask(tasks, "q-1"); task == nil || task.ID != "b" {
+		t.Errorf("questionTask(q-1) = %+v, want b", task)
+	}
+	if task := questionTask(tasks, "q-2"); task != nil {
+		t.Errorf("questionTask(q-2) = %+v, want nil", task)
+	}
+}
+
+func TestTrackerClientRequest(t *testing.T) {
+	client := fakeTracker(t, func(env ipc.Envelope, enc *json.Encoder) {
+		// A broadcast for someone else arrives before the reply.
+		enc.Encode(ipc.Envelope{Kind: "ack", ID: "other"})
+		switch env.Command {
+		case "start_task":
+			enc.Encode(ipc.Envelope{Kind: "ack", ID: env.ID, TaskID: "task-1"})
+		case "finish_task":
+			enc.Encode(ipc.Envelope{Kind: "error", ID: env.ID, Error: &ipc.Error{Code: ipc.ErrorCodeInvalidTarget, Message: "no task"}})
+		}
+	})
+	reply, err := client.request(context.Background(), ipc.Envelope{Command: "start_task"})
+	if err != nil || reply.TaskID != "task-1" {
+		t.Fatalf("start_task = %+v, %v; want task-1", reply, err)
+	}
+	var replyErr *ipc.Error
+	if err := client.sendCommand(context.Background(), ipc.Envelope{Command: "finish_task"}); !errors.As(err, &replyErr) || replyErr.Code != ipc.ErrorCodeInvalidTarget {
+		t.Fatalf("finish_task = %v, want invalid target", err)
+	}
+}
+
+func TestTrackerClientCurrentTask(t *testing.T) {
+	target := tmuxContext{SessionID: "$1", WindowID: "@1", PaneID: "%1"}
+	client := fakeTracker(t, func(env ipc.Envelope, enc *json.Encoder) {
+		if env.Kind != "ui-register" || env.Filter == nil || env.Filter.WindowID != "@1" {
+			return
+		}
+		enc.Encode(ipc.Envelope{Kind: "ack"})
+		enc.Encode(ipc.Envelope{Kind: "state", Tasks: []ipc.Task{{ID: "root", Pane: "%1", Summary: "Fix login"}}})
+	})
+	task, err := client.currentTask(context.Background(), target)
+	if err != nil || task == nil || task.Summary != "Fix login" {
+		t.Fatalf("currentTask = %+v, %v; want Fix login", task, err)
+	}
+}
+
+// fakeTracker serves one envelope per connection on a temporary socket,
+// answering through reply.
+func fakeTracker(t *testing.T, reply func(ipc.Envelope, *json.Encoder)) *trackerClient {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "tracker.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var env ipc.Envelope
+				if err := json.NewDecoder(conn).Decode(&env); err != nil {
+					return
+				}
+				reply(env, json.NewEncoder(conn))
+			}()
+		}
+	}()
+	return &trackerClient{socket: socket}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	tasksResourceURI   = "tracker://tasks"
+	taskResourcePrefix = "tracker://tasks/"
+	todosResourceURI   = "tracker://todos"
+
+	resourceRetryDelay   = 2 * time.Second
+	todosPollInterval    = 2 * time.Second
+	resourceJSONMIMEType = "application/json"
+)
+
+// resourceFeed keeps a ui-register subscription open so resource reads are
+// served from the latest state and subscribed clients hear about changes.
+type resourceFeed struct {
+	client *trackerClient
+	server *mcp.Server
+
+	mu     sync.Mutex
+	loaded bool
+	tasks  []ipc.Task
+	// seen is set once byID holds a snapshot. Unlike loaded it survives a
+	// reconnect, so the first snapshot after one is diffed rather than
+	// taken as a fresh start.
+	seen bool
+	byID map[string][]byte
+}
+
+func newResourceFeed(client *trackerClient, server *mcp.Server) *resourceFeed {
+	return &resourceFeed{client: client, server: server, byID: map[string][]byte{}}
+}
+
+func taskResourceURI(id string) string {
+	return taskResourcePrefix + url.QueryEscape(id)
+}
+
+func (f *resourceFeed) register() {
+	f.server.AddResource(&mcp.Resource{
+		URI:         tasksResourceURI,
+		Name:        "tasks",
+		Title:       "Tracked tasks",
+		Description: "Every task tracker-server knows about.",
+		MIMEType:    resourceJSONMIMEType,
+	}, f.readTasks)
+	f.server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: taskResourcePrefix + "{id}",
+		Name:        "task",
+		Title:       "Tracked task",
+		Description: "One task by its tracker ID (session|window|pane, with a #suffix for sub-tasks, percent-encoded).",
+		MIMEType:    resourceJSONMIMEType,
+	}, f.readTask)
+	f.server.AddResource(&mcp.Resource{
+		URI:         todosResourceURI,
+		Name:        "todos",
+		Title:       "Todo lists",
+		Description: "The global, session and window todo lists kept by `agent`.",
+		MIMEType:    resourceJSONMIMEType,
+	}, f.readTodos)
+}
+
+func (f *resourceFeed) snapshot(ctx context.Context) ([]ipc.Task, error) {
+	f.mu.Lock()
+	if f.loaded {
+		tasks := f.tasks
+		f.mu.Unlock()
+		return tasks, nil
+	}
+	f.mu.Unlock()
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+	}
+	var tasks []ipc.Task
+	err := f.client.watchState(ctx, nil, func(env ipc.Envelope) (bool, error) {
+		tasks = env.Tasks
+		return true, nil
+	})
+	return tasks, err
+}
+
+func (f *resourceFeed) readTasks(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	tasks, err := f.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []ipc.Task{}
+	}
+	return jsonResource(req.Params.URI, tasks)
+}
+
+func (f *resourceFeed) readTask(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	id, err := url.QueryUnescape(strings.TrimPrefix(req.Params.URI, taskResourcePrefix))
+	if err != nil {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	tasks, err := f.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		if task.ID == id {
+			return jsonResource(req.Params.URI, task)
+		}
+	}
+	return nil, mcp.ResourceNotFoundError(req.Params.URI)
+}
+
+func (f *resourceFeed) readTodos(_ context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	data, err := os.ReadFile(todoStorePath())
+	if os.IsNotExist(err) {
+		data = []byte("{}")
+	} else if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{
+		{URI: req.Params.URI, MIMEType: resourceJSONMIMEType, Text: string(data)},
+	}}, nil
+}
+
+func jsonResource(uri string, value any) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{
+		{URI: uri, MIMEType: resourceJSONMIMEType, Text: string(data)},
+	}}, nil
+}
+
+// follow keeps the state subscription alive, reconnecting after the tracker
+// restarts, and watches the todo store for changes.
+func (f *resourceFeed) follow(ctx context.Context) {
+	go f.followTodos(ctx)
+	for ctx.Err() == nil {
+		err := f.client.watchState(ctx, nil, func(env ipc.Envelope) (bool, error) {
+			f.apply(ctx, env.Tasks)
+			return false, nil
+		})
+		f.mu.Lock()
+		f.loaded = false
+		f.mu.Unlock()
+		if err != nil && ctx.Err() == nil {
+			log.Printf("tracker resources: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(resourceRetryDelay):
+		}
+	}
+}
+
+// apply stores a new snapshot and notifies subscribers of every resource it
+// changed.
+func (f *resourceFeed) apply(ctx context.Context, tasks []ipc.Task) {
+	for _, uri := range f.update(tasks) {
+		f.notify(ctx, uri)
+	}
+}
+
+// update stores a new snapshot and returns the URIs of the resources it
+// changed. The snapshot after a reconnect is compared with the last one seen
+// before it, so changes made while disconnected are reported too; only the
+// very first snapshot reports nothing. Durations tick on every snapshot, so
+// they are left out of the comparison.
+func (f *resourceFeed) update(tasks []ipc.Task) []string {
+	byID := make(map[string][]byte, len(tasks))
+	for _, task := range tasks {
+		task.DurationSeconds = 0
+		data, err := json.Marshal(task)
+		if err != nil {
+			continue
+		}
+		byID[task.ID] = data
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var uris []string
+	for id, data := range byID {
+		if previous, ok := f.byID[id]; !ok || !bytes.Equal(previous, data) {
+			uris = append(uris, taskResourceURI(id))
+		}
+	}
+	for id := range f.byID {
+		if _, ok := byID[id]; !ok {
+			uris = append(uris, taskResourceURI(id))
+		}
+	}
+	initial := !f.seen
+	f.seen = true
+	f.loaded = true
+	f.tasks = tasks
+	f.byID = byID
+	if initial || len(uris) == 0 {
+		return nil
+	}
+	return append(uris, tasksResourceURI)
+}
+
+func (f *resourceFeed) followTodos(ctx context.Context) {
+	ticker := time.NewTicker(todosPollInterval)
+	defer ticker.Stop()
+	var last time.Time
+	if info, err := os.Stat(todoStorePath()); err == nil {
+		last = info.ModTime()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var modTime time.Time
+		if info, err := os.Stat(todoStorePath()); err == nil {
+			modTime = info.ModTime()
+		}
+		if !modTime.Equal(last) {
+			last = modTime
+			f.notify(ctx, todosResourceURI)
+		}
+	}
+}
+
+func (f *resourceFeed) notify(ctx context.Context, uri string) {
+	if err := f.server.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: uri}); err != nil {
+		log.Printf("tracker resources: notify %s: %v", uri, err)
+	}
+}
+
+// todoStorePath matches the store `agent` writes todos to.
+func todoStorePath() string {
+	return filepath.Join(os.Getenv("HOME"), ".cache", "agent", "todos.json")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestTaskResourceURI(t *testing.T) {
+	if got, want := taskResourceURI("$0|@12|%30#2"), "tracker://tasks/%240%7C%4012%7C%2530%232"; got != want {
+		t.Errorf("taskResourceURI = %q, want %q", got, want)
+	}
+}
+
+func TestResourceFeedUpdate(t *testing.T) {
+	f := newResourceFeed(nil, nil)
+	steps := []struct {
+		name       string
+		disconnect bool
+		tasks      []ipc.Task
+		want       []string
+	}{
+		{
+			name:  "first snapshot",
+			tasks: []ipc.Task{{ID: "a", Summary: "one"}, {ID: "b", Summary: "two"}},
+		},
+		{
+			name:  "only durations ticked",
+			tasks: []ipc.Task{{ID: "a", Summary: "one", DurationSeconds: 5}, {ID: "b", Summary: "two", DurationSeconds: 5}},
+		},
+		{
+			name:  "one task changed",
+			tasks: []ipc.Task{{ID: "a", Summary: "one"}, {ID: "b", Summary: "changed"}},
+			want:  []string{taskResourceURI("b"), tasksResourceURI},
+		},
+		{
+			name:       "changed while disconnected",
+			disconnect: true,
+			tasks:      []ipc.Task{{ID: "b", Summary: "changed"}, {ID: "c", Summary: "three"}},
+			want:       []string{taskResourceURI("a"), taskResourceURI("c"), tasksResourceURI},
+		},
+		{
+			name:       "nothing changed across a reconnect",
+			disconnect: true,
+			tasks:      []ipc.Task{{ID: "b", Summary: "changed"}, {ID: "c", Summary: "three"}},
+		},
+	}
+	for _, step := range steps {
+		if step.disconnect {
+			f.mu.Lock()
+			f.loaded = false
+			f.mu.Unlock()
+		}
+		got := f.update(step.tasks)
+		if len(got) > 0 {
+			// Task URIs come out in map order; the collection is always last.
+			slices.Sort(got[:len(got)-1])
+		}
+		if !slices.Equal(got, step.want) {
+			t.Errorf("%s: update = %v, want %v", step.name, got, step.want)
+		}
+		if !f.loaded {
+			t.Errorf("%s: feed not loaded after a snapshot", step.name)
+		}
+	}
+}
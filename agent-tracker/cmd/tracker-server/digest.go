@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDigestWindow = 3 * time.Second
+
+	// notifyStatusDigest is the status backends see on a merged notification.
+	notifyStatusDigest = "digest"
+)
+
+// digestSettings sets how long after a completion or notify message others
+// are held so a burst of them becomes one notification. "0s" turns batching
+// off.
+type digestSettings struct {
+	Window string `json:"window,omitempty"`
+}
+
+func (d *digestSettings) duration() time.Duration {
+	if d == nil || strings.TrimSpace(d.Window) == "" {
+		return defaultDigestWindow
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(d.Window))
+	if err != nil || window < 0 {
+		log.Printf("digest: ignoring invalid window %q", d.Window)
+		return defaultDigestWindow
+	}
+	return window
+}
+
+// digestBatches reports whether notifications with status are held for the
+// digest; anything asking for a decision from the user goes out at once.
+func digestBatches(status string) bool {
+	return status == statusCompleted || status == notifyStatusMessage || status == notifyStatusReminder
+}
+
+type routedNotification struct {
+	notification notification
+	backends     []string
+}
+
+// notificationDigest sends a notification at once when none went out within
+// the last window. Notifications that arrive while the window is open are
+// held until it closes, then each backend gets either the single
+// notification or a digest of everything routed to it, and a new window
+// opens in case the burst goes on.
+type notificationDigest struct {
+	mu      sync.Mutex
+	window  time.Duration
+	pending []routedNotification
+	timer   *time.Timer
+	deliver func(backend string, n notification) error
+}
+
+func (d *notificationDigest) add(n notification, backends []string) {
+	item := routedNotification{notification: n, backends: backends}
+	d.mu.Lock()
+	if d.timer != nil {
+		d.pending = append(d.pending, item)
+		d.mu.Unlock()
+		return
+	}
+	d.timer = time.AfterFunc(d.window, d.flush)
+	d.mu.Unlock()
+	d.send([]routedNotification{item})
+}
+
+func (d *notificationDigest) flush() {
+	d.mu.Lock()
+	pending := d.pending
+	d.pending = nil
+	if len(pending) == 0 {
+		d.timer = nil
+	} else {
+		d.timer = time.AfterFunc(d.window, d.flush)
+	}
+	d.mu.Unlock()
+	d.send(pending)
+}
+
+func (d *notificationDigest) send(items []routedNotification) {
+	order := []string{}
+	byBackend := map[string][]notification{}
+	for _, item := range items {
+		for _, backend := range item.backends {
+			if _, seen := byBackend[backend]; !seen {
+				order = append(order, backend)
+			}
+			byBackend[backend] = append(byBackend[backend], item.notification)
+		}
+	}
+	for _, backend := range order {
+		batch := byBackend[backend]
+		n := batch[0]
+		if len(batch) > 1 {
+			n = digestNotification(batch)
+		}
+		if err := d.deliver(backend, n); err != nil {
+			log.Printf("notification error: %v", err)
+		}
+	}
+}
+
+// digestNotification merges items, oldest first, into one notification that
+// lists each window with its note and focuses the earliest window on click.
+func digestNotification(items []notification) notification {
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, fmt.Sprintf("%s: %s", item.Title, firstLine(item.Message)))
+	}
+	first := items[0]
+	return notification{
+		Title:    fmt.Sprintf("%d agent updates", len(items)),
+		Message:  strings.Join(lines, "\n"),
+		Status:   notifyStatusDigest,
+		Target:   first.Target,
+		Duration: first.Duration,
+		Action:   first.Action,
+	}
+}
+
+func firstLine(text string) string {
+	text = strings.TrimSpace(text)
+	if idx := strings.IndexByte(text, '\n'); idx >= 0 {
+		return strings.TrimSpace(text[:idx])
+	}
+	return text
+}
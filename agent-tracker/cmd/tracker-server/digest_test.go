@@ -0,0 +1,129 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDigestSettingsDuration(t *testing.T) {
+	tests := []struct {
+		settings *digestSettings
+		want     time.Duration
+	}{
+		{settings: nil, want: defaultDigestWindow},
+		{settings: &digestSettings{}, want: defaultDigestWindow},
+		{settings: &digestSettings{Window: "10s"}, want: 10 * time.Second},
+		{settings: &digestSettings{Window: "0s"}, want: 0},
+		{settings: &digestSettings{Window: "-1s"}, want: defaultDigestWindow},
+		{settings: &digestSettings{Window: "soon"}, want: defaultDigestWindow},
+	}
+	for _, tt := range tests {
+		if got := tt.settings.duration(); got != tt.want {
+			t.Errorf("duration(%+v) = %v, want %v", tt.settings, got, tt.want)
+		}
+	}
+}
+
+func TestDigestBatches(t *testing.T) {
+	for status, want := range map[string]bool{
+		statusCompleted:      true,
+		notifyStatusMessage:  true,
+		notifyStatusReminder: true,
+		statusWaitingInput:   false,
+		statusFailed:         false,
+		notifyStatusStalled:  false,
+	} {
+		if got := digestBatches(status); got != want {
+			t.Errorf("digestBatches(%s) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestDigestNotification(t *testing.T) {
+	first := notification{Title: "work / api", Message: "Fixed login\nand more", Target: tmuxTarget{WindowID: "@1"}}
+	second := notification{Title: "work / web", Message: "Added form", Target: tmuxTarget{WindowID: "@2"}}
+	n := digestNotification([]notification{first, second})
+	if n.Title != "2 agent updates" {
+		t.Errorf("title = %q", n.Title)
+	}
+	if want := "work / api: Fixed login\nwork / web: Added form"; n.Message != want {
+		t.Errorf("message = %q, want %q", n.Message, want)
+	}
+	if n.Status != notifyStatusDigest || n.Target.WindowID != "@1" {
+		t.Errorf("digest = %+v, want digest status focusing the first window", n)
+	}
+}
+
+// digestRecorder collects what a notificationDigest delivers.
+type digestRecorder struct {
+	mu   sync.Mutex
+	sent []notification
+}
+
+func (r *digestRecorder) deliver(backend string, n notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func (r *digestRecorder) titles() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var titles []string
+	for _, n := range r.sent {
+		titles = append(titles, n.Title)
+	}
+	return titles
+}
+
+func TestNotificationDigestSendsALoneNotificationAtOnce(t *testing.T) {
+	rec := &digestRecorder{}
+	d := &notificationDigest{window: time.Hour, deliver: rec.deliver}
+	d.add(notification{Title: "one"}, []string{"desktop"})
+	if got := rec.titles(); len(got) != 1 || got[0] != "one" {
+		t.Fatalf("sent = %v, want one right away", got)
+	}
+	d.add(notification{Title: "two"}, []string{"desktop"})
+	if got := rec.titles(); len(got) != 1 {
+		t.Errorf("sent = %v, want the second held for the window", got)
+	}
+	d.timer.Stop()
+}
+
+func TestNotificationDigestBatchesFollowers(t *testing.T) {
+	rec := &digestRecorder{}
+	window := 50 * time.Millisecond
+	d := &notificationDigest{window: window, deliver: rec.deliver}
+	d.add(notification{Title: "one"}, []string{"desktop"})
+	d.add(notification{Title: "two"}, []string{"desktop"})
+	d.add(notification{Title: "three"}, []string{"desktop"})
+	waitFor(t, func() bool { return len(rec.titles()) == 2 })
+	if got := rec.titles(); got[1] != "2 agent updates" {
+		t.Errorf("sent = %v, want one then a digest of two", got)
+	}
+	// The window closes once a flush finds nothing new, after which the
+	// next notification goes out at once again.
+	waitFor(t, func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.timer == nil
+	})
+	d.add(notification{Title: "four"}, []string{"desktop"})
+	if got := rec.titles(); len(got) != 3 || got[2] != "four" {
+		t.Errorf("sent = %v, want four right away", got)
+	}
+	d.timer.Stop()
+}
+
+func waitFor(t *testing.T, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// httpSettings configures the optional loopback HTTP gateway. The gateway
+// only starts when both an address and a token are set.
+type httpSettings struct {
+	Addr  string `json:"addr,omitempty"`
+	Token string `json:"token,omitempty"`
+}
+
+// gatewayCommands are the socket commands the gateway accepts as
+// POST /commands/<name>.
+var gatewayCommands = map[string]bool{
+	"start_task":  true,
+	"push_task":   true,
+	"finish_task": true,
+	"update_task": true,
+	"acknowledge": true,
+	"delete_task": true,
+	"notify":      true,
+
+	"wait_for_input": true,
+	"block_task":     true,
+	"fail_task":      true,
+	"resume_task":    true,
+	"heartbeat":      true,
+	"set_progress":   true,
+	"set_checklist":  true,
+	"check_items":    true,
+
+	"ask_user":        true,
+	"answer":          true,
+	"cancel_question": true,
+
+	"post_message": true,
+	"mark_read":    true,
+}
+
+const sseKeepaliveInterval = 30 * time.Second
+
+type gateway struct {
+	srv   *server
+	token string
+}
+
+// startGateway listens on the configured loopback address. It returns a nil
+// server when the gateway is not configured.
+func (s *server) startGateway(errCh chan<- error) (*http.Server, error) {
+	s.mu.Lock()
+	settings := s.http
+	s.mu.Unlock()
+	addr := strings.TrimSpace(settings.Addr)
+	token := strings.TrimSpace(settings.Token)
+	if addr == "" {
+		return nil, nil
+	}
+	if token == "" {
+		return nil, fmt.Errorf("http gateway on %s requires a token", addr)
+	}
+	if err := requireLoopback(addr); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	g := &gateway{srv: s, token: token}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tasks", g.authorized(g.handleTasks))
+	mux.HandleFunc("/commands/", g.authorized(g.handleCommand))
+	mux.HandleFunc("/events", g.authorized(g.handleEvents))
+	httpSrv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := httpSrv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+	log.Printf("http gateway listening on %s", ln.Addr())
+	return httpSrv, nil
+}
+
+func requireLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid http gateway address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("http gateway address %q is not a loopback address", addr)
+}
+
+// authorized accepts the token as a bearer token or, for EventSource clients
+// that cannot set headers, as the token query parameter.
+func (g *gateway) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(g.token)) != 1 {
+			writeGatewayError(w, http.StatusUnauthorized, &ipc.Error{Code: ipc.ErrorCodeUnauthorized, Message: "missing or invalid token"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (g *gateway) handleTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeGatewayError(w, http.StatusMethodNotAllowed, &ipc.Error{Code: ipc.ErrorCodeInvalidArgument, Message: "use GET"})
+		return
+	}
+	writeGatewayJSON(w, http.StatusOK, g.srv.buildStateEnvelope(filterFromQuery(r), detailFromQuery(r)))
+}
+
+func (g *gateway) handleCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeGatewayError(w, http.StatusMethodNotAllowed, &ipc.Error{Code: ipc.ErrorCodeInvalidArgument, Message: "use POST"})
+		return
+	}
+	command := strings.TrimPrefix(r.URL.Path, "/commands/")
+	if !gatewayCommands[command] {
+		writeGatewayError(w, http.StatusNotFound, &ipc.Error{Code: ipc.ErrorCodeUnknownCommand, Message: fmt.Sprintf("unknown command %q", command)})
+		return
+	}
+	var env ipc.Envelope
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&env); err != nil {
+			writeGatewayError(w, http.StatusBadRequest, &ipc.Error{Code: ipc.ErrorCodeInvalidArgument, Message: "invalid JSON body: " + err.Error()})
+			return
+		}
+	}
+	env.Kind = "command"
+	env.Command = command
+	reply := ipc.Envelope{Kind: "ack", ID: env.ID}
+	if err := g.srv.handleCommand(env, &reply); err != nil {
+		log.Printf("http command error: %v", err)
+		replyErr := replyError(err)
+		writeGatewayError(w, gatewayStatusForCode(replyErr.Code), replyErr)
+		return
+	}
+	writeGatewayJSON(w, http.StatusOK, &reply)
+}
+
+// handleEvents streams the same envelopes a ui-register subscriber receives
+// as server-sent events. mode=delta selects delta updates; the seq of each
+// delta event is its event ID, so Last-Event-ID resumes a dropped stream.
+func (g *gateway) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeGatewayError(w, http.StatusMethodNotAllowed, &ipc.Error{Code: ipc.ErrorCodeInvalidArgument, Message: "use GET"})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeGatewayError(w, http.StatusInternalServerError, &ipc.Error{Code: ipc.ErrorCodeInternal, Message: "streaming unsupported"})
+		return
+	}
+	query := r.URL.Query()
+	register := ipc.Envelope{Kind: "ui-register", Mode: query.Get("mode"), Filter: filterFromQuery(r), Detail: detailFromQuery(r)}
+	since := firstNonEmpty(r.Header.Get("Last-Event-ID"), query.Get("since"))
+	if since != "" {
+		seq, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			writeGatewayError(w, http.StatusBadRequest, &ipc.Error{Code: ipc.ErrorCodeInvalidArgument, Message: fmt.Sprintf("invalid since %q", since)})
+			return
+		}
+		register.Since = seq
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	stream := &sseWriter{w: w, flusher: flusher}
+	defer stream.close()
+	sub := &uiSubscriber{enc: json.NewEncoder(stream)}
+	g.srv.registerSubscriber(sub, register)
+	defer g.srv.removeSubscriber(sub)
+	if err := g.srv.sendStateTo(sub); err != nil {
+		return
+	}
+	ticker := time.NewTicker(sseKeepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if err := stream.comment("keepalive"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sseWriter turns each JSON document written by a json.Encoder into one
+// server-sent event named after the envelope kind. A broadcast may still hold
+// the subscriber after the handler returns, so writes fail once closed.
+type sseWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	closed  bool
+}
+
+func (s *sseWriter) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+}
+
+func (s *sseWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, net.ErrClosed
+	}
+	var head struct {
+		Kind string `json:"kind"`
+		Seq  uint64 `json:"seq"`
+	}
+	_ = json.Unmarshal(p, &head)
+	var b strings.Builder
+	if head.Seq != 0 {
+		fmt.Fprintf(&b, "id: %d\n", head.Seq)
+	}
+	if head.Kind != "" {
+		fmt.Fprintf(&b, "event: %s\n", head.Kind)
+	}
+	fmt.Fprintf(&b, "data: %s\n\n", strings.TrimSpace(string(p)))
+	if _, err := s.w.Write([]byte(b.String())); err != nil {
+		return 0, err
+	}
+	s.flusher.Flush()
+	return len(p), nil
+}
+
+func (s *sseWriter) comment(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return net.ErrClosed
+	}
+	if _, err := fmt.Fprintf(s.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func filterFromQuery(r *http.Request) *ipc.Filter {
+	query := r.URL.Query()
+	filter := &ipc.Filter{
+		SessionID: query.Get("session_id"),
+		WindowID:  query.Get("window_id"),
+	}
+	for _, value := range query["status"] {
+		filter.Statuses = append(filter.Statuses, strings.Split(value, ",")...)
+	}
+	if unacked, err := strconv.ParseBool(query.Get("unacknowledged")); err == nil {
+		filter.UnacknowledgedOnly = unacked
+	}
+	return normalizeFilter(filter)
+}
+
+// detailFromQuery reports whether detail=true asked for whole timelines.
+func detailFromQuery(r *http.Request) bool {
+	detail, _ := strconv.ParseBool(r.URL.Query().Get("detail"))
+	return detail
+}
+
+func gatewayStatusForCode(code string) int {
+	switch code {
+	case ipc.ErrorCodeInvalidArgument, ipc.ErrorCodeInvalidTarget:
+		return http.StatusBadRequest
+	case ipc.ErrorCodeUnknownCommand:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeGatewayError(w http.ResponseWriter, status int, replyErr *ipc.Error) {
+	writeGatewayJSON(w, status, &ipc.Envelope{Kind: "error", Error: replyErr})
+}
+
+func writeGatewayJSON(w http.ResponseWriter, status int, env *ipc.Envelope) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(env); err != nil {
+		log.Printf("http write error: %v", err)
+	}
+}
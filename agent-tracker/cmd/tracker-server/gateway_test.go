@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestRequireLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		ok   bool
+	}{
+		{addr: "127.0.0.1:7070", ok: true},
+		{addr: "localhost:7070", ok: true},
+		{addr: "[::1]:7070", ok: true},
+		{addr: "127.0.0.2:7070", ok: true},
+		{addr: "0.0.0.0:7070", ok: false},
+		{addr: "192.168.1.4:7070", ok: false},
+		{addr: "example.com:7070", ok: false},
+		{addr: "7070", ok: false},
+	}
+	for _, tt := range tests {
+		if err := requireLoopback(tt.addr); (err == nil) != tt.ok {
+			t.Errorf("requireLoopback(%q) = %v, want ok %v", tt.addr, err, tt.ok)
+		}
+	}
+}
+
+func TestFilterFromQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  *ipc.Filter
+	}{
+		{query: "", want: nil},
+		{query: "session_id=$1&window_id=@2", want: &ipc.Filter{SessionID: "$1", WindowID: "@2"}},
+		{query: "status=completed,failed&status=blocked", want: &ipc.Filter{Statuses: []string{"completed", "failed", "blocked"}}},
+		{query: "unacknowledged=true", want: &ipc.Filter{UnacknowledgedOnly: true}},
+		{query: "unacknowledged=maybe", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := filterFromQuery(httptest.NewRequest(http.MethodGet, "/tasks?"+tt.query, nil))
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("filterFromQuery = %+v, want %+v", got, tt.want)
+			}
+			if got != nil && (got.SessionID != tt.want.SessionID || got.WindowID != tt.want.WindowID || got.UnacknowledgedOnly != tt.want.UnacknowledgedOnly || !slices.Equal(got.Statuses, tt.want.Statuses)) {
+				t.Errorf("filterFromQuery = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGatewayCommandErrors(t *testing.T) {
+	g := &gateway{srv: newTestServer(), token: "secret"}
+	handler := g.authorized(g.handleCommand)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		body   string
+		status int
+		code   string
+	}{
+		{name: "missing token", method: http.MethodPost, path: "/commands/notify", status: http.StatusUnauthorized, code: ipc.ErrorCodeUnauthorized},
+		{name: "wrong token", method: http.MethodPost, path: "/commands/notify", auth: "Bearer nope", status: http.StatusUnauthorized, code: ipc.ErrorCodeUnauthorized},
+		{name: "token in query", method: http.MethodPost, path: "/commands/launch?token=secret", status: http.StatusNotFound, code: ipc.ErrorCodeUnknownCommand},
+		{name: "wrong method", method: http.MethodGet, path: "/commands/notify", auth: "Bearer secret", status: http.StatusMethodNotAllowed, code: ipc.ErrorCodeInvalidArgument},
+		{name: "command not exposed", method: http.MethodPost, path: "/commands/notifications_toggle", auth: "Bearer secret", status: http.StatusNotFound, code: ipc.ErrorCodeUnknownCommand},
+		{name: "invalid body", method: http.MethodPost, path: "/commands/notify", auth: "Bearer secret", body: "{", status: http.StatusBadRequest, code: ipc.ErrorCodeInvalidArgument},
+		{name: "rejected command", method: http.MethodPost, path: "/commands/finish_task", auth: "Bearer secret", body: `{"task_id":"$1|@1|%9"}`, status: http.StatusBadRequest, code: ipc.ErrorCodeInvalidTarget},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			events := decodeEvents(t, rec.Body)
+			if len(events) != 1 || events[0].Error == nil || events[0].Error.Code != tt.code {
+				t.Errorf("reply = %+v, want error code %s", events, tt.code)
+			}
+		})
+	}
+}
+
+func TestSSEWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	stream := &sseWriter{w: rec, flusher: rec}
+	if _, err := stream.Write([]byte(`{"kind":"task_upsert","seq":42}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Write([]byte(`{"kind":"state"}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.comment("keepalive"); err != nil {
+		t.Fatal(err)
+	}
+	want := "id: 42\nevent: task_upsert\ndata: {\"kind\":\"task_upsert\",\"seq\":42}\n\n" +
+		"event: state\ndata: {\"kind\":\"state\"}\n\n" +
+		": keepalive\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("stream = %q, want %q", got, want)
+	}
+	stream.close()
+	if _, err := stream.Write([]byte(`{}`)); err == nil {
+		t.Error("write after close succeeded")
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+const (
+	historyMaxEntries       = 5000
+	historyMaxAge           = 180 * 24 * time.Hour
+	historyCompactThreshold = 500
+)
+
+// historyArchive appends finished task runs to a JSON-lines file so they
+// survive delete_task and restarts of the same pane. The file is compacted
+// at startup and every historyCompactThreshold appends, keeping at most
+// historyMaxEntries runs from the last historyMaxAge.
+type historyArchive struct {
+	path     string
+	appended int
+}
+
+func newHistoryArchive(path string) *historyArchive {
+	return &historyArchive{path: path}
+}
+
+func (h *historyArchive) append(entry ipc.HistoryEntry) error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	h.appended++
+	if h.appended >= historyCompactThreshold {
+		return h.compact(time.Now())
+	}
+	return nil
+}
+
+func (h *historyArchive) load() ([]ipc.HistoryEntry, error) {
+	file, err := os.Open(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	var entries []ipc.HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry ipc.HistoryEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// compact rewrites the archive with one line per run and without the runs
+// that fall outside the retention limits.
+func (h *historyArchive) compact(now time.Time) error {
+	entries, err := h.load()
+	if err != nil || entries == nil {
+		return err
+	}
+	return h.write(pruneHistory(entries, now))
+}
+
+func (h *historyArchive) write(entries []ipc.HistoryEntry) error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, h.path); err != nil {
+		return err
+	}
+	h.appended = 0
+	return nil
+}
+
+// pruneHistory keeps the last line written for each run, in the place the
+// run was first archived, then drops runs that completed more than
+// historyMaxAge before now and all but the newest historyMaxEntries.
+func pruneHistory(entries []ipc.HistoryEntry, now time.Time) []ipc.HistoryEntry {
+	byID := make(map[string]int, len(entries))
+	kept := make([]ipc.HistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if idx, ok := byID[entry.ID]; ok && entry.ID != "" {
+			kept[idx] = entry
+			continue
+		}
+		byID[entry.ID] = len(kept)
+		kept = append(kept, entry)
+	}
+	cutoff := now.Add(-historyMaxAge)
+	recent := kept[:0]
+	for _, entry := range kept {
+		if entry.CompletedAt.After(cutoff) {
+			recent = append(recent, entry)
+		}
+	}
+	if over := len(recent) - historyMaxEntries; over > 0 {
+		recent = recent[over:]
+	}
+	return recent
+}
+
+// compactHistory applies the retention limits once at startup, so an archive
+// that grew before limits existed shrinks without waiting for new runs.
+func (s *server) compactHistory() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.history == nil {
+		return
+	}
+	if err := s.history.compact(time.Now()); err != nil {
+		log.Printf("history compaction error: %v", err)
+	}
+}
+
+// archiveTaskLocked appends the finished run of key to the history. Archiving
+// a run again supersedes its earlier line; load and compaction keep only the
+// last line written for each run.
+func (s *server) archiveTaskLocked(key string) {
+	if s.history == nil {
+		return
+	}
+	t, ok := s.tasks[key]
+	if !ok || !statusIsFinal(t.Status) || t.CompletedAt == nil {
+		return
+	}
+	if err := s.history.append(historyEntryForTask(key, t)); err != nil {
+		log.Printf("history archive error: %v", err)
+	}
+}
+
+func historyEntryForTask(key string, t *taskRecord) ipc.HistoryEntry {
+	duration := t.CompletedAt.Sub(t.StartedAt)
+	if duration < 0 {
+		duration = 0
+	}
+	timeline, _ := wireTimeline(t.Timeline, 0)
+	return ipc.HistoryEntry{
+		ID:              historyRunID(key, t),
+		SessionID:       t.SessionID,
+		Session:         firstNonEmpty(t.SessionName, t.SessionID),
+		WindowID:        t.WindowID,
+		Window:          firstNonEmpty(t.WindowName, t.WindowID),
+		Pane:            t.Pane,
+		Status:          t.Status,
+		Summary:         strings.TrimSpace(t.Summary),
+		CompletionNote:  strings.TrimSpace(t.CompletionNote),
+		StartedAt:       t.StartedAt,
+		CompletedAt:     *t.CompletedAt,
+		DurationSeconds: duration.Seconds(),
+		WaitingSeconds:  ipc.WaitingSeconds(timeline, *t.CompletedAt),
+		AcknowledgedAt:  t.AcknowledgedAt,
+	}
+}
+
+// acknowledgeLocked clears the review flag. A finished run records when it
+// was reviewed and is archived again, so reports can tell how long it waited.
+func (s *server) acknowledgeLocked(key string, t *taskRecord, now time.Time) {
+	t.Acknowledged = true
+	if statusIsFinal(t.Status) && t.CompletedAt != nil {
+		t.AcknowledgedAt = &now
+		s.archiveTaskLocked(key)
+	}
+	s.taskChangedLocked(key)
+}
+
+func historyRunID(key string, t *taskRecord) string {
+	return fmt.Sprintf("%s|%d", key, t.StartedAt.UnixNano())
+}
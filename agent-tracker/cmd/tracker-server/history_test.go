@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestPruneHistory(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(id, summary string, age time.Duration) ipc.HistoryEntry {
+		return ipc.HistoryEntry{ID: id, Summary: summary, CompletedAt: now.Add(-age)}
+	}
+	tests := []struct {
+		name    string
+		entries []ipc.HistoryEntry
+		want    []string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:    "last line for a run wins in its first place",
+			entries: []ipc.HistoryEntry{entry("a", "a1", time.Hour), entry("b", "b1", time.Hour), entry("a", "a2", time.Hour)},
+			want:    []string{"a2", "b1"},
+		},
+		{
+			name:    "entries without an id are all kept",
+			entries: []ipc.HistoryEntry{entry("", "x", time.Hour), entry("", "y", time.Hour)},
+			want:    []string{"x", "y"},
+		},
+		{
+			name:    "runs past the maximum age are dropped",
+			entries: []ipc.HistoryEntry{entry("old", "old", historyMaxAge+time.Hour), entry("new", "new", historyMaxAge-time.Hour)},
+			want:    []string{"new"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range pruneHistory(tt.entries, now) {
+				got = append(got, e.Summary)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("pruneHistory = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneHistoryKeepsNewestEntries(t *testing.T) {
+	now := time.Now()
+	var entries []ipc.HistoryEntry
+	for i := 0; i < historyMaxEntries+10; i++ {
+		entries = append(entries, ipc.HistoryEntry{ID: fmt.Sprint(i), CompletedAt: now})
+	}
+	pruned := pruneHistory(entries, now)
+	if len(pruned) != historyMaxEntries {
+		t.Fatalf("kept %d entries, want %d", len(pruned), historyMaxEntries)
+	}
+	if pruned[0].ID != "10" {
+		t.Errorf("oldest kept entry = %s, want 10", pruned[0].ID)
+	}
+}
+
+func TestHistoryArchiveCompactsAfterThreshold(t *testing.T) {
+	h := newHistoryArchive(filepath.Join(t.TempDir(), "history.jsonl"))
+	now := time.Now()
+	for i := 0; i < historyCompactThreshold-1; i++ {
+		if err := h.append(ipc.HistoryEntry{ID: "same", Summary: fmt.Sprint(i), CompletedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := h.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != historyCompactThreshold-1 {
+		t.Fatalf("entries before compaction = %d, want %d", len(entries), historyCompactThreshold-1)
+	}
+	if err := h.append(ipc.HistoryEntry{ID: "same", Summary: "last", CompletedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err = h.load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Summary != "last" {
+		t.Errorf("entries after compaction = %+v, want only the last", entries)
+	}
+	if h.appended != 0 {
+		t.Errorf("appended = %d after compaction, want 0", h.appended)
+	}
+}
+
+func TestHistoryArchiveCompactWithoutFile(t *testing.T) {
+	h := newHistoryArchive(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err := h.compact(time.Now()); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	entries, err := h.load()
+	if err != nil || entries != nil {
+		t.Errorf("load = %v, %v; want no entries", entries, err)
+	}
+}
+
+func TestAcknowledgeSupersedesArchivedRun(t *testing.T) {
+	s := newTestServer()
+	s.history = newHistoryArchive(filepath.Join(t.TempDir(), "history.jsonl"))
+	key := taskKey("$1", "@1", "%1")
+	completed := time.Now().Add(-time.Minute)
+	s.tasks[key] = &taskRecord{
+		SessionID: "$1", WindowID: "@1", Pane: "%1", Summary: "ship it",
+		Status: statusCompleted, StartedAt: completed.Add(-time.Hour), CompletedAt: &completed,
+	}
+	s.mu.Lock()
+	s.archiveTaskLocked(key)
+	s.mu.Unlock()
+
+	if err := s.acknowledgeTask(tmuxTarget{TaskID: key}); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := s.history.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("archive holds %d lines, want the run and its acknowledgement", len(lines))
+	}
+	entries := pruneHistory(lines, time.Now())
+	if len(entries) != 1 {
+		t.Fatalf("pruned archive holds %d runs, want 1", len(entries))
+	}
+	if entries[0].AcknowledgedAt == nil || entries[0].ID != historyRunID(key, s.tasks[key]) {
+		t.Errorf("archived run = %+v, want it acknowledged", entries[0])
+	}
+}
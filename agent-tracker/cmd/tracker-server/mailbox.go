@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// readMessageRetention is how long a read message is kept before it is
+// dropped from the mailbox file.
+const readMessageRetention = 7 * 24 * time.Hour
+
+// messageRecord is one mailbox entry. WindowID stays empty when the
+// recipient agent had no tmux window at post time; until then the message is
+// matched by window name.
+type messageRecord struct {
+	ID       string     `json:"id"`
+	From     string     `json:"from,omitempty"`
+	To       string     `json:"to,omitempty"`
+	WindowID string     `json:"window_id,omitempty"`
+	Text     string     `json:"text"`
+	PostedAt time.Time  `json:"posted_at"`
+	ReadAt   *time.Time `json:"read_at,omitempty"`
+}
+
+func (m *messageRecord) wire() ipc.Message {
+	out := ipc.Message{
+		ID:       m.ID,
+		From:     m.From,
+		To:       m.To,
+		WindowID: m.WindowID,
+		Text:     m.Text,
+		PostedAt: m.PostedAt.Format(time.RFC3339),
+	}
+	if m.ReadAt != nil {
+		out.ReadAt = m.ReadAt.Format(time.RFC3339)
+	}
+	return out
+}
+
+func (m *messageRecord) addressedTo(windowID, windowName string) bool {
+	if m.WindowID != "" {
+		return m.WindowID == windowID
+	}
+	return windowName != "" && m.To == windowName
+}
+
+// mailboxStore keeps every message in one JSON file next to the task
+// snapshot. Mailboxes are small, so each change rewrites the file.
+type mailboxStore struct {
+	path string
+}
+
+func newMailboxStore(path string) *mailboxStore {
+	return &mailboxStore{path: path}
+}
+
+func (st *mailboxStore) load() ([]*messageRecord, error) {
+	data, err := os.ReadFile(st.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var messages []*messageRecord
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("read %s: %w", st.path, err)
+		}
+	}
+	return messages, nil
+}
+
+func (st *mailboxStore) save(messages []*messageRecord) error {
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := st.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, st.path)
+}
+
+func (s *server) restoreMessages() error {
+	if s.mailbox == nil {
+		return nil
+	}
+	messages, err := s.mailbox.load()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.messages = messages
+	s.mu.Unlock()
+	return nil
+}
+
+// messagesChangedLocked prunes old read messages, saves the mailbox and bumps
+// mailSeq so subscribers are sent the new unread list. Callers hold s.mu.
+func (s *server) messagesChangedLocked(now time.Time) {
+	kept := s.messages[:0]
+	for _, m := range s.messages {
+		if m.ReadAt != nil && now.Sub(*m.ReadAt) > readMessageRetention {
+			continue
+		}
+		kept = append(kept, m)
+	}
+	s.messages = kept
+	s.mailSeq++
+	if s.mailbox == nil {
+		return
+	}
+	if err := s.mailbox.save(s.messages); err != nil {
+		log.Printf("mailbox save error: %v", err)
+	}
+}
+
+// postMessage leaves text for the agent whose window is named msg.To, or for
+// msg.WindowID directly. from names the sender and may be empty.
+func (s *server) postMessage(from string, msg ipc.Message) (ipc.Message, error) {
+	text := strings.TrimSpace(msg.Text)
+	if text == "" {
+		return ipc.Message{}, invalidArgument("post_message requires text")
+	}
+	record := &messageRecord{
+		From:     strings.TrimSpace(firstNonEmpty(msg.From, from)),
+		To:       strings.TrimSpace(msg.To),
+		WindowID: strings.TrimSpace(msg.WindowID),
+		Text:     text,
+	}
+	switch {
+	case record.WindowID != "" && record.To == "":
+		if _, name, err := tmuxNamesForWindow(record.WindowID); err == nil {
+			record.To = name
+		}
+	case record.WindowID == "" && record.To != "":
+		record.WindowID = tmuxWindowNamed(record.To)
+	case record.WindowID == "":
+		return ipc.Message{}, invalidArgument("post_message requires a recipient")
+	}
+	now := time.Now()
+	record.ID = fmt.Sprintf("m-%d", now.UnixNano())
+	record.PostedAt = now
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, record)
+	s.messagesChangedLocked(now)
+	return record.wire(), nil
+}
+
+// markMessagesRead marks the target window's unread messages as read: those
+// listed in ids, or all of them when ids is empty.
+func (s *server) markMessagesRead(target tmuxTarget, ids []string) error {
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			wanted[id] = true
+		}
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	marked := 0
+	for _, m := range s.messages {
+		if m.ReadAt != nil || !m.addressedTo(target.WindowID, target.WindowName) {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[m.ID] {
+			continue
+		}
+		m.WindowID = target.WindowID
+		m.ReadAt = &now
+		marked++
+	}
+	if marked == 0 {
+		if len(wanted) > 0 {
+			return invalidArgument("no unread messages with those ids for window %s", target.WindowID)
+		}
+		return nil
+	}
+	s.messagesChangedLocked(now)
+	return nil
+}
+
+// snapshotMessages returns unread messages, only those for filter's window
+// when it names one, along with the mailbox sequence they reflect.
+func (s *server) snapshotMessages(filter *ipc.Filter) ([]ipc.Message, uint64) {
+	windowID := ""
+	if filter != nil {
+		windowID = filter.WindowID
+	}
+	s.mu.Lock()
+	unbound := false
+	for _, m := range s.messages {
+		if m.ReadAt == nil && m.WindowID == "" {
+			unbound = true
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	// Messages posted before their recipient had a window are matched by
+	// name, which costs a tmux call, so only look when there are any.
+	windowName := ""
+	if unbound && windowID != "" {
+		if _, name, err := tmuxNamesForWindow(windowID); err == nil {
+			windowName = name
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []ipc.Message
+	for _, m := range s.messages {
+		if m.ReadAt != nil {
+			continue
+		}
+		if windowID != "" && !m.addressedTo(windowID, windowName) {
+			continue
+		}
+		messages = append(messages, m.wire())
+	}
+	return messages, s.mailSeq
+}
+
+// tmuxWindowNamed returns the ID of a tmux window called name, or "" when
+// there is none. Agent windows are named after their unique agent ID.
+func tmuxWindowNamed(name string) string {
+	panes, _, err := tmuxLivePanes()
+	if err != nil {
+		return ""
+	}
+	for _, pane := range panes {
+		if pane.WindowName == name {
+			return pane.WindowID
+		}
+	}
+	return ""
+}
+
+func mailboxPath() string {
+	return filepath.Join(filepath.Dir(settingsStorePath()), "messages.json")
+}
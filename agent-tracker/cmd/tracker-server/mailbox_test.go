@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestMessageAddressedTo(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    messageRecord
+		windowID   string
+		windowName string
+		want       bool
+	}{
+		{name: "bound to the window", message: messageRecord{To: "api", WindowID: "@1"}, windowID: "@1", want: true},
+		{name: "bound to another window", message: messageRecord{To: "api", WindowID: "@2"}, windowID: "@1", windowName: "api"},
+		{name: "unbound, matched by name", message: messageRecord{To: "api"}, windowID: "@1", windowName: "api", want: true},
+		{name: "unbound, other name", message: messageRecord{To: "web"}, windowID: "@1", windowName: "api"},
+		{name: "unbound, name unknown", message: messageRecord{}, windowID: "@1"},
+	}
+	for _, tt := range tests {
+		if got := tt.message.addressedTo(tt.windowID, tt.windowName); got != tt.want {
+			t.Errorf("%s: addressedTo = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMailboxStoreRoundTrip(t *testing.T) {
+	st := newMailboxStore(filepath.Join(t.TempDir(), "run", "messages.json"))
+	messages, err := st.load()
+	if err != nil || messages != nil {
+		t.Fatalf("load before any save = %v, %v", messages, err)
+	}
+	posted := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := st.save([]*messageRecord{{ID: "m-1", To: "api", Text: "schema changed", PostedAt: posted}}); err != nil {
+		t.Fatal(err)
+	}
+	messages, err = st.load()
+	if err != nil || len(messages) != 1 || messages[0].Text != "schema changed" || !messages[0].PostedAt.Equal(posted) {
+		t.Fatalf("load = %+v, %v", messages, err)
+	}
+}
+
+func TestMessagesChangedLockedPrunesOldReadMessages(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-readMessageRetention - time.Hour)
+	recent := now.Add(-time.Hour)
+	s := newTestServer()
+	s.messages = []*messageRecord{
+		{ID: "unread"},
+		{ID: "read recently", ReadAt: &recent},
+		{ID: "read long ago", ReadAt: &old},
+	}
+	s.messagesChangedLocked(now)
+	var ids []string
+	for _, m := range s.messages {
+		ids = append(ids, m.ID)
+	}
+	if want := []string{"unread", "read recently"}; !slices.Equal(ids, want) {
+		t.Errorf("messages = %v, want %v", ids, want)
+	}
+	if s.mailSeq != 1 {
+		t.Errorf("mailSeq = %d, want 1", s.mailSeq)
+	}
+}
+
+func TestPostMessage(t *testing.T) {
+	s := newTestServer()
+	var cmdErr *commandError
+	if _, err := s.postMessage("", ipc.Message{To: "api", Text: "  "}); !errors.As(err, &cmdErr) || cmdErr.code != ipc.ErrorCodeInvalidArgument {
+		t.Errorf("post without text = %v, want invalid argument", err)
+	}
+	if _, err := s.postMessage("", ipc.Message{Text: "hi"}); !errors.As(err, &cmdErr) || cmdErr.code != ipc.ErrorCodeInvalidArgument {
+		t.Errorf("post without recipient = %v, want invalid argument", err)
+	}
+	msg, err := s.postMessage("web", ipc.Message{To: " api ", WindowID: "@1", Text: " schema changed "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.From != "web" || msg.To != "api" || msg.WindowID != "@1" || msg.Text != "schema changed" || msg.ID == "" {
+		t.Errorf("posted = %+v", msg)
+	}
+}
+
+func TestMarkMessagesReadAndSnapshot(t *testing.T) {
+	s := newTestServer()
+	s.messages = []*messageRecord{
+		{ID: "m-1", To: "api", WindowID: "@1", Text: "one"},
+		{ID: "m-2", To: "api", WindowID: "@1", Text: "two"},
+		{ID: "m-3", To: "web", WindowID: "@2", Text: "three"},
+	}
+	target := tmuxTarget{SessionID: "$1", WindowID: "@1", WindowName: "api"}
+	var cmdErr *commandError
+	if err := s.markMessagesRead(target, []string{"m-3"}); !errors.As(err, &cmdErr) || cmdErr.code != ipc.ErrorCodeInvalidArgument {
+		t.Errorf("marking another window's message = %v, want invalid argument", err)
+	}
+	if err := s.markMessagesRead(target, []string{"m-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := messageIDs(s.snapshotMessages(&ipc.Filter{WindowID: "@1"})); !slices.Equal(got, []string{"m-2"}) {
+		t.Errorf("unread for @1 = %v, want m-2", got)
+	}
+	if err := s.markMessagesRead(target, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := messageIDs(s.snapshotMessages(nil)); !slices.Equal(got, []string{"m-3"}) {
+		t.Errorf("unread everywhere = %v, want m-3", got)
+	}
+	if err := s.markMessagesRead(target, nil); err != nil {
+		t.Errorf("marking an empty inbox read = %v, want nil", err)
+	}
+}
+
+func messageIDs(messages []ipc.Message, _ uint64) []string {
+	var ids []string
+	for _, m := range messages {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
@@ -22,26 +22,59 @@ import (
 )
 
 const (
-	statusInProgress = "in_progress"
-	statusCompleted  = "completed"
+	statusInProgress   = "in_progress"
+	statusWaitingInput = "waiting_input"
+	statusBlocked      = "blocked"
+	statusCompleted    = "completed"
+	statusFailed       = "failed"
 )
 
+// taskRecord is the live state of a task, guarded by s.mu. Snapshots are
+// shallow copies encoded after the lock is released, so they share the
+// Checklist and Timeline arrays: those may be appended to, resliced or
+// replaced, but never edited in place (see editChecklist).
 type taskRecord struct {
-	SessionID      string
-	SessionName    string
-	WindowID       string
-	WindowName     string
-	Pane           string
-	Summary        string
-	CompletionNote string
-	StartedAt      time.Time
-	CompletedAt    *time.Time
-	Status         string
-	Acknowledged   bool
+	SessionID      string          `json:"session_id"`
+	SessionName    string          `json:"session_name,omitempty"`
+	WindowID       string          `json:"window_id"`
+	WindowName     string          `json:"window_name,omitempty"`
+	Pane           string          `json:"pane,omitempty"`
+	Parent         string          `json:"parent,omitempty"`
+	Summary        string          `json:"summary,omitempty"`
+	CompletionNote string          `json:"completion_note,omitempty"`
+	StatusNote     string          `json:"status_note,omitempty"`
+	StartedAt      time.Time       `json:"started_at"`
+	CompletedAt    *time.Time      `json:"completed_at,omitempty"`
+	Status         string          `json:"status"`
+	Acknowledged   bool            `json:"acknowledged"`
+	AcknowledgedAt *time.Time      `json:"acknowledged_at,omitempty"`
+	PID            int             `json:"pid,omitempty"`
+	OrphanedAt     *time.Time      `json:"orphaned_at,omitempty"`
+	RemindersSent  int             `json:"reminders_sent,omitempty"`
+	VisitedAt      *time.Time      `json:"visited_at,omitempty"`
+	LastActivityAt *time.Time      `json:"last_activity_at,omitempty"`
+	StalledAt      *time.Time      `json:"stalled_at,omitempty"`
+	PromptDetected bool            `json:"prompt_detected,omitempty"`
+	Question       *questionRecord `json:"question,omitempty"`
+	Progress       *ipc.Progress   `json:"progress,omitempty"`
+	Checklist      []ipc.Step      `json:"checklist,omitempty"`
+	Timeline       []timelineEvent `json:"timeline,omitempty"`
+	TmuxServer     string          `json:"tmux_server,omitempty"`
 }
 
 type storedSettings struct {
-	NotificationsEnabled *bool `json:"notifications_enabled,omitempty"`
+	NotificationsEnabled *bool              `json:"notifications_enabled,omitempty"`
+	HTTP                 *httpSettings      `json:"http,omitempty"`
+	Reaper               *reaperSettings    `json:"reaper,omitempty"`
+	StatusNotifications  map[string]string  `json:"status_notifications,omitempty"`
+	Notifiers            []notifierSettings `json:"notifiers,omitempty"`
+	NotificationRules    []notificationRule `json:"notification_rules,omitempty"`
+	QuietHours           *quietHours        `json:"quiet_hours,omitempty"`
+	Digest               *digestSettings    `json:"digest,omitempty"`
+	Reminders            *reminderSettings  `json:"reminders,omitempty"`
+	Stall                *stallSettings     `json:"stall,omitempty"`
+	PromptDetection      *promptSettings    `json:"prompt_detection,omitempty"`
+	PaneAttribution      *bool              `json:"pane_attribution,omitempty"`
 }
 
 type tmuxTarget struct {
@@ -52,10 +85,27 @@ type tmuxTarget struct {
 	PaneID      string
 	WindowIndex string
 	PaneIndex   string
+	TaskID      string
+	PeerPID     int
 }
 
+// uiSubscriber is the write side of a client connection. Replies and
+// broadcasts share the encoder, so every write goes through mu.
 type uiSubscriber struct {
-	enc *json.Encoder
+	mu      sync.Mutex
+	enc     *json.Encoder
+	mode    string
+	filter  *ipc.Filter
+	seq     uint64
+	mailSeq uint64
+	visible map[string]bool
+	detail  bool
+}
+
+func (sub *uiSubscriber) send(env *ipc.Envelope) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.enc.Encode(env)
 }
 
 type server struct {
@@ -64,7 +114,26 @@ type server struct {
 	notificationsEnabled bool
 	tasks                map[string]*taskRecord
 	subscribers          map[*uiSubscriber]struct{}
+	seq                  uint64
+	changes              []taskChange
 	settingsPath         string
+	store                *taskStore
+	history              *historyArchive
+	http                 httpSettings
+	reaper               reaperSettings
+	reapNow              chan struct{}
+	statusNotifications  map[string]string
+	notifications        *notificationRouter
+	reminderDelays       []time.Duration
+	stall                *stallSettings
+	paneHashes           map[string][32]byte
+	prompts              *promptMatcher
+	mailbox              *mailboxStore
+	messages             []*messageRecord
+	mailSeq              uint64
+	paneAttribution      bool
+	peerPanes            paneLookup
+	unreconciled         bool
 }
 
 func newServer() *server {
@@ -73,7 +142,17 @@ func newServer() *server {
 		notificationsEnabled: true,
 		tasks:                make(map[string]*taskRecord),
 		subscribers:          make(map[*uiSubscriber]struct{}),
+		seq:                  uint64(time.Now().UnixNano()),
 		settingsPath:         settingsStorePath(),
+		store:                newTaskStore(taskSnapshotPath(), taskJournalPath()),
+		history:              newHistoryArchive(historyArchivePath()),
+		reapNow:              make(chan struct{}, 1),
+		notifications:        newNotificationRouter(nil, nil, nil, defaultDigestWindow),
+		reminderDelays:       (*reminderSettings)(nil).delays(),
+		paneHashes:           make(map[string][32]byte),
+		prompts:              newPromptMatcher(nil),
+		mailbox:              newMailboxStore(mailboxPath()),
+		paneAttribution:      true,
 	}
 }
 
@@ -88,6 +167,18 @@ func (s *server) run() error {
 	if err := s.loadSettings(); err != nil {
 		return err
 	}
+	if err := s.restoreTasks(); err != nil {
+		return err
+	}
+	defer s.closeStore()
+	s.compactHistory()
+	if err := s.restoreMessages(); err != nil {
+		return err
+	}
+	go s.compactLoop()
+	go s.reapLoop()
+	go s.reminderLoop()
+	go s.watchdogLoop()
 	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0o755); err != nil {
 		return err
 	}
@@ -108,6 +199,12 @@ func (s *server) run() error {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
 	errCh := make(chan error, 1)
+	httpSrv, err := s.startGateway(errCh)
+	if err != nil {
+		log.Printf("http gateway disabled: %v", err)
+	} else if httpSrv != nil {
+		defer httpSrv.Close()
+	}
 	go func() {
 		for {
 			conn, err := ln.Accept()
@@ -132,13 +229,16 @@ func (s *server) run() error {
 
 func (s *server) handleConn(conn net.Conn) {
 	defer conn.Close()
+	peerPID, ok := acceptPeer(peerCredentials(conn))
+	if !ok {
+		return
+	}
 
 	dec := json.NewDecoder(bufio.NewReader(conn))
-	enc := json.NewEncoder(conn)
-
-	var sub *uiSubscriber
+	sub := &uiSubscriber{enc: json.NewEncoder(conn)}
+	registered := false
 	defer func() {
-		if sub != nil {
+		if registered {
 			s.removeSubscriber(sub)
 		}
 	}()
@@ -150,17 +250,19 @@ func (s *server) handleConn(conn net.Conn) {
 		}
 		switch env.Kind {
 		case "command":
-			if err := s.handleCommand(env); err != nil {
-				log.Printf("command error: %v", err)
+			env.PeerPID = peerPID
+			reply := ipc.Envelope{Kind: "ack", ID: env.ID}
+			if err := s.handleCommand(env, &reply); err != nil {
+				log.Printf("command error (pid %d): %v", peerPID, err)
+				reply = ipc.Envelope{Kind: "error", ID: env.ID, Error: replyError(err)}
 			}
-			reply := ipc.Envelope{Kind: "ack"}
-			if err := enc.Encode(&reply); err != nil {
+			if err := sub.send(&reply); err != nil {
 				return
 			}
 		case "ui-register":
-			if sub == nil {
-				sub = &uiSubscriber{enc: enc}
-				s.addSubscriber(sub)
+			if !registered {
+				s.registerSubscriber(sub, env)
+				registered = true
 			}
 			if err := s.sendStateTo(sub); err != nil {
 				return
@@ -171,20 +273,34 @@ func (s *server) handleConn(conn net.Conn) {
 	}
 }
 
-func (s *server) handleCommand(env ipc.Envelope) error {
+// handleCommand applies one command. Commands that create a task report its
+// ID in reply.TaskID.
+func (s *server) handleCommand(env ipc.Envelope, reply *ipc.Envelope) error {
+	if err := s.targetTask(&env); err != nil {
+		return err
+	}
+	if err := s.attributePeer(&env); err != nil {
+		return err
+	}
 	switch env.Command {
-	case "start_task":
+	case "start_task", "push_task":
 		target, err := requireSessionWindow(env)
 		if err != nil {
 			return err
 		}
 		summary := firstNonEmpty(env.Summary, env.Message)
 		if summary == "" {
-			return fmt.Errorf("start_task requires summary")
+			return invalidArgument("%s requires summary", env.Command)
+		}
+		start := s.startTask
+		if env.Command == "push_task" {
+			start = s.pushTask
 		}
-		if err := s.startTask(target, summary); err != nil {
+		key, err := start(target, summary)
+		if err != nil {
 			return err
 		}
+		reply.TaskID = key
 		s.broadcastStateAsync()
 		s.statusRefreshAsync()
 		return nil
@@ -198,12 +314,44 @@ func (s *server) handleCommand(env ipc.Envelope) error {
 		if err != nil {
 			return err
 		}
-		if notify && s.notificationsAreEnabled() {
+		if notify && s.shouldNotifyStatus(statusCompleted, target) {
 			go s.notifyResponded(target)
 		}
 		s.broadcastStateAsync()
 		s.statusRefreshAsync()
 		return nil
+	case "wait_for_input", "block_task", "fail_task":
+		target, err := requireSessionWindow(env)
+		if err != nil {
+			return err
+		}
+		status := map[string]string{
+			"wait_for_input": statusWaitingInput,
+			"block_task":     statusBlocked,
+			"fail_task":      statusFailed,
+		}[env.Command]
+		note := firstNonEmpty(env.Summary, env.Message)
+		entered, err := s.setTaskStatus(target, status, note)
+		if err != nil {
+			return err
+		}
+		if entered && s.shouldNotifyStatus(status, target) {
+			go s.notifyStatus(target, status, note)
+		}
+		s.broadcastStateAsync()
+		s.statusRefreshAsync()
+		return nil
+	case "resume_task":
+		target, err := requireSessionWindow(env)
+		if err != nil {
+			return err
+		}
+		if err := s.resumeTask(target); err != nil {
+			return err
+		}
+		s.broadcastStateAsync()
+		s.statusRefreshAsync()
+		return nil
 	case "notify":
 		target, err := requireSessionWindow(env)
 		if err != nil {
@@ -211,12 +359,12 @@ func (s *server) handleCommand(env ipc.Envelope) error {
 		}
 		message := firstNonEmpty(env.Summary, env.Message)
 		if message == "" {
-			return fmt.Errorf("notify requires summary")
+			return invalidArgument("notify requires summary")
 		}
+		s.recordEvent(target, eventNotify, message)
+		s.broadcastStateAsync()
 		if s.notificationsAreEnabled() {
-			if err := sendSystemNotification(notificationTitleForTarget(target), message, notificationActionForTarget(target)); err != nil {
-				return err
-			}
+			go s.notifyMessage(target, message)
 		}
 		return nil
 	case "update_task":
@@ -226,7 +374,7 @@ func (s *server) handleCommand(env ipc.Envelope) error {
 		}
 		summary := firstNonEmpty(env.Summary, env.Message)
 		if summary == "" {
-			return fmt.Errorf("update_task requires summary")
+			return invalidArgument("update_task requires summary")
 		}
 		if err := s.updateTaskSummary(target, summary); err != nil {
 			return err
@@ -234,6 +382,24 @@ func (s *server) handleCommand(env ipc.Envelope) error {
 		s.broadcastStateAsync()
 		s.statusRefreshAsync()
 		return nil
+	case "set_progress", "set_checklist", "check_items":
+		target, err := requireSessionWindow(env)
+		if err != nil {
+			return err
+		}
+		switch env.Command {
+		case "set_progress":
+			err = s.setProgress(target, env.Progress)
+		case "set_checklist":
+			err = s.setChecklist(target, env.Checklist)
+		default:
+			err = s.checkSteps(target, env.Checklist)
+		}
+		if err != nil {
+			return err
+		}
+		s.broadcastStateAsync()
+		return nil
 	case "notifications_toggle":
 		enabled, err := s.toggleNotifications()
 		if err != nil {
@@ -255,37 +421,180 @@ func (s *server) handleCommand(env ipc.Envelope) error {
 		if err != nil {
 			return err
 		}
-		if err := s.acknowledgeTask(target.SessionID, target.WindowID, target.PaneID); err != nil {
+		if err := s.acknowledgeTask(target); err != nil {
 			return err
 		}
 		s.broadcastStateAsync()
 		s.statusRefreshAsync()
 		return nil
+	case "heartbeat":
+		target, err := requireSessionWindow(env)
+		if err != nil {
+			return err
+		}
+		if err := s.heartbeat(target); err != nil {
+			return err
+		}
+		s.broadcastStateAsync()
+		return nil
+	case "visit":
+		target, err := requireSessionWindow(env)
+		if err != nil {
+			return err
+		}
+		s.markVisited(target.SessionID, target.WindowID, target.PaneID)
+		return nil
+	case "ask_user":
+		target, err := requireSessionWindow(env)
+		if err != nil {
+			return err
+		}
+		if env.Question == nil || strings.TrimSpace(env.Question.Text) == "" {
+			return invalidArgument("ask_user requires question text")
+		}
+		entered, err := s.askUser(target, *env.Question)
+		if err != nil {
+			return err
+		}
+		if entered && s.shouldNotifyStatus(statusWaitingInput, target) {
+			go s.notifyStatus(target, statusWaitingInput, env.Question.Text)
+		}
+		s.broadcastStateAsync()
+		s.statusRefreshAsync()
+		return nil
+	case "answer":
+		target, err := requireSessionWindow(env)
+		if err != nil {
+			return err
+		}
+		answer := firstNonEmpty(env.Message, env.Summary)
+		questionID := ""
+		if env.Question != nil {
+			answer = firstNonEmpty(env.Question.Answer, answer)
+			questionID = strings.TrimSpace(env.Question.ID)
+		}
+		if answer == "" {
+			return invalidArgument("answer requires an answer")
+		}
+		if err := s.answerQuestion(target, questionID, answer); err != nil {
+			return err
+		}
+		s.broadcastStateAsync()
+		s.statusRefreshAsync()
+		return nil
+	case "cancel_question":
+		target, err := requireSessionWindow(env)
+		if err != nil {
+			return err
+		}
+		questionID := ""
+		if env.Question != nil {
+			questionID = strings.TrimSpace(env.Question.ID)
+		}
+		if err := s.cancelQuestion(target, questionID); err != nil {
+			return err
+		}
+		s.broadcastStateAsync()
+		s.statusRefreshAsync()
+		return nil
+	case "post_message":
+		if env.Mail == nil {
+			return invalidArgument("post_message requires mail")
+		}
+		from := ""
+		if strings.TrimSpace(env.WindowID) != "" || strings.TrimSpace(env.Pane) != "" {
+			sender, err := requireSessionWindow(env)
+			if err != nil {
+				return err
+			}
+			from = sender.WindowName
+		}
+		if _, err := s.postMessage(from, *env.Mail); err != nil {
+			return err
+		}
+		s.broadcastStateAsync()
+		s.statusRefreshAsync()
+		return nil
+	case "mark_read":
+		target, err := requireSessionWindow(env)
+		if err != nil {
+			return err
+		}
+		ids := make([]string, 0, len(env.Messages))
+		for _, message := range env.Messages {
+			ids = append(ids, message.ID)
+		}
+		if err := s.markMessagesRead(target, ids); err != nil {
+			return err
+		}
+		s.broadcastStateAsync()
+		s.statusRefreshAsync()
+		return nil
+	case "reconcile":
+		s.requestReap()
+		return nil
 	case "delete_task":
 		target, err := requireSessionWindow(env)
 		if err != nil {
 			return err
 		}
-		if err := s.deleteTask(target.SessionID, target.WindowID, target.PaneID); err != nil {
+		if err := s.deleteTask(target); err != nil {
 			return err
 		}
 		s.broadcastStateAsync()
 		s.statusRefreshAsync()
 		return nil
 	default:
-		return fmt.Errorf("unknown command %q", env.Command)
+		return &commandError{code: ipc.ErrorCodeUnknownCommand, message: fmt.Sprintf("unknown command %q", env.Command)}
+	}
+}
+
+// commandError is a rejection that is reported back to the client with a
+// stable code; any other error from handleCommand is reported as internal.
+type commandError struct {
+	code    string
+	message string
+}
+
+func (e *commandError) Error() string {
+	return e.message
+}
+
+func invalidArgument(format string, args ...any) error {
+	return &commandError{code: ipc.ErrorCodeInvalidArgument, message: fmt.Sprintf(format, args...)}
+}
+
+func invalidTarget(format string, args ...any) error {
+	return &commandError{code: ipc.ErrorCodeInvalidTarget, message: fmt.Sprintf(format, args...)}
+}
+
+func replyError(err error) *ipc.Error {
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) {
+		return &ipc.Error{Code: cmdErr.code, Message: cmdErr.message}
 	}
+	return &ipc.Error{Code: ipc.ErrorCodeInternal, Message: err.Error()}
 }
 
-func (s *server) startTask(target tmuxTarget, summary string) error {
+// startTask starts the pane's root task, or restarts target.TaskID, and
+// returns its key.
+func (s *server) startTask(target tmuxTarget, summary string) (string, error) {
 	if target.SessionID == "" || target.WindowID == "" {
-		return fmt.Errorf("cannot create task: missing session or window ID")
+		return "", invalidTarget("cannot create task: missing session or window ID")
 	}
 	target = normalizeTargetNames(target)
 	now := time.Now()
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	key := taskKey(target.SessionID, target.WindowID, target.PaneID)
+	key := target.TaskID
+	if key == "" {
+		key = taskKey(target.SessionID, target.WindowID, target.PaneID)
+	}
+	s.startTaskLocked(key, target, summary, now)
+	return key, nil
+}
+
+func (s *server) startTaskLocked(key string, target tmuxTarget, summary string, now time.Time) {
 	t, ok := s.tasks[key]
 	if !ok {
 		s.tasks[key] = &taskRecord{
@@ -298,30 +607,61 @@ func (s *server) startTask(target tmuxTarget, summary string) error {
 			StartedAt:    now,
 			Status:       statusInProgress,
 			Acknowledged: true,
+			PID:          target.PeerPID,
 		}
-		return nil
+		s.tasks[key].recordEvent(eventStart, summary, now)
+		touchActivityLocked(s.tasks[key], now)
+		s.taskChangedLocked(key)
+		return
+	}
+	if t.Parent == "" {
+		s.dropFinishedSubtasksLocked(key)
+	}
+	// New work for a finished run means it has been looked at.
+	if statusIsFinal(t.Status) && !t.Acknowledged {
+		s.acknowledgeLocked(key, t, now)
 	}
 	mergeTaskNamesFromTarget(t, target)
-	if !(t.Status == statusInProgress && strings.TrimSpace(t.Summary) != "") {
+	// A start while the task is already running continues it.
+	continuing := t.Status == statusInProgress && strings.TrimSpace(t.Summary) != ""
+	switch {
+	case !continuing:
 		t.Summary = summary
+		t.Timeline = nil
+		t.recordEvent(eventStart, summary, now)
+	case summary != t.Summary:
+		t.recordEvent(eventUpdate, summary, now)
 	}
 	t.StartedAt = now
 	t.Status = statusInProgress
 	t.CompletedAt = nil
+	t.AcknowledgedAt = nil
 	t.CompletionNote = ""
+	t.StatusNote = ""
+	t.PromptDetected = false
+	t.Question = nil
 	t.Acknowledged = true
-	return nil
+	t.OrphanedAt = nil
+	// The start comes from a live pane, possibly of a tmux server started
+	// since; the next sweep records which.
+	t.TmuxServer = ""
+	if !continuing {
+		t.Progress = nil
+		t.Checklist = nil
+	}
+	touchActivityLocked(t, now)
+	s.taskChangedLocked(key)
 }
 
 func (s *server) updateTaskSummary(target tmuxTarget, summary string) error {
 	if target.SessionID == "" || target.WindowID == "" {
-		return fmt.Errorf("cannot update task: missing session or window ID")
+		return invalidTarget("cannot update task: missing session or window ID")
 	}
 	target = normalizeTargetNames(target)
 	now := time.Now()
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	key := taskKey(target.SessionID, target.WindowID, target.PaneID)
+	key := s.taskKeyForLocked(target)
 	t, ok := s.tasks[key]
 	if !ok {
 		t = &taskRecord{
@@ -337,6 +677,9 @@ func (s *server) updateTaskSummary(target tmuxTarget, summary string) error {
 		s.tasks[key] = t
 	}
 	mergeTaskNamesFromTarget(t, target)
+	if summary != t.Summary {
+		t.recordEvent(eventUpdate, summary, now)
+	}
 	t.Summary = summary
 	if t.Status == "" {
 		t.Status = statusInProgress
@@ -344,6 +687,8 @@ func (s *server) updateTaskSummary(target tmuxTarget, summary string) error {
 	if t.StartedAt.IsZero() {
 		t.StartedAt = now
 	}
+	touchActivityLocked(t, now)
+	s.taskChangedLocked(key)
 	return nil
 }
 
@@ -355,9 +700,10 @@ func (s *server) finishTask(target tmuxTarget, note string) (bool, error) {
 	now := time.Now()
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	key := taskKey(target.SessionID, target.WindowID, target.PaneID)
+	key := s.taskKeyForLocked(target)
 	t, ok := s.tasks[key]
 	wasCompleted := false
+	previousNote := ""
 	if !ok {
 		t = &taskRecord{
 			SessionID:   target.SessionID,
@@ -370,34 +716,78 @@ func (s *server) finishTask(target tmuxTarget, note string) (bool, error) {
 		s.tasks[key] = t
 	} else {
 		wasCompleted = t.Status == statusCompleted
+		previousNote = t.CompletionNote
 	}
 	if t.Summary == "" {
 		t.Summary = note
 	}
 	mergeTaskNamesFromTarget(t, target)
 	t.Status = statusCompleted
+	t.StatusNote = ""
+	t.PromptDetected = false
 	t.CompletedAt = &now
 	if note != "" {
 		t.CompletionNote = note
 	}
-	// Auto-acknowledge if user is currently in this pane
-	t.Acknowledged = isActivePane(target.PaneID)
-	return !wasCompleted, nil
+	// Auto-acknowledge if user is currently in this pane. A finished
+	// sub-task needs no review of its own; its parent is still running.
+	t.Acknowledged = t.Parent != "" || isActivePane(target.PaneID)
+	t.AcknowledgedAt = nil
+	if t.Acknowledged {
+		t.AcknowledgedAt = &now
+	}
+	if !wasCompleted {
+		t.RemindersSent = 0
+		t.VisitedAt = nil
+	}
+	archive := !wasCompleted || t.CompletionNote != previousNote
+	if archive {
+		t.recordEvent(eventFinish, note, now)
+	}
+	s.finishDescendantsLocked(key, now)
+	s.taskChangedLocked(key)
+	if archive {
+		s.archiveTaskLocked(key)
+	}
+	return !wasCompleted && t.Parent == "", nil
 }
 
-func (s *server) acknowledgeTask(sessionID, windowID, paneID string) error {
+// acknowledgeTask clears the review flag of target.TaskID, or of every task
+// on the pane when no task is named.
+func (s *server) acknowledgeTask(target tmuxTarget) error {
+	now := time.Now()
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if t, ok := s.tasks[taskKey(sessionID, windowID, paneID)]; ok {
-		t.Acknowledged = true
+	for key, t := range s.tasks {
+		if target.TaskID != "" && key != target.TaskID {
+			continue
+		}
+		if target.TaskID == "" && !t.onPane(target.SessionID, target.WindowID, target.PaneID) {
+			continue
+		}
+		if !t.Acknowledged {
+			s.acknowledgeLocked(key, t, now)
+		}
 	}
 	return nil
 }
 
-func (s *server) deleteTask(sessionID, windowID, paneID string) error {
+// deleteTask removes target.TaskID, or the pane's root task when no task is
+// named, together with its sub-tasks.
+func (s *server) deleteTask(target tmuxTarget) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.tasks, taskKey(sessionID, windowID, paneID))
+	key := target.TaskID
+	if key == "" {
+		key = taskKey(target.SessionID, target.WindowID, target.PaneID)
+	}
+	if _, ok := s.tasks[key]; !ok {
+		return nil
+	}
+	for _, childKey := range append(s.descendantsLocked(key), key) {
+		delete(s.tasks, childKey)
+		s.taskChangedLocked(childKey)
+	}
 	return nil
 }
 
@@ -421,6 +811,9 @@ func mergeTaskNamesFromTarget(task *taskRecord, target tmuxTarget) {
 	if windowName := strings.TrimSpace(target.WindowName); windowName != "" {
 		task.WindowName = windowName
 	}
+	if target.PeerPID > 0 {
+		task.PID = target.PeerPID
+	}
 }
 
 func (s *server) loadSettings() error {
@@ -435,11 +828,25 @@ func (s *server) loadSettings() error {
 	if err := json.Unmarshal(data, &stored); err != nil {
 		return err
 	}
+	s.mu.Lock()
 	if stored.NotificationsEnabled != nil {
-		s.mu.Lock()
 		s.notificationsEnabled = *stored.NotificationsEnabled
-		s.mu.Unlock()
 	}
+	if stored.HTTP != nil {
+		s.http = *stored.HTTP
+	}
+	if stored.Reaper != nil {
+		s.reaper = *stored.Reaper
+	}
+	s.statusNotifications = stored.StatusNotifications
+	s.reminderDelays = stored.Reminders.delays()
+	s.stall = stored.Stall
+	s.prompts = newPromptMatcher(stored.PromptDetection)
+	if stored.PaneAttribution != nil {
+		s.paneAttribution = *stored.PaneAttribution
+	}
+	s.notifications = newNotificationRouter(stored.Notifiers, stored.NotificationRules, stored.QuietHours, stored.Digest.duration())
+	s.mu.Unlock()
 	return nil
 }
 
@@ -447,8 +854,18 @@ func (s *server) saveSettingsLocked() error {
 	if err := os.MkdirAll(filepath.Dir(s.settingsPath), 0o755); err != nil {
 		return err
 	}
-	enabled := s.notificationsEnabled
-	data, err := json.MarshalIndent(storedSettings{NotificationsEnabled: &enabled}, "", "  ")
+	// Keep keys the server does not manage, such as the http gateway block,
+	// which is edited by hand.
+	fields := map[string]json.RawMessage{}
+	if data, err := os.ReadFile(s.settingsPath); err == nil {
+		_ = json.Unmarshal(data, &fields)
+	}
+	enabled, err := json.Marshal(s.notificationsEnabled)
+	if err != nil {
+		return err
+	}
+	fields["notifications_enabled"] = enabled
+	data, err := json.MarshalIndent(fields, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -476,14 +893,19 @@ func (s *server) toggleNotifications() (bool, error) {
 }
 
 func (s *server) notifyResponded(target tmuxTarget) {
-	target = s.fillTargetNamesFromTask(target)
-	summary := strings.TrimSpace(s.summaryForTask(target.SessionID, target.WindowID, target.PaneID))
+	summary := strings.TrimSpace(s.summaryForTask(target))
 	if summary == "" {
 		summary = "Task marked complete"
 	}
-	title := notificationTitleForTarget(target)
-	action := notificationActionForTarget(target)
-	if err := sendSystemNotification(title, summary, action); err != nil {
+	if err := s.dispatchNotification(s.notificationFor(target, statusCompleted, summary)); err != nil {
+		log.Printf("notification error: %v", err)
+	}
+}
+
+// notifyMessage delivers a notify command's message. It runs apart from the
+// command so a slow backend does not hold up the client's reply.
+func (s *server) notifyMessage(target tmuxTarget, message string) {
+	if err := s.dispatchNotification(s.notificationFor(target, notifyStatusMessage, message)); err != nil {
 		log.Printf("notification error: %v", err)
 	}
 }
@@ -563,10 +985,10 @@ func stripSessionIndexPrefix(name string) string {
 	return trimmed
 }
 
-func (s *server) summaryForTask(sessionID, windowID, paneID string) string {
+func (s *server) summaryForTask(target tmuxTarget) string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if t, ok := s.tasks[taskKey(sessionID, windowID, paneID)]; ok {
+	if t, ok := s.tasks[s.taskKeyForLocked(target)]; ok {
 		note := strings.TrimSpace(t.CompletionNote)
 		summary := strings.TrimSpace(t.Summary)
 		if note != "" && !isGenericCompletionNote(note) {
@@ -601,10 +1023,6 @@ func (s *server) broadcastStateAsync() {
 }
 
 func (s *server) broadcastState() {
-	env := s.buildStateEnvelope()
-	if env == nil {
-		return
-	}
 	s.mu.Lock()
 	subs := make([]*uiSubscriber, 0, len(s.subscribers))
 	for sub := range s.subscribers {
@@ -612,8 +1030,26 @@ func (s *server) broadcastState() {
 	}
 	s.mu.Unlock()
 
+	var full, detailed *ipc.Envelope
 	for _, sub := range subs {
-		if err := sub.enc.Encode(env); err != nil {
+		var err error
+		switch {
+		case sub.mode == ipc.SubscribeModeDelta:
+			err = s.sendDeltaTo(sub)
+		case sub.filter != nil:
+			err = s.sendFilteredStateTo(sub, false)
+		case sub.detail:
+			if detailed == nil {
+				detailed = s.buildStateEnvelope(nil, true)
+			}
+			err = sub.send(detailed)
+		default:
+			if full == nil {
+				full = s.buildStateEnvelope(nil, false)
+			}
+			err = sub.send(full)
+		}
+		if err != nil {
 			s.removeSubscriber(sub)
 		}
 	}
@@ -627,113 +1063,168 @@ func (s *server) statusRefreshAsync() {
 	}()
 }
 
-func (s *server) sendState(enc *json.Encoder) {
-	env := s.buildStateEnvelope()
-	if env == nil {
-		return
+func (s *server) sendStateTo(sub *uiSubscriber) error {
+	var err error
+	switch {
+	case sub.mode == ipc.SubscribeModeDelta:
+		if err = s.sendDeltaTo(sub); err == nil {
+			err = s.sendSyncedTo(sub)
+		}
+	case sub.filter != nil:
+		err = s.sendFilteredStateTo(sub, true)
+	default:
+		err = sub.send(s.buildStateEnvelope(nil, sub.detail))
 	}
-	if err := enc.Encode(env); err != nil {
-		log.Printf("state send error: %v", err)
+	if err != nil {
+		s.removeSubscriber(sub)
 	}
+	return err
 }
 
-func (s *server) sendStateTo(sub *uiSubscriber) error {
-	env := s.buildStateEnvelope()
-	if env == nil {
-		return nil
-	}
-	if err := sub.enc.Encode(env); err != nil {
-		s.removeSubscriber(sub)
-		return err
+func (s *server) buildStateEnvelope(filter *ipc.Filter, detail bool) *ipc.Envelope {
+	tasks, _ := s.snapshotTasks(filter, detail)
+	messages, _ := s.snapshotMessages(filter)
+	return &ipc.Envelope{
+		Kind:     "state",
+		Message:  stateSummary(tasks),
+		Tasks:    tasks,
+		Messages: messages,
 	}
-	return nil
 }
 
-func (s *server) buildStateEnvelope() *ipc.Envelope {
+// snapshotTasks renders every task matching filter for the wire along with
+// the change sequence number the snapshot is consistent with. Timelines are
+// sent whole only with detail.
+func (s *server) snapshotTasks(filter *ipc.Filter, detail bool) ([]ipc.Task, uint64) {
 	s.mu.Lock()
-	copies := make([]*taskRecord, 0, len(s.tasks))
-	for _, task := range s.tasks {
-		copy := *task
-		copies = append(copies, &copy)
+	seq := s.seq
+	keys := make([]string, 0, len(s.tasks))
+	copies := make([]taskRecord, 0, len(s.tasks))
+	for key, task := range s.tasks {
+		if !task.matches(filter) {
+			continue
+		}
+		keys = append(keys, key)
+		copies = append(copies, *task)
 	}
 	s.mu.Unlock()
 
 	now := time.Now()
-	tasks := make([]ipc.Task, 0, len(copies))
 	nameCache := make(map[string][2]string)
-	for _, t := range copies {
-		started := ""
-		if !t.StartedAt.IsZero() {
-			started = t.StartedAt.Format(time.RFC3339)
-		}
-		completed := ""
-		var duration time.Duration
-		if t.CompletedAt != nil {
-			completed = t.CompletedAt.Format(time.RFC3339)
-			duration = t.CompletedAt.Sub(t.StartedAt)
-		} else {
-			duration = now.Sub(t.StartedAt)
-		}
-		if duration < 0 {
-			duration = 0
-		}
-		sessionName := strings.TrimSpace(t.SessionName)
-		windowName := strings.TrimSpace(t.WindowName)
-		if sessionName == strings.TrimSpace(t.SessionID) {
-			sessionName = ""
-		}
-		if windowName == strings.TrimSpace(t.WindowID) {
-			windowName = ""
-		}
-		if sessionName == "" || windowName == "" {
-			if cached, ok := nameCache[t.WindowID]; ok {
-				if sessionName == "" {
-					sessionName = cached[0]
-				}
-				if windowName == "" {
-					windowName = cached[1]
-				}
-			} else {
-				sessName, winName, err := tmuxNamesForWindow(t.WindowID)
-				if err == nil {
-					nameCache[t.WindowID] = [2]string{sessName, winName}
-					if sessionName == "" {
-						sessionName = sessName
-					}
-					if windowName == "" {
-						windowName = winName
-					}
-				}
+	tasks := make([]ipc.Task, 0, len(copies))
+	for i := range copies {
+		tasks = append(tasks, s.wireTask(keys[i], &copies[i], now, nameCache, detail))
+	}
+	return tasks, seq
+}
+
+// wireTask converts a task copy to its wire form, with only the latest
+// timeline events unless detail is set. Names tmux has to be asked for are
+// cached per window and written back to the live record so later broadcasts
+// can skip the lookup.
+func (s *server) wireTask(key string, t *taskRecord, now time.Time, nameCache map[string][2]string, detail bool) ipc.Task {
+	started := ""
+	if !t.StartedAt.IsZero() {
+		started = t.StartedAt.Format(time.RFC3339)
+	}
+	completed := ""
+	var duration time.Duration
+	if t.CompletedAt != nil {
+		completed = t.CompletedAt.Format(time.RFC3339)
+		duration = t.CompletedAt.Sub(t.StartedAt)
+	} else {
+		duration = now.Sub(t.StartedAt)
+	}
+	if duration < 0 {
+		duration = 0
+	}
+	sessionName := strings.TrimSpace(t.SessionName)
+	windowName := strings.TrimSpace(t.WindowName)
+	if sessionName == strings.TrimSpace(t.SessionID) {
+		sessionName = ""
+	}
+	if windowName == strings.TrimSpace(t.WindowID) {
+		windowName = ""
+	}
+	if sessionName == "" || windowName == "" {
+		cached, ok := nameCache[t.WindowID]
+		if !ok {
+			sessName, winName, err := tmuxNamesForWindow(t.WindowID)
+			if err == nil {
+				cached = [2]string{sessName, winName}
+				nameCache[t.WindowID] = cached
+				s.rememberTaskNames(key, tmuxTarget{SessionName: sessName, WindowName: winName})
 			}
 		}
 		if sessionName == "" {
-			sessionName = t.SessionID
+			sessionName = cached[0]
 		}
 		if windowName == "" {
-			windowName = t.WindowID
-		}
-
-		tasks = append(tasks, ipc.Task{
-			SessionID:       t.SessionID,
-			Session:         sessionName,
-			WindowID:        t.WindowID,
-			Window:          windowName,
-			Pane:            t.Pane,
-			Status:          t.Status,
-			Summary:         t.Summary,
-			CompletionNote:  t.CompletionNote,
-			StartedAt:       started,
-			CompletedAt:     completed,
-			DurationSeconds: duration.Seconds(),
-			Acknowledged:    t.Acknowledged,
-		})
-	}
-
-	msg := stateSummary(tasks)
-	return &ipc.Envelope{
-		Kind:    "state",
-		Message: msg,
-		Tasks:   tasks,
+			windowName = cached[1]
+		}
+	}
+	if sessionName == "" {
+		sessionName = t.SessionID
+	}
+	if windowName == "" {
+		windowName = t.WindowID
+	}
+
+	orphaned := ""
+	if t.OrphanedAt != nil {
+		orphaned = t.OrphanedAt.Format(time.RFC3339)
+	}
+	lastActivity := ""
+	if t.Status == statusInProgress {
+		lastActivity = t.lastActivity().Format(time.RFC3339)
+	}
+	limit := broadcastTimelineEvents
+	if detail {
+		limit = 0
+	}
+	timeline, omitted := wireTimeline(t.Timeline, limit)
+
+	return ipc.Task{
+		ID:              key,
+		ParentID:        t.Parent,
+		SessionID:       t.SessionID,
+		Session:         sessionName,
+		WindowID:        t.WindowID,
+		Window:          windowName,
+		Pane:            t.Pane,
+		Status:          t.Status,
+		Summary:         t.Summary,
+		CompletionNote:  t.CompletionNote,
+		StatusNote:      t.StatusNote,
+		StartedAt:       started,
+		CompletedAt:     completed,
+		DurationSeconds: duration.Seconds(),
+		Acknowledged:    t.Acknowledged,
+		Orphaned:        t.OrphanedAt != nil,
+		OrphanedAt:      orphaned,
+		Stalled:         t.StalledAt != nil && t.Status == statusInProgress,
+		LastActivityAt:  lastActivity,
+		Question:        t.Question.wire(),
+		Progress:        t.Progress,
+		Checklist:       append([]ipc.Step(nil), t.Checklist...),
+		Timeline:        timeline,
+		TimelineOmitted: omitted,
+		PID:             t.PID,
+	}
+}
+
+func (s *server) rememberTaskNames(key string, names tmuxTarget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[key]
+	if !ok {
+		return
+	}
+	if strings.TrimSpace(task.SessionName) == "" {
+		task.SessionName = strings.TrimSpace(names.SessionName)
+	}
+	if strings.TrimSpace(task.WindowName) == "" {
+		task.WindowName = strings.TrimSpace(names.WindowName)
 	}
 }
 
@@ -836,20 +1327,24 @@ func runTmux(args ...string) error {
 }
 
 func isActivePane(paneID string) bool {
+	return activePanes()[paneID]
+}
+
+// activePanes reports the pane each attached client is looking at.
+func activePanes() map[string]bool {
 	clients, err := listClients()
 	if err != nil {
-		return false
+		return nil
 	}
+	active := map[string]bool{}
 	for _, client := range clients {
 		output, err := tmuxDisplay(client, "#{pane_id}")
 		if err != nil {
 			continue
 		}
-		if strings.TrimSpace(output) == paneID {
-			return true
-		}
+		active[strings.TrimSpace(output)] = true
 	}
-	return false
+	return active
 }
 
 func tmuxOutput(args ...string) (string, error) {
@@ -899,6 +1394,18 @@ func settingsStorePath() string {
 	return filepath.Join(base, "settings.json")
 }
 
+func taskSnapshotPath() string {
+	return filepath.Join(filepath.Dir(settingsStorePath()), "tasks.json")
+}
+
+func taskJournalPath() string {
+	return filepath.Join(filepath.Dir(settingsStorePath()), "tasks.journal")
+}
+
+func historyArchivePath() string {
+	return filepath.Join(filepath.Dir(settingsStorePath()), "history.jsonl")
+}
+
 func taskKey(sessionID, windowID, paneID string) string {
 	return strings.Join([]string{sessionID, windowID, paneID}, "|")
 }
@@ -910,6 +1417,8 @@ func requireSessionWindow(env ipc.Envelope) (tmuxTarget, error) {
 		WindowName:  strings.TrimSpace(env.Window),
 		WindowID:    strings.TrimSpace(env.WindowID),
 		PaneID:      strings.TrimSpace(env.Pane),
+		TaskID:      strings.TrimSpace(env.TaskID),
+		PeerPID:     env.PeerPID,
 	})
 
 	fetchOrder := []string{}
@@ -928,7 +1437,10 @@ func requireSessionWindow(env ipc.Envelope) (tmuxTarget, error) {
 		info, err := detectTmuxTarget(target)
 		if err != nil {
 			if target == "" {
-				return tmuxTarget{}, err
+				return tmuxTarget{}, invalidTarget("%v", err)
+			}
+			if target == ctx.PaneID && (ctx.SessionID == "" || ctx.WindowID == "") {
+				return tmuxTarget{}, invalidTarget("unknown pane %s", ctx.PaneID)
 			}
 			continue
 		}
@@ -936,7 +1448,7 @@ func requireSessionWindow(env ipc.Envelope) (tmuxTarget, error) {
 	}
 
 	if ctx.SessionID == "" || ctx.WindowID == "" {
-		return tmuxTarget{}, fmt.Errorf("session and window required")
+		return tmuxTarget{}, invalidTarget("session and window required")
 	}
 
 	if ctx.SessionName == "" || ctx.WindowName == "" {
@@ -952,7 +1464,7 @@ func requireSessionWindow(env ipc.Envelope) (tmuxTarget, error) {
 		ctx.WindowName = ctx.WindowID
 	}
 	if strings.TrimSpace(ctx.PaneID) == "" {
-		return tmuxTarget{}, fmt.Errorf("pane identifier required")
+		return tmuxTarget{}, invalidTarget("pane identifier required")
 	}
 
 	return ctx, nil
@@ -997,6 +1509,9 @@ func detectTmuxTarget(target string) (tmuxTarget, error) {
 	if len(parts) != 7 {
 		return tmuxTarget{}, fmt.Errorf("unexpected tmux response: %s", strings.TrimSpace(output))
 	}
+	if strings.TrimSpace(parts[4]) == "" {
+		return tmuxTarget{}, fmt.Errorf("tmux target %q not found", target)
+	}
 	return tmuxTarget{
 		SessionName: strings.TrimSpace(parts[0]),
 		SessionID:   strings.TrimSpace(parts[1]),
@@ -1053,8 +1568,10 @@ func stateSummary(tasks []ipc.Task) string {
 		switch t.Status {
 		case statusInProgress:
 			inProgress++
-		case statusCompleted:
-			if !t.Acknowledged {
+		case statusWaitingInput, statusBlocked:
+			waiting++
+		default:
+			if statusNeedsAttention(t.Status) && !t.Acknowledged {
 				waiting++
 			}
 		}
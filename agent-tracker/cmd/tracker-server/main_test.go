@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestReplyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ipc.Error
+	}{
+		{
+			name: "invalid argument",
+			err:  invalidArgument("start_task requires summary"),
+			want: ipc.Error{Code: ipc.ErrorCodeInvalidArgument, Message: "start_task requires summary"},
+		},
+		{
+			name: "invalid target",
+			err:  invalidTarget("unknown pane %s", "%3"),
+			want: ipc.Error{Code: ipc.ErrorCodeInvalidTarget, Message: "unknown pane %3"},
+		},
+		{
+			name: "wrapped command error keeps its code",
+			err:  fmt.Errorf("context: %w", invalidTarget("unknown task x")),
+			want: ipc.Error{Code: ipc.ErrorCodeInvalidTarget, Message: "unknown task x"},
+		},
+		{
+			name: "anything else is internal",
+			err:  errors.New("disk full"),
+			want: ipc.Error{Code: ipc.ErrorCodeInternal, Message: "disk full"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replyError(tt.err); *got != tt.want {
+				t.Errorf("replyError = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleCommandErrorCodes(t *testing.T) {
+	target := ipc.Envelope{Session: "work", SessionID: "$1", Window: "api", WindowID: "@1", Pane: "%1"}
+	tests := []struct {
+		name string
+		env  ipc.Envelope
+		code string
+	}{
+		{name: "start without summary", env: withCommand(target, "start_task"), code: ipc.ErrorCodeInvalidArgument},
+		{name: "update without summary", env: withCommand(target, "update_task"), code: ipc.ErrorCodeInvalidArgument},
+		{name: "notify without summary", env: withCommand(target, "notify"), code: ipc.ErrorCodeInvalidArgument},
+		{name: "unknown command", env: withCommand(target, "launch_rockets"), code: ipc.ErrorCodeUnknownCommand},
+		{name: "unknown task id", env: ipc.Envelope{Command: "finish_task", TaskID: "$1|@1|%9"}, code: ipc.ErrorCodeInvalidTarget},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer()
+			err := s.handleCommand(tt.env, &ipc.Envelope{})
+			if err == nil {
+				t.Fatal("handleCommand succeeded, want an error")
+			}
+			if got := replyError(err).Code; got != tt.code {
+				t.Errorf("code = %s, want %s (%v)", got, tt.code, err)
+			}
+		})
+	}
+}
+
+func TestIPCErrorMessage(t *testing.T) {
+	if got := (&ipc.Error{Code: "invalid_target", Message: "unknown pane"}).Error(); got != "invalid_target: unknown pane" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := (&ipc.Error{Message: "rejected"}).Error(); got != "rejected" {
+		t.Errorf("Error() without code = %q", got)
+	}
+}
+
+func withCommand(env ipc.Envelope, command string) ipc.Envelope {
+	env.Command = command
+	return env
+}
+
+// newTestServer returns a server that keeps everything in memory and talks
+// to no notifier.
+func newTestServer() *server {
+	return &server{
+		tasks:          make(map[string]*taskRecord),
+		subscribers:    make(map[*uiSubscriber]struct{}),
+		reapNow:        make(chan struct{}, 1),
+		notifications:  newNotificationRouter(nil, nil, nil, defaultDigestWindow),
+		reminderDelays: (*reminderSettings)(nil).delays(),
+		paneHashes:     make(map[string][32]byte),
+		prompts:        newPromptMatcher(nil),
+	}
+}
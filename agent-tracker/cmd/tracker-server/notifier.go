@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	notifierDesktop = "desktop"
+	notifierTmux    = "tmux"
+	notifierCommand = "command"
+	notifierWebhook = "webhook"
+
+	tmuxNotifyPopup   = "popup"
+	tmuxNotifyBell    = "bell"
+	tmuxNotifyMessage = "message"
+
+	// notifyStatusMessage is the status rules see for an explicit notify
+	// command, which is not tied to a task status change.
+	notifyStatusMessage = "notify"
+)
+
+// notification is one message on its way to the configured backends.
+type notification struct {
+	Title    string
+	Message  string
+	Status   string
+	Target   tmuxTarget
+	Duration time.Duration
+	Action   *notificationAction
+}
+
+type notifier interface {
+	notify(n notification) error
+}
+
+// notifierSettings configures one backend. Type picks the backend; the
+// remaining fields only apply to the type that reads them.
+type notifierSettings struct {
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type"`
+	TmuxMode string `json:"tmux_mode,omitempty"`
+	Command  string `json:"command,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// notificationRule routes notifications that match every field it sets. The
+// first matching rule decides: Silent drops the notification, Notifiers picks
+// backends by name, and neither falls back to the default backends.
+type notificationRule struct {
+	Session     string   `json:"session,omitempty"`
+	Window      string   `json:"window,omitempty"`
+	Statuses    []string `json:"statuses,omitempty"`
+	MinDuration string   `json:"min_duration,omitempty"`
+	Notifiers   []string `json:"notifiers,omitempty"`
+	Silent      bool     `json:"silent,omitempty"`
+}
+
+// quietHours limits delivery to the Allow backends between Start and End,
+// given as local HH:MM. A window that wraps midnight is allowed.
+type quietHours struct {
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+	Allow []string `json:"allow,omitempty"`
+}
+
+// notificationRouter holds the backends and rules loaded from settings.
+type notificationRouter struct {
+	notifiers map[string]notifier
+	defaults  []string
+	rules     []notificationRule
+	quiet     *quietHours
+	digest    *notificationDigest
+}
+
+// newNotificationRouter builds the router from settings. Without any
+// configured backend every notification goes to the desktop, as before
+// backends were configurable. A zero digestWindow delivers immediately.
+func newNotificationRouter(backends []notifierSettings, rules []notificationRule, quiet *quietHours, digestWindow time.Duration) *notificationRouter {
+	r := &notificationRouter{notifiers: map[string]notifier{}, rules: rules, quiet: quiet}
+	if digestWindow > 0 {
+		r.digest = &notificationDigest{window: digestWindow, deliver: r.deliver}
+	}
+	for _, cfg := range backends {
+		name := firstNonEmpty(cfg.Name, cfg.Type)
+		n, err := buildNotifier(cfg)
+		if err != nil {
+			log.Printf("notifier %s disabled: %v", name, err)
+			continue
+		}
+		if _, exists := r.notifiers[name]; exists {
+			log.Printf("notifier %s defined twice; keeping the first", name)
+			continue
+		}
+		r.notifiers[name] = n
+		r.defaults = append(r.defaults, name)
+	}
+	if len(r.notifiers) == 0 {
+		r.notifiers[notifierDesktop] = desktopNotifier{}
+		r.defaults = []string{notifierDesktop}
+	}
+	return r
+}
+
+func buildNotifier(cfg notifierSettings) (notifier, error) {
+	switch strings.TrimSpace(cfg.Type) {
+	case notifierDesktop:
+		return desktopNotifier{}, nil
+	case notifierTmux:
+		mode := firstNonEmpty(cfg.TmuxMode, tmuxNotifyPopup)
+		switch mode {
+		case tmuxNotifyPopup, tmuxNotifyBell, tmuxNotifyMessage:
+			return tmuxNotifier{mode: mode}, nil
+		}
+		return nil, fmt.Errorf("unknown tmux_mode %q", mode)
+	case notifierCommand:
+		if strings.TrimSpace(cfg.Command) == "" {
+			return nil, errors.New("command notifier requires command")
+		}
+		return commandNotifier{command: cfg.Command, timeout: commandNotifierTimeout}, nil
+	case notifierWebhook:
+		if err := requireLoopbackURL(cfg.URL); err != nil {
+			return nil, err
+		}
+		return webhookNotifier{url: cfg.URL, client: &http.Client{Timeout: 5 * time.Second}}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q", cfg.Type)
+	}
+}
+
+func requireLoopbackURL(raw string) error {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("webhook url %q must be http or https", raw)
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("webhook url %q is not a local endpoint", raw)
+}
+
+// route returns the backends a notification should go to at now.
+func (r *notificationRouter) route(n notification, now time.Time) []string {
+	names := r.defaults
+	for _, rule := range r.rules {
+		if !rule.matches(n) {
+			continue
+		}
+		if rule.Silent {
+			return nil
+		}
+		if len(rule.Notifiers) > 0 {
+			names = rule.Notifiers
+		}
+		break
+	}
+	if r.quiet != nil && r.quiet.active(now) {
+		allowed := make([]string, 0, len(names))
+		for _, name := range names {
+			for _, allow := range r.quiet.Allow {
+				if name == allow {
+					allowed = append(allowed, name)
+					break
+				}
+			}
+		}
+		names = allowed
+	}
+	return names
+}
+
+// send routes n and delivers it, through the digest when digestBatches
+// allows.
+func (r *notificationRouter) send(n notification, now time.Time) error {
+	names := r.route(n, now)
+	if len(names) == 0 {
+		return nil
+	}
+	if r.digest != nil && digestBatches(n.Status) {
+		r.digest.add(n, names)
+		return nil
+	}
+	var errs []error
+	for _, name := range names {
+		errs = append(errs, r.deliver(name, n))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *notificationRouter) deliver(name string, n notification) error {
+	backend, ok := r.notifiers[name]
+	if !ok {
+		return fmt.Errorf("unknown notifier %q", name)
+	}
+	if err := backend.notify(n); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func (rule notificationRule) matches(n notification) bool {
+	if rule.Session != "" && rule.Session != n.Target.SessionID && !strings.EqualFold(rule.Session, stripSessionIndexPrefix(n.Target.SessionName)) && !strings.EqualFold(rule.Session, n.Target.SessionName) {
+		return false
+	}
+	if rule.Window != "" && rule.Window != n.Target.WindowID && !strings.EqualFold(rule.Window, n.Target.WindowName) {
+		return false
+	}
+	if len(rule.Statuses) > 0 {
+		found := false
+		for _, status := range rule.Statuses {
+			if status == n.Status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.MinDuration != "" {
+		min, err := time.ParseDuration(rule.MinDuration)
+		if err != nil {
+			log.Printf("notification rule: invalid min_duration %q", rule.MinDuration)
+			return false
+		}
+		if n.Duration < min {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *quietHours) active(now time.Time) bool {
+	start, okStart := parseClock(q.Start)
+	end, okEnd := parseClock(q.End)
+	if !okStart || !okEnd || start == end {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+func parseClock(value string) (int, bool) {
+	ts, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, false
+	}
+	return ts.Hour()*60 + ts.Minute(), true
+}
+
+// dispatchNotification routes n through the configured rules and backends.
+func (s *server) dispatchNotification(n notification) error {
+	s.mu.Lock()
+	router := s.notifications
+	s.mu.Unlock()
+	if router == nil {
+		router = newNotificationRouter(nil, nil, nil, 0)
+	}
+	return router.send(n, time.Now())
+}
+
+// notificationFor fills in names, the task duration and the click action for
+// a notification about target.
+func (s *server) notificationFor(target tmuxTarget, status, message string) notification {
+	target = s.fillTargetNamesFromTask(target)
+	n := notification{
+		Title:   notificationTitleForTarget(target),
+		Message: message,
+		Status:  status,
+		Target:  target,
+		Action:  notificationActionForTarget(target),
+	}
+	s.mu.Lock()
+	if t, ok := s.tasks[s.taskKeyForLocked(target)]; ok && !t.StartedAt.IsZero() {
+		end := time.Now()
+		if t.CompletedAt != nil {
+			end = *t.CompletedAt
+		}
+		n.Duration = end.Sub(t.StartedAt)
+	}
+	s.mu.Unlock()
+	return n
+}
+
+type desktopNotifier struct{}
+
+func (desktopNotifier) notify(n notification) error {
+	return sendSystemNotification(n.Title, n.Message, n.Action)
+}
+
+// tmuxNotifier reaches headless machines through every attached tmux client.
+type tmuxNotifier struct {
+	mode string
+}
+
+func (t tmuxNotifier) notify(n notification) error {
+	clients, err := listClients()
+	if err != nil {
+		return err
+	}
+	text := n.Title + ": " + n.Message
+	if t.mode == tmuxNotifyBell && n.Target.WindowID != "" {
+		if err := runTmux("set-option", "-w", "-t", n.Target.WindowID, "@unread", "1"); err != nil {
+			log.Printf("tmux bell: %v", err)
+		}
+	}
+	var errs []error
+	for _, client := range clients {
+		switch t.mode {
+		case tmuxNotifyPopup:
+			script := fmt.Sprintf("printf '%%s\\n\\n%%s\\n' %s %s; read -rsn1 -t 15", shellQuote(n.Title), shellQuote(n.Message))
+			cmd := exec.Command("tmux", "display-popup", "-c", client, "-T", " Tracker ", "-w", "60%", "-h", "8", "-E", "bash -c "+shellQuote(script))
+			if err := cmd.Start(); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			go cmd.Wait()
+		case tmuxNotifyBell:
+			if err := writeBell(client); err != nil {
+				errs = append(errs, err)
+			}
+			if err := runTmux("display-message", "-c", client, text); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			if err := runTmux("display-message", "-c", client, text); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func writeBell(tty string) error {
+	file, err := os.OpenFile(tty, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write([]byte{'\a'})
+	return err
+}
+
+// commandNotifierTimeout bounds how long a command backend may run before it
+// is killed.
+const commandNotifierTimeout = 10 * time.Second
+
+// commandNotifier runs a shell command with the notification in its
+// environment.
+type commandNotifier struct {
+	command string
+	timeout time.Duration
+}
+
+func (c commandNotifier) notify(n notification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.command)
+	// Children of sh may hold the output pipe open after sh is killed.
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(),
+		"TRACKER_TITLE="+n.Title,
+		"TRACKER_MESSAGE="+n.Message,
+		"TRACKER_STATUS="+n.Status,
+		"TRACKER_SESSION="+n.Target.SessionName,
+		"TRACKER_SESSION_ID="+n.Target.SessionID,
+		"TRACKER_WINDOW="+n.Target.WindowName,
+		"TRACKER_WINDOW_ID="+n.Target.WindowID,
+		"TRACKER_PANE="+n.Target.PaneID,
+		"TRACKER_DURATION_SECONDS="+strconv.Itoa(int(n.Duration.Seconds())),
+	)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("command timed out after %s", c.timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%w (%s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// webhookNotifier posts the notification as JSON to a local endpoint.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (w webhookNotifier) notify(n notification) error {
+	body, err := json.Marshal(map[string]any{
+		"title":            n.Title,
+		"message":          n.Message,
+		"status":           n.Status,
+		"session":          n.Target.SessionName,
+		"session_id":       n.Target.SessionID,
+		"window":           n.Target.WindowName,
+		"window_id":        n.Target.WindowID,
+		"pane":             n.Target.PaneID,
+		"duration_seconds": n.Duration.Seconds(),
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationRuleMatches(t *testing.T) {
+	n := notification{
+		Status:   statusCompleted,
+		Duration: 10 * time.Minute,
+		Target:   tmuxTarget{SessionID: "$1", SessionName: "2 - work", WindowID: "@3", WindowName: "api"},
+	}
+	tests := []struct {
+		name string
+		rule notificationRule
+		want bool
+	}{
+		{name: "empty rule", rule: notificationRule{}, want: true},
+		{name: "session id", rule: notificationRule{Session: "$1"}, want: true},
+		{name: "session name without index", rule: notificationRule{Session: "Work"}, want: true},
+		{name: "full session name", rule: notificationRule{Session: "2 - work"}, want: true},
+		{name: "other session", rule: notificationRule{Session: "play"}, want: false},
+		{name: "window name", rule: notificationRule{Window: "API"}, want: true},
+		{name: "window id", rule: notificationRule{Window: "@3"}, want: true},
+		{name: "other window", rule: notificationRule{Window: "web"}, want: false},
+		{name: "status listed", rule: notificationRule{Statuses: []string{statusFailed, statusCompleted}}, want: true},
+		{name: "status not listed", rule: notificationRule{Statuses: []string{statusFailed}}, want: false},
+		{name: "long enough", rule: notificationRule{MinDuration: "5m"}, want: true},
+		{name: "too short", rule: notificationRule{MinDuration: "1h"}, want: false},
+		{name: "invalid min duration never matches", rule: notificationRule{MinDuration: "long"}, want: false},
+		{name: "every field must match", rule: notificationRule{Session: "work", Window: "web"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.matches(n); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuietHoursActive(t *testing.T) {
+	at := func(hour, minute int) time.Time { return time.Date(2026, 5, 1, hour, minute, 0, 0, time.Local) }
+	tests := []struct {
+		name  string
+		quiet quietHours
+		now   time.Time
+		want  bool
+	}{
+		{name: "inside a daytime window", quiet: quietHours{Start: "12:00", End: "13:30"}, now: at(12, 45), want: true},
+		{name: "end is exclusive", quiet: quietHours{Start: "12:00", End: "13:30"}, now: at(13, 30), want: false},
+		{name: "start is inclusive", quiet: quietHours{Start: "12:00", End: "13:30"}, now: at(12, 0), want: true},
+		{name: "before midnight in a wrapping window", quiet: quietHours{Start: "22:00", End: "07:00"}, now: at(23, 15), want: true},
+		{name: "after midnight in a wrapping window", quiet: quietHours{Start: "22:00", End: "07:00"}, now: at(6, 59), want: true},
+		{name: "outside a wrapping window", quiet: quietHours{Start: "22:00", End: "07:00"}, now: at(12, 0), want: false},
+		{name: "empty window", quiet: quietHours{Start: "09:00", End: "09:00"}, now: at(9, 0), want: false},
+		{name: "invalid clock", quiet: quietHours{Start: "9pm", End: "07:00"}, now: at(23, 0), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.quiet.active(tt.now); got != tt.want {
+				t.Errorf("active = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type recordingNotifier struct {
+	sent *[]string
+	name string
+}
+
+func (r recordingNotifier) notify(n notification) error {
+	*r.sent = append(*r.sent, r.name+":"+n.Message)
+	return nil
+}
+
+func TestNotificationRouterRoute(t *testing.T) {
+	var sent []string
+	quietNow := time.Date(2026, 5, 1, 23, 0, 0, 0, time.Local)
+	dayNow := time.Date(2026, 5, 1, 12, 0, 0, 0, time.Local)
+	r := &notificationRouter{
+		notifiers: map[string]notifier{
+			"desktop": recordingNotifier{sent: &sent, name: "desktop"},
+			"phone":   recordingNotifier{sent: &sent, name: "phone"},
+		},
+		defaults: []string{"desktop", "phone"},
+		rules: []notificationRule{
+			{Window: "scratch", Silent: true},
+			{Statuses: []string{statusFailed}, Notifiers: []string{"phone"}},
+			{Statuses: []string{statusFailed}, Silent: true},
+		},
+		quiet: &quietHours{Start: "22:00", End: "07:00", Allow: []string{"phone"}},
+	}
+	tests := []struct {
+		name string
+		n    notification
+		now  time.Time
+		want []string
+	}{
+		{name: "defaults", n: notification{Status: statusCompleted}, now: dayNow, want: []string{"desktop", "phone"}},
+		{name: "silent rule", n: notification{Status: statusCompleted, Target: tmuxTarget{WindowName: "scratch"}}, now: dayNow, want: nil},
+		{name: "first matching rule wins", n: notification{Status: statusFailed}, now: dayNow, want: []string{"phone"}},
+		{name: "quiet hours keep allowed backends", n: notification{Status: statusCompleted}, now: quietNow, want: []string{"phone"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.route(tt.n, tt.now); !slices.Equal(got, tt.want) {
+				t.Errorf("route = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if err := r.send(notification{Status: statusFailed, Message: "boom"}, dayNow); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(sent, []string{"phone:boom"}) {
+		t.Errorf("sent = %v, want phone:boom", sent)
+	}
+}
+
+func TestBuildNotifier(t *testing.T) {
+	tests := []struct {
+		cfg notifierSettings
+		ok  bool
+	}{
+		{cfg: notifierSettings{Type: "desktop"}, ok: true},
+		{cfg: notifierSettings{Type: "tmux"}, ok: true},
+		{cfg: notifierSettings{Type: "tmux", TmuxMode: "bell"}, ok: true},
+		{cfg: notifierSettings{Type: "tmux", TmuxMode: "siren"}, ok: false},
+		{cfg: notifierSettings{Type: "command", Command: "true"}, ok: true},
+		{cfg: notifierSettings{Type: "command"}, ok: false},
+		{cfg: notifierSettings{Type: "webhook", URL: "http://127.0.0.1:9000/hook"}, ok: true},
+		{cfg: notifierSettings{Type: "webhook", URL: "https://localhost/hook"}, ok: true},
+		{cfg: notifierSettings{Type: "webhook", URL: "https://example.com/hook"}, ok: false},
+		{cfg: notifierSettings{Type: "webhook", URL: "ftp://127.0.0.1/hook"}, ok: false},
+		{cfg: notifierSettings{Type: "pager"}, ok: false},
+	}
+	for _, tt := range tests {
+		if _, err := buildNotifier(tt.cfg); (err == nil) != tt.ok {
+			t.Errorf("buildNotifier(%+v) = %v, want ok %v", tt.cfg, err, tt.ok)
+		}
+	}
+}
+
+func TestNewNotificationRouterFallsBackToDesktop(t *testing.T) {
+	r := newNotificationRouter([]notifierSettings{{Type: "pager"}}, nil, nil, 0)
+	if !slices.Equal(r.defaults, []string{notifierDesktop}) {
+		t.Errorf("defaults = %v, want desktop only", r.defaults)
+	}
+}
+
+func TestCommandNotifier(t *testing.T) {
+	ok := commandNotifier{command: `test "$TRACKER_STATUS" = completed && test "$TRACKER_WINDOW" = api`, timeout: 5 * time.Second}
+	if err := ok.notify(notification{Status: statusCompleted, Target: tmuxTarget{WindowName: "api"}}); err != nil {
+		t.Errorf("notify with matching environment: %v", err)
+	}
+	hung := commandNotifier{command: "sleep 10", timeout: 100 * time.Millisecond}
+	start := time.Now()
+	err := hung.notify(notification{})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("hung command: err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("hung command took %s to give up", elapsed)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+var errPeerCredentialsUnsupported = errors.New("peer credentials are not supported on this platform")
+
+// peerLookupTTL is how long the pane list and process table behind pane
+// attribution are reused before tmux and ps are asked again.
+const peerLookupTTL = 10 * time.Second
+
+// peerCred identifies the process that connected to the socket.
+type peerCred struct {
+	PID int
+	UID int
+}
+
+// acceptPeer reports whether a socket connection may talk to the server: only
+// processes of the user running the server may. It returns the peer's PID, or
+// 0 where the platform cannot tell.
+func acceptPeer(cred peerCred, err error) (int, bool) {
+	switch {
+	case errors.Is(err, errPeerCredentialsUnsupported):
+		return 0, true
+	case err != nil:
+		log.Printf("peer credentials: %v", err)
+		return 0, false
+	case cred.UID != os.Getuid():
+		log.Printf("rejected connection from uid %d (pid %d)", cred.UID, cred.PID)
+		return 0, false
+	}
+	return cred.PID, true
+}
+
+// selfReportCommands are the commands an agent sends about its own work. A
+// process inside a pane that has a task of its own may send them only for
+// that pane, which catches agents reporting to the wrong target.
+var selfReportCommands = map[string]bool{
+	"start_task":     true,
+	"push_task":      true,
+	"update_task":    true,
+	"finish_task":    true,
+	"wait_for_input": true,
+	"block_task":     true,
+	"fail_task":      true,
+	"resume_task":    true,
+	"heartbeat":      true,
+	"set_progress":   true,
+	"set_checklist":  true,
+	"check_items":    true,
+	"ask_user":       true,
+	"notify":         true,
+}
+
+// attributePeer resolves the sending process to its tmux pane. A command that
+// names no target gets that pane instead of whatever pane tmux considers
+// current; a self-report that names another pane is rejected when the
+// sender's pane has a task of its own. Senders outside tmux, or unknown ones
+// as from the HTTP gateway, pass unchanged.
+func (s *server) attributePeer(env *ipc.Envelope) error {
+	if env.PeerPID <= 0 || !s.paneAttributionEnabled() {
+		return nil
+	}
+	switch env.Command {
+	case "notifications_toggle", "reconcile":
+		return nil
+	}
+	named := env.Pane != "" || env.WindowID != "" || env.SessionID != "" || env.Window != "" || env.Session != ""
+	if named && !selfReportCommands[env.Command] {
+		return nil
+	}
+	sender, err := s.peerPanes.paneForPID(env.PeerPID, time.Now())
+	if err != nil {
+		return nil
+	}
+	if !named {
+		env.SessionID, env.WindowID, env.Pane = sender.SessionID, sender.WindowID, sender.PaneID
+		return nil
+	}
+	if env.Pane == sender.PaneID || (env.Pane == "" && (env.WindowID == "" || env.WindowID == sender.WindowID)) {
+		return nil
+	}
+	s.mu.Lock()
+	_, busy := s.tasks[s.taskKeyForLocked(sender)]
+	s.mu.Unlock()
+	if !busy {
+		return nil
+	}
+	return invalidTarget("pid %d runs in pane %s, which has its own task; it cannot send %s for %s", env.PeerPID, sender.PaneID, env.Command, firstNonEmpty(env.Pane, env.WindowID, env.Window, env.SessionID, env.Session))
+}
+
+func (s *server) paneAttributionEnabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paneAttribution
+}
+
+// paneLookup finds the tmux pane a process runs in. Self-reports arrive
+// often, so the pane list and the process table are kept for peerLookupTTL;
+// a process newer than the table is looked up on its own, and a lookup that
+// fails on old data is retried once on fresh data.
+type paneLookup struct {
+	mu      sync.Mutex
+	at      time.Time
+	panes   map[int]tmuxTarget
+	parents map[int]int
+}
+
+func (l *paneLookup) paneForPID(pid int, now time.Time) (tmuxTarget, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fresh := false
+	if l.panes == nil || now.Sub(l.at) > peerLookupTTL {
+		if err := l.refresh(now); err != nil {
+			return tmuxTarget{}, err
+		}
+		fresh = true
+	}
+	if pane, ok := l.walk(pid); ok {
+		return pane, nil
+	}
+	if !fresh {
+		if err := l.refresh(now); err != nil {
+			return tmuxTarget{}, err
+		}
+		if pane, ok := l.walk(pid); ok {
+			return pane, nil
+		}
+	}
+	return tmuxTarget{}, fmt.Errorf("process is not in a tmux pane")
+}
+
+func (l *paneLookup) refresh(now time.Time) error {
+	panes, err := tmuxPanePIDs()
+	if err != nil {
+		return err
+	}
+	parents, err := processParents()
+	if err != nil {
+		return err
+	}
+	l.at, l.panes, l.parents = now, panes, parents
+	return nil
+}
+
+// walk goes up the process tree from pid until it reaches a pane's shell.
+func (l *paneLookup) walk(pid int) (tmuxTarget, bool) {
+	// The depth limit guards against a cycle in a racy process listing.
+	for depth := 0; pid > 1 && depth < 64; depth++ {
+		if pane, ok := l.panes[pid]; ok {
+			return pane, true
+		}
+		ppid, ok := l.parents[pid]
+		if !ok {
+			var err error
+			if ppid, err = processParent(pid); err != nil {
+				return tmuxTarget{}, false
+			}
+			l.parents[pid] = ppid
+		}
+		pid = ppid
+	}
+	return tmuxTarget{}, false
+}
+
+// tmuxPanePIDs maps the shell of every tmux pane to the pane.
+func tmuxPanePIDs() (map[int]tmuxTarget, error) {
+	output, err := tmuxOutput("list-panes", "-a", "-F", "#{pane_pid}:::#{session_id}:::#{window_id}:::#{pane_id}")
+	if err != nil {
+		return nil, err
+	}
+	panes := map[int]tmuxTarget{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ":::")
+		if len(parts) != 4 {
+			continue
+		}
+		panePID, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		panes[panePID] = tmuxTarget{SessionID: parts[1], WindowID: parts[2], PaneID: parts[3]}
+	}
+	return panes, nil
+}
+
+// processParent asks ps for the parent of one process.
+func processParent(pid int) (int, error) {
+	output, err := exec.Command("ps", "-o", "ppid=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(output)))
+}
+
+// processParents maps every running process to its parent.
+func processParents() (map[int]int, error) {
+	output, err := exec.Command("ps", "-A", "-o", "pid=,ppid=").Output()
+	if err != nil {
+		return nil, err
+	}
+	parents := map[int]int{}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		parents[pid] = ppid
+	}
+	return parents, nil
+}
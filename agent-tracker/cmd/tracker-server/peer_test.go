@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestAcceptPeer(t *testing.T) {
+	tests := []struct {
+		name    string
+		cred    peerCred
+		err     error
+		wantPID int
+		wantOK  bool
+	}{
+		{name: "same user", cred: peerCred{PID: 42, UID: os.Getuid()}, wantPID: 42, wantOK: true},
+		{name: "other user", cred: peerCred{PID: 42, UID: os.Getuid() + 1}, wantOK: false},
+		{name: "credentials unreadable", err: errors.New("bad socket"), wantOK: false},
+		{name: "platform cannot tell", err: errPeerCredentialsUnsupported, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pid, ok := acceptPeer(tt.cred, tt.err)
+			if pid != tt.wantPID || ok != tt.wantOK {
+				t.Errorf("acceptPeer = %d, %v; want %d, %v", pid, ok, tt.wantPID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPaneLookupWalk(t *testing.T) {
+	pane := tmuxTarget{SessionID: "$1", WindowID: "@1", PaneID: "%1"}
+	l := &paneLookup{
+		panes:   map[int]tmuxTarget{100: pane},
+		parents: map[int]int{300: 200, 200: 100, 100: 1, 400: 1, 500: 501, 501: 500},
+	}
+	if got, ok := l.walk(300); !ok || got != pane {
+		t.Errorf("walk(300) = %+v, %v; want %+v", got, ok, pane)
+	}
+	if got, ok := l.walk(100); !ok || got != pane {
+		t.Errorf("walk of the pane's shell = %+v, %v; want %+v", got, ok, pane)
+	}
+	if _, ok := l.walk(400); ok {
+		t.Error("walk found a pane for a process outside tmux")
+	}
+	if _, ok := l.walk(500); ok {
+		t.Error("walk found a pane in a parent cycle")
+	}
+}
+
+func TestPaneLookupReusesFreshData(t *testing.T) {
+	now := time.Now()
+	pane := tmuxTarget{SessionID: "$1", WindowID: "@1", PaneID: "%1"}
+	l := &paneLookup{at: now, panes: map[int]tmuxTarget{100: pane}, parents: map[int]int{300: 100}}
+	got, err := l.paneForPID(300, now.Add(peerLookupTTL/2))
+	if err != nil || got != pane {
+		t.Errorf("paneForPID = %+v, %v; want %+v from the cache", got, err, pane)
+	}
+	if !l.at.Equal(now) {
+		t.Error("a lookup within the TTL refreshed the cache")
+	}
+}
+
+func TestAttributePeer(t *testing.T) {
+	sender := tmuxTarget{SessionID: "$1", WindowID: "@1", PaneID: "%1"}
+	primed := func(s *server) {
+		s.paneAttribution = true
+		s.peerPanes = paneLookup{at: time.Now(), panes: map[int]tmuxTarget{100: sender}, parents: map[int]int{300: 100}}
+	}
+	rootKey := taskKey("$1", "@1", "%1")
+	withRoot := func(s *server) {
+		s.tasks[rootKey] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1", Status: statusInProgress, StartedAt: time.Now()}
+	}
+	withSubtaskOnly := func(s *server) {
+		s.tasks[rootKey+"#2"] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1", Parent: rootKey, Status: statusInProgress, StartedAt: time.Now()}
+	}
+	otherPane := ipc.Envelope{SessionID: "$1", WindowID: "@2", Pane: "%2"}
+	tests := []struct {
+		name     string
+		setup    func(*server)
+		env      ipc.Envelope
+		wantPane string
+		wantErr  bool
+	}{
+		{name: "untargeted command goes to the sender's pane", env: ipc.Envelope{Command: "update_task"}, wantPane: "%1"},
+		{name: "own pane", setup: withRoot, env: ipc.Envelope{Command: "update_task", SessionID: "$1", WindowID: "@1", Pane: "%1"}, wantPane: "%1"},
+		{name: "other pane while the sender's is idle", env: withCommand(otherPane, "update_task"), wantPane: "%2"},
+		{name: "other pane while the sender has a task", setup: withRoot, env: withCommand(otherPane, "update_task"), wantErr: true},
+		{name: "other pane while the sender has a sub-task", setup: withSubtaskOnly, env: withCommand(otherPane, "finish_task"), wantErr: true},
+		{name: "commands about other panes are not self-reports", setup: withRoot, env: withCommand(otherPane, "acknowledge"), wantPane: "%2"},
+		{name: "reconcile is left alone", env: ipc.Envelope{Command: "reconcile"}, wantPane: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer()
+			primed(s)
+			if tt.setup != nil {
+				tt.setup(s)
+			}
+			env := tt.env
+			env.PeerPID = 300
+			err := s.attributePeer(&env)
+			if tt.wantErr {
+				if err == nil || replyError(err).Code != ipc.ErrorCodeInvalidTarget {
+					t.Fatalf("attributePeer error = %v, want an invalid target", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if env.Pane != tt.wantPane {
+				t.Errorf("pane = %q, want %q", env.Pane, tt.wantPane)
+			}
+		})
+	}
+
+	s := newTestServer()
+	primed(s)
+	withRoot(s)
+	s.paneAttribution = false
+	env := withCommand(otherPane, "update_task")
+	env.PeerPID = 300
+	if err := s.attributePeer(&env); err != nil {
+		t.Errorf("attributePeer with attribution off = %v, want nil", err)
+	}
+	env = ipc.Envelope{Command: "update_task"}
+	primed(s)
+	if err := s.attributePeer(&env); err != nil || env.Pane != "" {
+		t.Errorf("sender without a PID: pane %q, error %v; want it untouched", env.Pane, err)
+	}
+}
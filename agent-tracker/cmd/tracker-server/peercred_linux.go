@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// peerCredentials returns the process and user on the other end of a Unix
+// socket connection, as the kernel reports them through SO_PEERCRED.
+func peerCredentials(conn net.Conn) (peerCred, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return peerCred{}, errors.New("not a unix socket connection")
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return peerCred{}, err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return peerCred{}, err
+	}
+	if credErr != nil {
+		return peerCred{}, credErr
+	}
+	return peerCred{PID: int(cred.Pid), UID: int(cred.Uid)}, nil
+}
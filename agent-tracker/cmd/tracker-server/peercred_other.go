@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "net"
+
+// peerCredentials is only implemented on Linux; elsewhere the socket's 0600
+// mode is the only guard.
+func peerCredentials(net.Conn) (peerCred, error) {
+	return peerCred{}, errPeerCredentialsUnsupported
+}
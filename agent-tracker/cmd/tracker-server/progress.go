@@ -0,0 +1,113 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// setProgress records how far the task has come; a nil or empty progress
+// clears it. Progress reports count as activity.
+func (s *server) setProgress(target tmuxTarget, progress *ipc.Progress) error {
+	if progress != nil {
+		p := *progress
+		p.Label = strings.TrimSpace(p.Label)
+		switch {
+		case p.Done < 0 || p.Total < 0:
+			return invalidArgument("progress counts cannot be negative")
+		case p.Total > 0 && p.Done > p.Total:
+			return invalidArgument("progress done %d is more than total %d", p.Done, p.Total)
+		case p.Percent < 0 || p.Percent > 100:
+			return invalidArgument("progress percent must be between 0 and 100")
+		}
+		if p.Done == 0 && p.Total == 0 && p.Percent == 0 && p.Label == "" {
+			progress = nil
+		} else {
+			progress = &p
+		}
+	}
+	return s.updateTask(target, func(t *taskRecord) {
+		t.Progress = progress
+	})
+}
+
+// setChecklist replaces the task's checklist with steps; no steps clears it.
+func (s *server) setChecklist(target tmuxTarget, steps []ipc.Step) error {
+	checklist, err := cleanSteps(steps)
+	if err != nil {
+		return err
+	}
+	return s.updateTask(target, func(t *taskRecord) {
+		t.Checklist = checklist
+	})
+}
+
+// checkSteps marks the named steps done or not done as given, adding steps
+// the checklist does not have yet.
+func (s *server) checkSteps(target tmuxTarget, steps []ipc.Step) error {
+	updates, err := cleanSteps(steps)
+	if err != nil {
+		return err
+	}
+	if len(updates) == 0 {
+		return invalidArgument("check_items requires at least one item")
+	}
+	return s.updateTask(target, func(t *taskRecord) {
+		t.editChecklist(func(checklist []ipc.Step) []ipc.Step {
+			for _, update := range updates {
+				found := false
+				for i := range checklist {
+					if checklist[i].Name == update.Name {
+						checklist[i].Done = update.Done
+						found = true
+					}
+				}
+				if !found {
+					checklist = append(checklist, update)
+				}
+			}
+			return checklist
+		})
+	})
+}
+
+// editChecklist lets fn change a copy of the checklist in place and keeps
+// what it returns, leaving snapshots that share the old one untouched.
+func (t *taskRecord) editChecklist(fn func([]ipc.Step) []ipc.Step) {
+	t.Checklist = fn(slices.Clone(t.Checklist))
+}
+
+// updateTask applies fn to the task target addresses and records the
+// change as activity.
+func (s *server) updateTask(target tmuxTarget, fn func(*taskRecord)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.taskKeyForLocked(target)
+	t, ok := s.tasks[key]
+	if !ok {
+		return invalidTarget("no task for pane %s", target.PaneID)
+	}
+	fn(t)
+	touchActivityLocked(t, time.Now())
+	s.taskChangedLocked(key)
+	return nil
+}
+
+func cleanSteps(steps []ipc.Step) ([]ipc.Step, error) {
+	var cleaned []ipc.Step
+	seen := map[string]bool{}
+	for _, step := range steps {
+		step.Name = strings.TrimSpace(step.Name)
+		if step.Name == "" {
+			return nil, invalidArgument("checklist items need a name")
+		}
+		if seen[step.Name] {
+			continue
+		}
+		seen[step.Name] = true
+		cleaned = append(cleaned, step)
+	}
+	return cleaned, nil
+}
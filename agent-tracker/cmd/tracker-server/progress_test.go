@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestCleanSteps(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []ipc.Step
+		want    []ipc.Step
+		wantErr bool
+	}{
+		{name: "none", steps: nil, want: nil},
+		{
+			name:  "trimmed and deduplicated",
+			steps: []ipc.Step{{Name: " build "}, {Name: "test", Done: true}, {Name: "build", Done: true}},
+			want:  []ipc.Step{{Name: "build"}, {Name: "test", Done: true}},
+		},
+		{name: "blank name", steps: []ipc.Step{{Name: "build"}, {Name: "  "}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := cleanSteps(tt.steps)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: cleanSteps = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSteps(t *testing.T) {
+	s := newTestServer()
+	key := taskKey("$1", "@1", "%1")
+	s.tasks[key] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1", Status: statusInProgress}
+	target := tmuxTarget{SessionID: "$1", WindowID: "@1", PaneID: "%1"}
+	if err := s.setChecklist(target, []ipc.Step{{Name: "build"}, {Name: "test"}}); err != nil {
+		t.Fatal(err)
+	}
+	before := s.tasks[key].Checklist
+	if err := s.checkSteps(target, []ipc.Step{{Name: "test", Done: true}, {Name: "deploy"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := []ipc.Step{{Name: "build"}, {Name: "test", Done: true}, {Name: "deploy"}}
+	if got := s.tasks[key].Checklist; !slices.Equal(got, want) {
+		t.Errorf("checklist = %v, want %v", got, want)
+	}
+	if before[1].Done {
+		t.Error("checking a step changed the checklist an earlier snapshot holds")
+	}
+	var cmdErr *commandError
+	if err := s.checkSteps(target, nil); !errors.As(err, &cmdErr) || cmdErr.code != ipc.ErrorCodeInvalidArgument {
+		t.Errorf("checking nothing = %v, want invalid argument", err)
+	}
+	if err := s.setChecklist(target, nil); err != nil || s.tasks[key].Checklist != nil {
+		t.Errorf("clearing = %v, checklist %v", err, s.tasks[key].Checklist)
+	}
+}
+
+func TestSetProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress *ipc.Progress
+		want     *ipc.Progress
+		wantErr  bool
+	}{
+		{name: "steps", progress: &ipc.Progress{Done: 3, Total: 7, Label: " migrations "}, want: &ipc.Progress{Done: 3, Total: 7, Label: "migrations"}},
+		{name: "percent", progress: &ipc.Progress{Percent: 40}, want: &ipc.Progress{Percent: 40}},
+		{name: "all zeros clear", progress: &ipc.Progress{}},
+		{name: "nil clears", progress: nil},
+		{name: "negative", progress: &ipc.Progress{Done: -1}, wantErr: true},
+		{name: "done past total", progress: &ipc.Progress{Done: 8, Total: 7}, wantErr: true},
+		{name: "percent past 100", progress: &ipc.Progress{Percent: 101}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer()
+			key := taskKey("$1", "@1", "%1")
+			s.tasks[key] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1", Progress: &ipc.Progress{Done: 1, Total: 2}}
+			err := s.setProgress(tmuxTarget{SessionID: "$1", WindowID: "@1", PaneID: "%1"}, tt.progress)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := s.tasks[key].Progress
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("progress = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
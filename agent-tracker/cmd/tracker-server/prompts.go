@@ -0,0 +1,224 @@
+package main
+
+import (
+	"log"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultPromptLines = 15
+
+// defaultPromptPatterns match the approval prompts of the agent CLIs we run,
+// keyed by the pane's current command.
+var defaultPromptPatterns = map[string][]string{
+	"claude": {
+		`Do you want to (proceed|make this edit|create|run|allow)`,
+		`❯\s*1\.\s*Yes`,
+	},
+	"codex": {
+		`(?i)allow (this )?command\?`,
+		`(?i)approve (this|the following)`,
+		`(?i)\bYes, proceed\b`,
+	},
+	"opencode": {
+		`(?i)permission (required|requested)`,
+		`(?i)allow (once|always)`,
+	},
+}
+
+// promptSettings configures approval prompt detection. Patterns replaces the
+// defaults for each command it names; an empty list turns detection off for
+// that command. Lines is how much of the bottom of the pane is searched.
+type promptSettings struct {
+	Patterns map[string][]string `json:"patterns,omitempty"`
+	Lines    int                 `json:"lines,omitempty"`
+}
+
+type promptMatcher struct {
+	byCommand map[string][]*regexp.Regexp
+	lines     int
+}
+
+func newPromptMatcher(settings *promptSettings) *promptMatcher {
+	sources := map[string][]string{}
+	for command, patterns := range defaultPromptPatterns {
+		sources[command] = patterns
+	}
+	m := &promptMatcher{byCommand: map[string][]*regexp.Regexp{}, lines: defaultPromptLines}
+	if settings != nil {
+		for command, patterns := range settings.Patterns {
+			sources[strings.ToLower(strings.TrimSpace(command))] = patterns
+		}
+		if settings.Lines > 0 {
+			m.lines = settings.Lines
+		}
+	}
+	for command, patterns := range sources {
+		m.byCommand[command] = nil
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				log.Printf("prompt detection: invalid pattern %q for %s: %v", pattern, command, err)
+				continue
+			}
+			m.byCommand[command] = append(m.byCommand[command], re)
+		}
+	}
+	return m
+}
+
+// match returns the line of output that looks like an approval prompt,
+// checking only the patterns of the agent CLI running in the pane. Output of
+// a command with no patterns is never matched.
+func (m *promptMatcher) match(command, output string) string {
+	if m == nil {
+		return ""
+	}
+	patterns := m.byCommand[strings.ToLower(strings.TrimSpace(command))]
+	if len(patterns) == 0 {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > m.lines {
+		lines = lines[len(lines)-m.lines:]
+	}
+	for _, line := range lines {
+		for _, re := range patterns {
+			if re.MatchString(line) {
+				return strings.TrimSpace(line)
+			}
+		}
+	}
+	return ""
+}
+
+func (m *promptMatcher) knows(command string) bool {
+	if m == nil {
+		return false
+	}
+	_, ok := m.byCommand[strings.ToLower(strings.TrimSpace(command))]
+	return ok
+}
+
+// paneAgentCommands maps each pane to the agent CLI it runs. Agents started
+// through an interpreter show up as e.g. "node", so a pane whose current
+// command has no patterns is looked up by the arguments of the processes
+// under its shell.
+func (m *promptMatcher) paneAgentCommands() map[string]string {
+	if m == nil {
+		return nil
+	}
+	output, err := exec.Command("tmux", "list-panes", "-a", "-F", "#{pane_id}\t#{pane_pid}\t#{pane_current_command}").Output()
+	if err != nil {
+		return nil
+	}
+	commands := map[string]string{}
+	unknown := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		pane, command := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[2])
+		commands[pane] = command
+		if pid, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil && !m.knows(command) {
+			unknown[pane] = pid
+		}
+	}
+	if len(unknown) == 0 {
+		return commands
+	}
+	children, args, err := processArgs()
+	if err != nil {
+		return commands
+	}
+	for pane, pid := range unknown {
+		if command := agentCommandUnder(pid, children, args, m.knows); command != "" {
+			commands[pane] = command
+		}
+	}
+	return commands
+}
+
+// agentCommandUnder searches the processes below pid for one whose program
+// or script is a known agent CLI, so "node /usr/lib/node_modules/codex/bin/codex.js"
+// resolves to codex.
+func agentCommandUnder(pid int, children map[int][]int, args map[int]string, known func(string) bool) string {
+	queue := []int{pid}
+	seen := map[int]bool{}
+	for len(queue) > 0 {
+		pid, queue = queue[0], queue[1:]
+		if seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		for _, arg := range strings.Fields(args[pid]) {
+			name := strings.ToLower(filepath.Base(arg))
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+			if known(name) {
+				return name
+			}
+		}
+		queue = append(queue, children[pid]...)
+	}
+	return ""
+}
+
+// processArgs lists every running process by parent along with its command
+// line.
+func processArgs() (map[int][]int, map[int]string, error) {
+	output, err := exec.Command("ps", "-A", "-o", "pid=,ppid=,args=").Output()
+	if err != nil {
+		return nil, nil, err
+	}
+	children := map[int][]int{}
+	args := map[int]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		children[ppid] = append(children[ppid], pid)
+		args[pid] = strings.Join(fields[2:], " ")
+	}
+	return children, args, nil
+}
+
+// setPromptWaitingLocked moves a running task to waiting_input because its
+// pane shows an approval prompt; active says whether someone is looking at
+// the pane. Callers hold s.mu.
+func (s *server) setPromptWaitingLocked(key string, t *taskRecord, line string, active bool) {
+	t.Status = statusWaitingInput
+	t.StatusNote = "Approval needed: " + line
+	t.recordEvent(statusWaitingInput, t.StatusNote, time.Now())
+	t.PromptDetected = true
+	t.StalledAt = nil
+	t.Acknowledged = active
+	t.RemindersSent = 0
+	t.VisitedAt = nil
+	s.taskChangedLocked(key)
+	log.Printf("prompt detection: %s waiting for approval", key)
+}
+
+// clearPromptWaitingLocked resumes a task whose approval prompt went away.
+// Callers hold s.mu.
+func (s *server) clearPromptWaitingLocked(key string, t *taskRecord) {
+	t.recordEvent(eventResume, "approval prompt gone", time.Now())
+	t.Status = statusInProgress
+	t.StatusNote = ""
+	t.PromptDetected = false
+	t.Acknowledged = true
+	s.taskChangedLocked(key)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPromptMatcherMatch(t *testing.T) {
+	defaults := newPromptMatcher(nil)
+	tests := []struct {
+		name    string
+		matcher *promptMatcher
+		command string
+		output  string
+		want    string
+	}{
+		{
+			name:    "claude edit prompt",
+			matcher: defaults,
+			command: "claude",
+			output:  "● Update(main.go)\n\n Do you want to make this edit to main.go?\n ❯ 1. Yes\n   2. No\n",
+			want:    "Do you want to make this edit to main.go?",
+		},
+		{
+			name:    "command case and padding are ignored",
+			matcher: defaults,
+			command: " Codex ",
+			output:  "$ rm -rf build\nAllow command?\n",
+			want:    "Allow command?",
+		},
+		{
+			name:    "another agent's prompt does not count",
+			matcher: defaults,
+			command: "codex",
+			output:  "Do you want to proceed?\n",
+		},
+		{
+			name:    "unknown command is not checked",
+			matcher: defaults,
+			command: "node",
+			output:  "Do you want to proceed?\n",
+		},
+		{
+			name:    "prompt scrolled out of the searched lines",
+			matcher: newPromptMatcher(&promptSettings{Lines: 2}),
+			command: "claude",
+			output:  "Do you want to proceed?\none\ntwo\n",
+		},
+		{
+			name:    "custom patterns replace the defaults",
+			matcher: newPromptMatcher(&promptSettings{Patterns: map[string][]string{"Claude": {`^Go ahead\?`}}}),
+			command: "claude",
+			output:  "Do you want to proceed?\nGo ahead?\n",
+			want:    "Go ahead?",
+		},
+		{
+			name:    "empty list turns detection off",
+			matcher: newPromptMatcher(&promptSettings{Patterns: map[string][]string{"opencode": {}}}),
+			command: "opencode",
+			output:  "Permission required\n",
+		},
+		{
+			name:    "invalid patterns are skipped",
+			matcher: newPromptMatcher(&promptSettings{Patterns: map[string][]string{"aider": {"(", "Run shell command"}}}),
+			command: "aider",
+			output:  "Run shell command? (Y)es/(N)o\n",
+			want:    "Run shell command? (Y)es/(N)o",
+		},
+		{
+			name:    "nil matcher",
+			command: "claude",
+			output:  "Do you want to proceed?\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matcher.match(tt.command, tt.output); got != tt.want {
+				t.Errorf("match = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgentCommandUnder(t *testing.T) {
+	known := newPromptMatcher(nil).knows
+	children := map[int][]int{10: {11}, 11: {12}, 20: {21}, 30: {31}, 31: {30}}
+	args := map[int]string{
+		10: "-zsh",
+		11: "node /usr/local/lib/node_modules/@openai/codex/bin/codex.js --full-auto",
+		12: "rg TODO",
+		20: "-zsh",
+		21: "node server.js",
+		30: "bash",
+		31: "bash",
+	}
+	tests := []struct {
+		pid  int
+		want string
+	}{
+		{pid: 10, want: "codex"},
+		{pid: 20},
+		{pid: 30},
+		{pid: 99},
+	}
+	for _, tt := range tests {
+		if got := agentCommandUnder(tt.pid, children, args, known); got != tt.want {
+			t.Errorf("agentCommandUnder(%d) = %q, want %q", tt.pid, got, tt.want)
+		}
+	}
+}
+
+func TestSetPromptWaitingLocked(t *testing.T) {
+	for _, active := range []bool{false, true} {
+		s := newTestServer()
+		task := &taskRecord{Status: statusInProgress, RemindersSent: 2}
+		s.tasks["a"] = task
+		s.setPromptWaitingLocked("a", task, "Allow command?", active)
+		if task.Status != statusWaitingInput || !task.PromptDetected || task.Acknowledged != active || task.RemindersSent != 0 {
+			t.Errorf("active=%v: task = %+v", active, task)
+		}
+		if !strings.HasSuffix(task.StatusNote, "Allow command?") {
+			t.Errorf("note = %q", task.StatusNote)
+		}
+		s.clearPromptWaitingLocked("a", task)
+		if task.Status != statusInProgress || task.PromptDetected || !task.Acknowledged {
+			t.Errorf("active=%v: task after the prompt went away = %+v", active, task)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// questionRecord is a question an agent asked through ask_user. It stays on
+// the task after it is answered so the asking agent can read the answer back
+// from the state feed.
+type questionRecord struct {
+	ID         string     `json:"id"`
+	Text       string     `json:"text"`
+	Options    []string   `json:"options,omitempty"`
+	AskedAt    time.Time  `json:"asked_at"`
+	Answer     string     `json:"answer,omitempty"`
+	AnsweredAt *time.Time `json:"answered_at,omitempty"`
+}
+
+func (q *questionRecord) pending() bool {
+	return q != nil && q.AnsweredAt == nil
+}
+
+func (q *questionRecord) wire() *ipc.Question {
+	if q == nil {
+		return nil
+	}
+	out := &ipc.Question{
+		ID:      q.ID,
+		Text:    q.Text,
+		Options: append([]string(nil), q.Options...),
+		AskedAt: q.AskedAt.Format(time.RFC3339),
+		Answer:  q.Answer,
+	}
+	if q.AnsweredAt != nil {
+		out.AnsweredAt = q.AnsweredAt.Format(time.RFC3339)
+	}
+	return out
+}
+
+// resolveAnswer turns "2" into the second option; anything else is kept as
+// typed.
+func (q *questionRecord) resolveAnswer(answer string) string {
+	answer = strings.TrimSpace(answer)
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(q.Options) {
+		return q.Options[n-1]
+	}
+	return answer
+}
+
+// askUser puts the task into waiting_input with question attached, replacing
+// any earlier question. It reports whether the task just entered
+// waiting_input.
+func (s *server) askUser(target tmuxTarget, question ipc.Question) (bool, error) {
+	if target.SessionID == "" || target.WindowID == "" {
+		return false, invalidTarget("cannot ask: missing session or window ID")
+	}
+	target = normalizeTargetNames(target)
+	text := strings.TrimSpace(question.Text)
+	var options []string
+	for _, option := range question.Options {
+		if option = strings.TrimSpace(option); option != "" {
+			options = append(options, option)
+		}
+	}
+	now := time.Now()
+	id := strings.TrimSpace(question.ID)
+	if id == "" {
+		id = fmt.Sprintf("q-%d", now.UnixNano())
+	}
+	active := isActivePane(target.PaneID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.taskKeyForLocked(target)
+	target.TaskID = key
+	entered := s.setTaskStatusLocked(target, statusWaitingInput, text, active, now)
+	t := s.tasks[key]
+	t.Question = &questionRecord{ID: id, Text: text, Options: options, AskedAt: now}
+	s.taskChangedLocked(key)
+	return entered, nil
+}
+
+// answerQuestion records answer on the pane's pending question and puts the
+// task back to work. An empty questionID answers whatever is pending.
+func (s *server) answerQuestion(target tmuxTarget, questionID, answer string) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.questionKeyLocked(target, questionID)
+	t, ok := s.tasks[key]
+	if !ok || !t.Question.pending() {
+		return invalidTarget("no open question for pane %s", target.PaneID)
+	}
+	if questionID != "" && questionID != t.Question.ID {
+		return invalidArgument("question %s is no longer open", questionID)
+	}
+	t.Question.Answer = t.Question.resolveAnswer(answer)
+	t.Question.AnsweredAt = &now
+	t.recordEvent(eventAnswer, t.Question.Answer, now)
+	s.resumeFromQuestionLocked(t, now)
+	s.taskChangedLocked(key)
+	return nil
+}
+
+// cancelQuestion withdraws a pending question, e.g. when the asking agent
+// gave up waiting.
+func (s *server) cancelQuestion(target tmuxTarget, questionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.questionKeyLocked(target, questionID)
+	t, ok := s.tasks[key]
+	if !ok || !t.Question.pending() || (questionID != "" && questionID != t.Question.ID) {
+		return nil
+	}
+	now := time.Now()
+	t.Question = nil
+	t.recordEvent(eventResume, "question withdrawn", now)
+	s.resumeFromQuestionLocked(t, now)
+	s.taskChangedLocked(key)
+	return nil
+}
+
+// questionKeyLocked finds the task on target's pane that asked questionID,
+// or any task there with an open question when questionID is empty. It falls
+// back to the task the pane's commands go to.
+func (s *server) questionKeyLocked(target tmuxTarget, questionID string) string {
+	if target.TaskID != "" {
+		return target.TaskID
+	}
+	for key, t := range s.tasks {
+		if !t.onPane(target.SessionID, target.WindowID, target.PaneID) || !t.Question.pending() {
+			continue
+		}
+		if questionID == "" || t.Question.ID == questionID {
+			return key
+		}
+	}
+	return s.taskKeyForLocked(target)
+}
+
+func (s *server) resumeFromQuestionLocked(t *taskRecord, now time.Time) {
+	if t.Status != statusWaitingInput {
+		return
+	}
+	t.Status = statusInProgress
+	t.StatusNote = ""
+	t.Acknowledged = true
+	touchActivityLocked(t, now)
+}
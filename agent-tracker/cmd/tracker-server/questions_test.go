@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestQuestionResolveAnswer(t *testing.T) {
+	q := &questionRecord{Options: []string{"Keep", "Drop"}}
+	tests := map[string]string{
+		"1":        "Keep",
+		" 2 ":      "Drop",
+		"0":        "0",
+		"3":        "3",
+		"drop it":  "drop it",
+		"  later ": "later",
+	}
+	for answer, want := range tests {
+		if got := q.resolveAnswer(answer); got != want {
+			t.Errorf("resolveAnswer(%q) = %q, want %q", answer, got, want)
+		}
+	}
+	if got := (&questionRecord{}).resolveAnswer("1"); got != "1" {
+		t.Errorf("resolveAnswer without options = %q, want 1", got)
+	}
+}
+
+func TestAnswerQuestion(t *testing.T) {
+	target := tmuxTarget{SessionID: "$1", SessionName: "work", WindowID: "@1", WindowName: "api", PaneID: "%1"}
+	tests := []struct {
+		name       string
+		questionID string
+		answer     string
+		wantCode   string
+		wantAnswer string
+	}{
+		{name: "pending question by index", answer: "2", wantAnswer: "Postgres"},
+		{name: "question by ID", questionID: "q-1", answer: "typed", wantAnswer: "typed"},
+		{name: "stale question ID", questionID: "q-0", answer: "1", wantCode: ipc.ErrorCodeInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer()
+			key := taskKey("$1", "@1", "%1")
+			s.tasks[key] = &taskRecord{
+				SessionID: "$1", WindowID: "@1", Pane: "%1", Status: statusWaitingInput,
+				Question: &questionRecord{ID: "q-1", Text: "Which database?", Options: []string{"SQLite", "Postgres"}},
+			}
+			err := s.answerQuestion(target, tt.questionID, tt.answer)
+			if tt.wantCode != "" {
+				var cmdErr *commandError
+				if !errors.As(err, &cmdErr) || cmdErr.code != tt.wantCode {
+					t.Fatalf("answerQuestion = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			task := s.tasks[key]
+			if task.Question.Answer != tt.wantAnswer || task.Question.pending() {
+				t.Errorf("question = %+v, want answered with %q", task.Question, tt.wantAnswer)
+			}
+			if task.Status != statusInProgress || !task.Acknowledged {
+				t.Errorf("task = %+v, want back in progress", task)
+			}
+		})
+	}
+}
+
+func TestAnswerQuestionWithoutOpenQuestion(t *testing.T) {
+	s := newTestServer()
+	target := tmuxTarget{SessionID: "$1", WindowID: "@1", PaneID: "%1"}
+	var cmdErr *commandError
+	if err := s.answerQuestion(target, "", "yes"); !errors.As(err, &cmdErr) || cmdErr.code != ipc.ErrorCodeInvalidTarget {
+		t.Fatalf("answerQuestion = %v, want invalid target", err)
+	}
+}
+
+func TestCancelQuestion(t *testing.T) {
+	s := newTestServer()
+	key := taskKey("$1", "@1", "%1")
+	s.tasks[key] = &taskRecord{
+		SessionID: "$1", WindowID: "@1", Pane: "%1", Status: statusWaitingInput,
+		Question: &questionRecord{ID: "q-1", Text: "Ready?"},
+	}
+	target := tmuxTarget{SessionID: "$1", WindowID: "@1", PaneID: "%1"}
+	if err := s.cancelQuestion(target, "q-other"); err != nil {
+		t.Fatal(err)
+	}
+	if s.tasks[key].Question == nil {
+		t.Fatal("cancelling another question withdrew this one")
+	}
+	if err := s.cancelQuestion(target, "q-1"); err != nil {
+		t.Fatal(err)
+	}
+	if task := s.tasks[key]; task.Question != nil || task.Status != statusInProgress {
+		t.Errorf("task = %+v, want question withdrawn and back in progress", task)
+	}
+}
+
+func TestQuestionKeyLocked(t *testing.T) {
+	s := newTestServer()
+	s.tasks["parent"] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1", Status: statusInProgress}
+	s.tasks["child"] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1", Status: statusWaitingInput, Question: &questionRecord{ID: "q-7"}}
+	target := tmuxTarget{SessionID: "$1", WindowID: "@1", PaneID: "%1"}
+	if got := s.questionKeyLocked(target, ""); got != "child" {
+		t.Errorf("any open question = %q, want child", got)
+	}
+	if got := s.questionKeyLocked(target, "q-7"); got != "child" {
+		t.Errorf("by ID = %q, want child", got)
+	}
+	target.TaskID = "parent"
+	if got := s.questionKeyLocked(target, "q-7"); got != "parent" {
+		t.Errorf("explicit task = %q, want parent", got)
+	}
+}
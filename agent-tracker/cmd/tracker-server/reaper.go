@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	defaultReapInterval = 15 * time.Second
+	defaultOrphanGrace  = 10 * time.Minute
+)
+
+// reaperSettings tunes how often tasks are reconciled with tmux and how long
+// a task whose pane is gone stays visible before it is deleted. Both are Go
+// duration strings such as "30s" or "1h".
+type reaperSettings struct {
+	Interval string `json:"interval,omitempty"`
+	Grace    string `json:"grace,omitempty"`
+}
+
+func (r reaperSettings) durations() (time.Duration, time.Duration) {
+	interval, grace := defaultReapInterval, defaultOrphanGrace
+	if d, err := time.ParseDuration(strings.TrimSpace(r.Interval)); err == nil && d > 0 {
+		interval = d
+	} else if strings.TrimSpace(r.Interval) != "" {
+		log.Printf("reaper: ignoring invalid interval %q", r.Interval)
+	}
+	if d, err := time.ParseDuration(strings.TrimSpace(r.Grace)); err == nil && d >= 0 {
+		grace = d
+	} else if strings.TrimSpace(r.Grace) != "" {
+		log.Printf("reaper: ignoring invalid grace %q", r.Grace)
+	}
+	return interval, grace
+}
+
+// reapLoop reconciles tasks with tmux on a timer and whenever a reconcile
+// command (sent from tmux hooks) asks for it.
+func (s *server) reapLoop() {
+	s.mu.Lock()
+	interval, _ := s.reaper.durations()
+	s.mu.Unlock()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.reapNow:
+		}
+		s.reapTasks()
+	}
+}
+
+// requestReap schedules a reconcile without blocking; requests arriving while
+// one is pending collapse into it.
+func (s *server) requestReap() {
+	select {
+	case s.reapNow <- struct{}{}:
+	default:
+	}
+}
+
+func (s *server) reapTasks() {
+	panes, tmuxServer, err := tmuxLivePanes()
+	if errors.Is(err, errTmuxNoServer) {
+		s.mu.Lock()
+		unreconciled := s.unreconciled
+		s.mu.Unlock()
+		if unreconciled {
+			// tmux has not started since the daemon restored its tasks.
+			return
+		}
+		panes, err = map[string]tmuxTarget{}, nil
+	}
+	if err != nil {
+		log.Printf("reaper: %v", err)
+		return
+	}
+	s.mu.Lock()
+	restored := s.reconcileRestoredLocked(panes, tmuxServer)
+	s.mu.Unlock()
+	if s.reapTasksWithPanes(panes, tmuxServer, time.Now()) || restored {
+		s.broadcastState()
+		s.statusRefreshAsync()
+	}
+}
+
+// reapTasksWithPanes marks tasks whose pane left tmux as orphaned, deletes
+// orphans older than the grace period, revives tasks whose pane came back and
+// refreshes renamed sessions and windows. Tasks from before a tmux restart
+// count as gone. It reports whether anything changed.
+func (s *server) reapTasksWithPanes(panes map[string]tmuxTarget, tmuxServer string, now time.Time) bool {
+	windows := paneWindows(panes)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, grace := s.reaper.durations()
+	changed := false
+	for key, task := range s.tasks {
+		pane, alive := task.livePane(panes, windows, tmuxServer)
+		if !alive {
+			switch {
+			case task.OrphanedAt == nil:
+				orphanedAt := now
+				task.OrphanedAt = &orphanedAt
+				log.Printf("reaper: %s orphaned (pane gone)", key)
+			case now.Sub(*task.OrphanedAt) >= grace:
+				delete(s.tasks, key)
+				log.Printf("reaper: deleting %s", key)
+			default:
+				continue
+			}
+			s.taskChangedLocked(key)
+			changed = true
+			continue
+		}
+		before := *task
+		task.OrphanedAt = nil
+		mergeTaskNamesFromTarget(task, normalizeTargetNames(pane))
+		claimed := task.claimTmuxServer(tmuxServer)
+		if claimed || before.OrphanedAt != nil || before.SessionName != task.SessionName || before.WindowName != task.WindowName {
+			s.taskChangedLocked(key)
+			changed = true
+		}
+	}
+	return changed
+}
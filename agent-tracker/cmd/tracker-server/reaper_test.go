@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReaperSettingsDurations(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings reaperSettings
+		interval time.Duration
+		grace    time.Duration
+	}{
+		{name: "defaults", interval: defaultReapInterval, grace: defaultOrphanGrace},
+		{name: "custom", settings: reaperSettings{Interval: "30s", Grace: "1h"}, interval: 30 * time.Second, grace: time.Hour},
+		{name: "zero grace deletes at once", settings: reaperSettings{Grace: "0s"}, interval: defaultReapInterval, grace: 0},
+		{name: "invalid values fall back", settings: reaperSettings{Interval: "soon", Grace: "-5m"}, interval: defaultReapInterval, grace: defaultOrphanGrace},
+		{name: "zero interval falls back", settings: reaperSettings{Interval: "0s"}, interval: defaultReapInterval, grace: defaultOrphanGrace},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, grace := tt.settings.durations()
+			if interval != tt.interval || grace != tt.grace {
+				t.Errorf("durations = %v, %v; want %v, %v", interval, grace, tt.interval, tt.grace)
+			}
+		})
+	}
+}
+
+func TestReapTasksWithPanes(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	longAgo := now.Add(-defaultOrphanGrace - time.Minute)
+	recently := now.Add(-time.Minute)
+	s := newTestServer()
+	s.tasks = map[string]*taskRecord{
+		"alive":          {SessionID: "$1", SessionName: "work", WindowID: "@1", WindowName: "api", Pane: "%1"},
+		"renamed":        {SessionID: "$1", SessionName: "work", WindowID: "@2", WindowName: "old", Pane: "%2"},
+		"window task":    {SessionID: "$1", SessionName: "work", WindowID: "@2", WindowName: "old"},
+		"newly gone":     {SessionID: "$1", WindowID: "@1", Pane: "%7"},
+		"gone a while":   {SessionID: "$1", WindowID: "@1", Pane: "%8", OrphanedAt: &recently},
+		"gone too long":  {SessionID: "$1", WindowID: "@1", Pane: "%9", OrphanedAt: &longAgo},
+		"came back":      {SessionID: "$1", SessionName: "work", WindowID: "@1", WindowName: "api", Pane: "%3", OrphanedAt: &longAgo},
+		"moved sessions": {SessionID: "$2", WindowID: "@1", Pane: "%1"},
+		"old server":     {SessionID: "$1", SessionName: "work", WindowID: "@1", WindowName: "api", Pane: "%1", TmuxServer: "90 1600000000"},
+	}
+	panes := map[string]tmuxTarget{
+		"%1": {SessionID: "$1", SessionName: "work", WindowID: "@1", WindowName: "api", PaneID: "%1"},
+		"%2": {SessionID: "$1", SessionName: "work", WindowID: "@2", WindowName: "web", PaneID: "%2"},
+		"%3": {SessionID: "$1", SessionName: "work", WindowID: "@1", WindowName: "api", PaneID: "%3"},
+	}
+	if !s.reapTasksWithPanes(panes, "100 1700000000", now) {
+		t.Fatal("reapTasksWithPanes reported no change")
+	}
+	tests := []struct {
+		key      string
+		exists   bool
+		orphaned bool
+		window   string
+	}{
+		{key: "alive", exists: true, window: "api"},
+		{key: "renamed", exists: true, window: "web"},
+		{key: "window task", exists: true, window: "web"},
+		{key: "newly gone", exists: true, orphaned: true},
+		{key: "gone a while", exists: true, orphaned: true},
+		{key: "gone too long", exists: false},
+		{key: "came back", exists: true, window: "api"},
+		{key: "moved sessions", exists: true, orphaned: true},
+		{key: "old server", exists: true, orphaned: true},
+	}
+	for _, tt := range tests {
+		task, ok := s.tasks[tt.key]
+		if ok != tt.exists {
+			t.Errorf("%s: exists = %v, want %v", tt.key, ok, tt.exists)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if (task.OrphanedAt != nil) != tt.orphaned {
+			t.Errorf("%s: orphaned = %v, want %v", tt.key, task.OrphanedAt != nil, tt.orphaned)
+		}
+		if tt.window != "" && task.WindowName != tt.window {
+			t.Errorf("%s: window = %q, want %q", tt.key, task.WindowName, tt.window)
+		}
+	}
+	if got := s.tasks["gone a while"].OrphanedAt; !got.Equal(recently) {
+		t.Errorf("orphan time moved to %v", got)
+	}
+	if server := s.tasks["alive"].TmuxServer; server != "100 1700000000" {
+		t.Errorf("alive task's tmux server = %q, want it claimed", server)
+	}
+	if s.reapTasksWithPanes(panes, "100 1700000000", now) {
+		t.Error("a second sweep with the same panes reported changes")
+	}
+}
+
+func TestReapAfterTmuxRestart(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	key := taskKey("$0", "@0", "%0")
+	s := newTestServer()
+	s.tasks[key] = &taskRecord{SessionID: "$0", WindowID: "@0", Pane: "%0", Status: statusInProgress, TmuxServer: "90 1600000000"}
+	panes := map[string]tmuxTarget{"%0": {SessionID: "$0", WindowID: "@0", PaneID: "%0"}}
+
+	s.reapTasksWithPanes(panes, "100 1700000000", now)
+	if s.tasks[key].OrphanedAt == nil {
+		t.Fatal("a task from the previous tmux server was not orphaned")
+	}
+	if _, err := s.startTask(tmuxTarget{SessionID: "$0", WindowID: "@0", PaneID: "%0"}, "new work"); err != nil {
+		t.Fatal(err)
+	}
+	s.reapTasksWithPanes(panes, "100 1700000000", now)
+	task := s.tasks[key]
+	if task.OrphanedAt != nil || task.TmuxServer != "100 1700000000" {
+		t.Errorf("restarted task: orphaned %v, tmux server %q; want it live under the new server", task.OrphanedAt, task.TmuxServer)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const reminderCheckInterval = 30 * time.Second
+
+// notifyStatusReminder is the status rules see on a reminder about a
+// finished task nobody has looked at yet.
+const notifyStatusReminder = "reminder"
+
+var defaultReminderBackoff = []string{"5m", "15m", "1h"}
+
+// reminderSettings lists how long after a task finishes each reminder is
+// sent while it stays unacknowledged. An empty list turns reminders off.
+type reminderSettings struct {
+	Backoff []string `json:"backoff"`
+}
+
+func (r *reminderSettings) delays() []time.Duration {
+	backoff := defaultReminderBackoff
+	if r != nil {
+		backoff = r.Backoff
+	}
+	delays := make([]time.Duration, 0, len(backoff))
+	for _, value := range backoff {
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d <= 0 {
+			log.Printf("reminders: ignoring invalid delay %q", value)
+			continue
+		}
+		delays = append(delays, d)
+	}
+	return delays
+}
+
+type dueReminder struct {
+	target  tmuxTarget
+	summary string
+	waited  time.Duration
+}
+
+func (s *server) reminderLoop() {
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.sendDueReminders(time.Now())
+	}
+}
+
+// sendDueReminders re-notifies about finished tasks that are still
+// unacknowledged and whose pane has not been visited since they finished.
+func (s *server) sendDueReminders(now time.Time) {
+	s.mu.Lock()
+	delays := s.reminderDelays
+	var due []dueReminder
+	for key, t := range s.tasks {
+		if !statusIsFinal(t.Status) || t.Acknowledged || t.CompletedAt == nil || t.RemindersSent >= len(delays) {
+			continue
+		}
+		if t.VisitedAt != nil && !t.VisitedAt.Before(*t.CompletedAt) {
+			continue
+		}
+		waited := now.Sub(*t.CompletedAt)
+		if waited < delays[t.RemindersSent] {
+			continue
+		}
+		t.RemindersSent++
+		s.taskChangedLocked(key)
+		due = append(due, dueReminder{
+			target: tmuxTarget{
+				SessionID:   t.SessionID,
+				SessionName: t.SessionName,
+				WindowID:    t.WindowID,
+				WindowName:  t.WindowName,
+				PaneID:      t.Pane,
+			},
+			summary: firstNonEmpty(t.CompletionNote, t.Summary),
+			waited:  waited,
+		})
+	}
+	enabled := s.notificationsEnabled
+	s.mu.Unlock()
+	if !enabled {
+		return
+	}
+	for _, reminder := range due {
+		message := fmt.Sprintf("Still waiting for review after %s", formatReminderWait(reminder.waited))
+		if summary := firstLine(reminder.summary); summary != "" {
+			message += ": " + summary
+		}
+		if err := s.dispatchNotification(s.notificationFor(reminder.target, notifyStatusReminder, message)); err != nil {
+			log.Printf("reminder error: %v", err)
+		}
+	}
+}
+
+func formatReminderWait(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// markVisited records that someone looked at the pane, which ends
+// reminders for its finished task.
+func (s *server) markVisited(sessionID, windowID, paneID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := taskKey(sessionID, windowID, paneID)
+	t, ok := s.tasks[key]
+	if !ok || !statusIsFinal(t.Status) || t.Acknowledged {
+		return
+	}
+	now := time.Now()
+	t.VisitedAt = &now
+	s.taskChangedLocked(key)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReminderSettingsDelays(t *testing.T) {
+	tests := []struct {
+		settings *reminderSettings
+		want     []time.Duration
+	}{
+		{settings: nil, want: []time.Duration{5 * time.Minute, 15 * time.Minute, time.Hour}},
+		{settings: &reminderSettings{}, want: []time.Duration{}},
+		{settings: &reminderSettings{Backoff: []string{" 10m ", "bad", "0s", "-1m", "2h"}}, want: []time.Duration{10 * time.Minute, 2 * time.Hour}},
+	}
+	for _, tt := range tests {
+		if got := tt.settings.delays(); !slices.Equal(got, tt.want) {
+			t.Errorf("delays(%+v) = %v, want %v", tt.settings, got, tt.want)
+		}
+	}
+}
+
+func TestFormatReminderWait(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		45 * time.Second:               "45s",
+		5*time.Minute + 20*time.Second: "5m",
+		2 * time.Hour:                  "2h",
+		time.Hour + 5*time.Minute:      "1h05m",
+	} {
+		if got := formatReminderWait(d); got != want {
+			t.Errorf("formatReminderWait(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestSendDueRemindersBackoff(t *testing.T) {
+	completed := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	visitedBefore := completed.Add(-time.Minute)
+	visitedAfter := completed.Add(time.Minute)
+	tests := []struct {
+		name  string
+		task  taskRecord
+		after time.Duration
+		want  int
+	}{
+		{name: "too early", task: taskRecord{Status: statusCompleted}, after: 4 * time.Minute, want: 0},
+		{name: "first reminder", task: taskRecord{Status: statusCompleted}, after: 5 * time.Minute, want: 1},
+		{name: "one step per check", task: taskRecord{Status: statusCompleted}, after: 2 * time.Hour, want: 1},
+		{name: "waits for the next delay", task: taskRecord{Status: statusCompleted, RemindersSent: 1}, after: 10 * time.Minute, want: 1},
+		{name: "second reminder", task: taskRecord{Status: statusCompleted, RemindersSent: 1}, after: 15 * time.Minute, want: 2},
+		{name: "backoff exhausted", task: taskRecord{Status: statusCompleted, RemindersSent: 3}, after: 24 * time.Hour, want: 3},
+		{name: "acknowledged", task: taskRecord{Status: statusCompleted, Acknowledged: true}, after: time.Hour, want: 0},
+		{name: "still running", task: taskRecord{Status: statusInProgress}, after: time.Hour, want: 0},
+		{name: "visited after finishing", task: taskRecord{Status: statusCompleted, VisitedAt: &visitedAfter}, after: time.Hour, want: 0},
+		{name: "visited before finishing", task: taskRecord{Status: statusCompleted, VisitedAt: &visitedBefore}, after: time.Hour, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer()
+			task := tt.task
+			task.CompletedAt = &completed
+			s.tasks["a"] = &task
+			s.sendDueReminders(completed.Add(tt.after))
+			if got := s.tasks["a"].RemindersSent; got != tt.want {
+				t.Errorf("reminders sent = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
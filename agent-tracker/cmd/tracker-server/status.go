@@ -0,0 +1,188 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	notifyPolicyAlways    = "always"
+	notifyPolicyUnfocused = "unfocused"
+	notifyPolicyNever     = "never"
+)
+
+// defaultStatusNotifications is the policy for each status a task can enter
+// that may deserve a desktop notification. settings.json can override any of
+// them under "status_notifications".
+var defaultStatusNotifications = map[string]string{
+	statusCompleted:     notifyPolicyAlways,
+	statusWaitingInput:  notifyPolicyAlways,
+	statusBlocked:       notifyPolicyUnfocused,
+	statusFailed:        notifyPolicyAlways,
+	notifyStatusStalled: notifyPolicyAlways,
+}
+
+// statusNeedsAttention reports whether a task in status stays flagged until
+// someone acknowledges it.
+func statusNeedsAttention(status string) bool {
+	switch status {
+	case statusCompleted, statusWaitingInput, statusBlocked, statusFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// statusIsFinal reports whether a task in status has stopped running.
+func statusIsFinal(status string) bool {
+	return status == statusCompleted || status == statusFailed
+}
+
+// setTaskStatus moves a task into waiting_input, blocked or failed, creating
+// it when the pane has no task yet. note is the reason shown to the user.
+// It reports whether the task just entered the status.
+func (s *server) setTaskStatus(target tmuxTarget, status, note string) (bool, error) {
+	if target.SessionID == "" || target.WindowID == "" {
+		return false, invalidTarget("cannot set %s: missing session or window ID", status)
+	}
+	target = normalizeTargetNames(target)
+	active := isActivePane(target.PaneID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setTaskStatusLocked(target, status, note, active, time.Now()), nil
+}
+
+// setTaskStatusLocked is setTaskStatus for callers that hold s.mu and have
+// already checked target. active tells whether the user is looking at the
+// pane; callers ask tmux before taking the lock.
+func (s *server) setTaskStatusLocked(target tmuxTarget, status, note string, active bool, now time.Time) bool {
+	key := s.taskKeyForLocked(target)
+	t, ok := s.tasks[key]
+	if !ok {
+		t = &taskRecord{
+			SessionID: target.SessionID,
+			WindowID:  target.WindowID,
+			Pane:      target.PaneID,
+			Summary:   note,
+			StartedAt: now,
+		}
+		s.tasks[key] = t
+	}
+	mergeTaskNamesFromTarget(t, target)
+	entered := t.Status != status
+	if entered || note != firstNonEmpty(t.StatusNote, t.CompletionNote) {
+		t.recordEvent(status, note, now)
+	}
+	t.Status = status
+	t.OrphanedAt = nil
+	t.PromptDetected = false
+	if status == statusFailed {
+		t.CompletedAt = &now
+		t.StatusNote = ""
+		if note != "" {
+			t.CompletionNote = note
+		}
+	} else {
+		t.CompletedAt = nil
+		t.AcknowledgedAt = nil
+		t.StatusNote = note
+	}
+	if entered {
+		t.Acknowledged = active
+		if t.Acknowledged && status == statusFailed {
+			t.AcknowledgedAt = &now
+		}
+		t.RemindersSent = 0
+		t.VisitedAt = nil
+	}
+	s.taskChangedLocked(key)
+	if entered && status == statusFailed {
+		s.archiveTaskLocked(key)
+	}
+	return entered
+}
+
+// resumeTask returns a waiting or blocked task to in_progress without
+// restarting its clock.
+func (s *server) resumeTask(target tmuxTarget) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.taskKeyForLocked(target)
+	t, ok := s.tasks[key]
+	if !ok {
+		return invalidTarget("no task for pane %s", target.PaneID)
+	}
+	if t.Status != statusWaitingInput && t.Status != statusBlocked {
+		return nil
+	}
+	now := time.Now()
+	t.recordEvent(eventResume, "", now)
+	t.Status = statusInProgress
+	t.StatusNote = ""
+	t.PromptDetected = false
+	t.Acknowledged = true
+	touchActivityLocked(t, now)
+	s.taskChangedLocked(key)
+	return nil
+}
+
+func (s *server) notificationPolicy(status string) string {
+	s.mu.Lock()
+	policy := strings.TrimSpace(s.statusNotifications[status])
+	s.mu.Unlock()
+	switch policy {
+	case notifyPolicyAlways, notifyPolicyUnfocused, notifyPolicyNever:
+		return policy
+	case "":
+	default:
+		log.Printf("unknown notification policy %q for %s", policy, status)
+	}
+	if policy, ok := defaultStatusNotifications[status]; ok {
+		return policy
+	}
+	return notifyPolicyNever
+}
+
+// shouldNotifyStatus applies the global toggle and the status policy to a
+// task that just entered status.
+func (s *server) shouldNotifyStatus(status string, target tmuxTarget) bool {
+	if !s.notificationsAreEnabled() {
+		return false
+	}
+	switch s.notificationPolicy(status) {
+	case notifyPolicyAlways:
+		return true
+	case notifyPolicyUnfocused:
+		return !isActivePane(target.PaneID)
+	default:
+		return false
+	}
+}
+
+func statusNotificationMessage(status, note, summary string) string {
+	detail := firstNonEmpty(note, summary)
+	var prefix string
+	switch status {
+	case statusWaitingInput:
+		prefix = "Waiting for input"
+	case statusBlocked:
+		prefix = "Blocked"
+	case statusFailed:
+		prefix = "Failed"
+	default:
+		return detail
+	}
+	if detail == "" {
+		return prefix
+	}
+	return prefix + ": " + detail
+}
+
+func (s *server) notifyStatus(target tmuxTarget, status, note string) {
+	summary := strings.TrimSpace(s.summaryForTask(target))
+	message := statusNotificationMessage(status, strings.TrimSpace(note), summary)
+	if err := s.dispatchNotification(s.notificationFor(target, status, message)); err != nil {
+		log.Printf("notification error: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatusNotificationMessage(t *testing.T) {
+	tests := []struct {
+		status, note, summary, want string
+	}{
+		{status: statusWaitingInput, note: "Which database?", summary: "Migrate", want: "Waiting for input: Which database?"},
+		{status: statusBlocked, summary: "Deploy", want: "Blocked: Deploy"},
+		{status: statusFailed, want: "Failed"},
+		{status: statusCompleted, note: "Done", summary: "Build", want: "Done"},
+		{status: notifyStatusStalled, summary: "Build", want: "Build"},
+	}
+	for _, tt := range tests {
+		if got := statusNotificationMessage(tt.status, tt.note, tt.summary); got != tt.want {
+			t.Errorf("statusNotificationMessage(%s, %q, %q) = %q, want %q", tt.status, tt.note, tt.summary, got, tt.want)
+		}
+	}
+}
+
+func TestNotificationPolicy(t *testing.T) {
+	s := newTestServer()
+	s.statusNotifications = map[string]string{
+		statusBlocked:      notifyPolicyNever,
+		statusWaitingInput: "sometimes",
+	}
+	tests := []struct {
+		status, want string
+	}{
+		{status: statusBlocked, want: notifyPolicyNever},
+		{status: statusWaitingInput, want: notifyPolicyAlways},
+		{status: statusFailed, want: notifyPolicyAlways},
+		{status: statusInProgress, want: notifyPolicyNever},
+	}
+	for _, tt := range tests {
+		if got := s.notificationPolicy(tt.status); got != tt.want {
+			t.Errorf("notificationPolicy(%s) = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestSetTaskStatusLocked(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	target := tmuxTarget{SessionID: "$1", SessionName: "work", WindowID: "@1", WindowName: "api", PaneID: "%1"}
+	tests := []struct {
+		name         string
+		existing     *taskRecord
+		status       string
+		active       bool
+		entered      bool
+		final        bool
+		acknowledged bool
+	}{
+		{name: "creates a task", status: statusBlocked, entered: true},
+		{name: "waiting while watched", existing: &taskRecord{Status: statusInProgress}, status: statusWaitingInput, active: true, entered: true, acknowledged: true},
+		{name: "failure is final", existing: &taskRecord{Status: statusInProgress}, status: statusFailed, entered: true, final: true},
+		{name: "same status again", existing: &taskRecord{Status: statusBlocked, Acknowledged: true}, status: statusBlocked, active: false, acknowledged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer()
+			key := taskKey(target.SessionID, target.WindowID, target.PaneID)
+			if tt.existing != nil {
+				tt.existing.SessionID, tt.existing.WindowID, tt.existing.Pane = target.SessionID, target.WindowID, target.PaneID
+				tt.existing.StartedAt = now.Add(-time.Hour)
+				s.tasks[key] = tt.existing
+			}
+			entered := s.setTaskStatusLocked(target, tt.status, "why", tt.active, now)
+			task := s.tasks[key]
+			if entered != tt.entered {
+				t.Errorf("entered = %v, want %v", entered, tt.entered)
+			}
+			if task.Status != tt.status {
+				t.Errorf("status = %s, want %s", task.Status, tt.status)
+			}
+			if (task.CompletedAt != nil) != tt.final {
+				t.Errorf("completed = %v, want %v", task.CompletedAt != nil, tt.final)
+			}
+			if task.Acknowledged != tt.acknowledged {
+				t.Errorf("acknowledged = %v, want %v", task.Acknowledged, tt.acknowledged)
+			}
+			note := task.StatusNote
+			if tt.final {
+				note = task.CompletionNote
+			}
+			if note != "why" {
+				t.Errorf("note = %q, want why", note)
+			}
+		})
+	}
+}
+
+func TestStatusPredicates(t *testing.T) {
+	for _, status := range []string{statusCompleted, statusWaitingInput, statusBlocked, statusFailed} {
+		if !statusNeedsAttention(status) {
+			t.Errorf("%s should need attention", status)
+		}
+	}
+	if statusNeedsAttention(statusInProgress) {
+		t.Error("in_progress should not need attention")
+	}
+	for status, final := range map[string]bool{statusCompleted: true, statusFailed: true, statusBlocked: false, statusWaitingInput: false, statusInProgress: false} {
+		if statusIsFinal(status) != final {
+			t.Errorf("statusIsFinal(%s) = %v, want %v", status, !final, final)
+		}
+	}
+}
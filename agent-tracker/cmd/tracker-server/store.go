@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	journalOpPut    = "put"
+	journalOpDelete = "delete"
+
+	journalCompactThreshold = 256
+	journalCompactInterval  = 10 * time.Minute
+)
+
+type journalEntry struct {
+	Op   string      `json:"op"`
+	Key  string      `json:"key"`
+	Task *taskRecord `json:"task,omitempty"`
+}
+
+// taskStore keeps tasks on disk as a snapshot plus an append-only journal of
+// changes made since the snapshot was written.
+type taskStore struct {
+	snapshotPath string
+	journalPath  string
+	journal      *os.File
+	pending      int
+}
+
+func newTaskStore(snapshotPath, journalPath string) *taskStore {
+	return &taskStore{snapshotPath: snapshotPath, journalPath: journalPath}
+}
+
+func (st *taskStore) load() (map[string]*taskRecord, error) {
+	tasks := make(map[string]*taskRecord)
+	data, err := os.ReadFile(st.snapshotPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &tasks); err != nil {
+			return nil, fmt.Errorf("read %s: %w", st.snapshotPath, err)
+		}
+	}
+
+	file, err := os.Open(st.journalPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return tasks, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			// A crash mid-write leaves a torn final line; everything before it is still good.
+			log.Printf("task journal: skipping line %d: %v", line, err)
+			continue
+		}
+		switch entry.Op {
+		case journalOpPut:
+			if entry.Task != nil {
+				tasks[entry.Key] = entry.Task
+			}
+		case journalOpDelete:
+			delete(tasks, entry.Key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (st *taskStore) open() error {
+	if err := os.MkdirAll(filepath.Dir(st.journalPath), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(st.journalPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	st.journal = file
+	return nil
+}
+
+func (st *taskStore) put(key string, task *taskRecord) error {
+	snapshot := *task
+	return st.append(journalEntry{Op: journalOpPut, Key: key, Task: &snapshot})
+}
+
+func (st *taskStore) remove(key string) error {
+	return st.append(journalEntry{Op: journalOpDelete, Key: key})
+}
+
+func (st *taskStore) append(entry journalEntry) error {
+	if st.journal == nil {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := st.journal.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	st.pending++
+	return nil
+}
+
+// compact writes the full task map as the new snapshot and truncates the
+// journal, so replay never has to walk more than one compaction interval.
+func (st *taskStore) compact(tasks map[string]*taskRecord) error {
+	if err := os.MkdirAll(filepath.Dir(st.snapshotPath), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := st.snapshotPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, st.snapshotPath); err != nil {
+		return err
+	}
+	if st.journal != nil {
+		if err := st.journal.Truncate(0); err != nil {
+			return err
+		}
+	} else if err := os.Remove(st.journalPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	st.pending = 0
+	return nil
+}
+
+func (st *taskStore) close() error {
+	if st.journal == nil {
+		return nil
+	}
+	err := st.journal.Close()
+	st.journal = nil
+	return err
+}
+
+func (s *server) restoreTasks() error {
+	if s.store == nil {
+		return nil
+	}
+	tasks, err := s.store.load()
+	if err != nil {
+		return err
+	}
+	unreconciled := false
+	if len(tasks) > 0 {
+		panes, tmuxServer, err := tmuxLivePanes()
+		if err != nil {
+			// At login the daemon can start before tmux does; the reaper
+			// reconciles once tmux answers.
+			log.Printf("task restore: deferring tmux reconciliation: %v", err)
+			unreconciled = true
+		} else {
+			tasks = reconcileTasksWithPanes(tasks, panes, tmuxServer)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks = tasks
+	s.unreconciled = unreconciled
+	if err := s.store.compact(s.tasks); err != nil {
+		return err
+	}
+	return s.store.open()
+}
+
+// reconcileTasksWithPanes drops restored tasks whose pane no longer lives in
+// the same tmux session and window of the same tmux server, and refreshes
+// names for the survivors. Tasks without a pane survive while their window
+// does.
+func reconcileTasksWithPanes(tasks map[string]*taskRecord, panes map[string]tmuxTarget, tmuxServer string) map[string]*taskRecord {
+	windows := paneWindows(panes)
+	live := make(map[string]*taskRecord, len(tasks))
+	for key, task := range tasks {
+		pane, ok := task.livePane(panes, windows, tmuxServer)
+		if !ok {
+			log.Printf("task restore: dropping %s (pane gone)", key)
+			continue
+		}
+		mergeTaskNamesFromTarget(task, normalizeTargetNames(pane))
+		task.claimTmuxServer(tmuxServer)
+		live[key] = task
+	}
+	return live
+}
+
+// livePane returns the pane, or for a task without one the window, that t
+// belongs to. tmux numbers sessions, windows and panes from zero again each
+// time its server starts, so a task recorded under another tmux server has
+// no live pane even where the IDs match.
+func (t *taskRecord) livePane(panes, windows map[string]tmuxTarget, tmuxServer string) (tmuxTarget, bool) {
+	if t.TmuxServer != "" && tmuxServer != "" && t.TmuxServer != tmuxServer {
+		return tmuxTarget{}, false
+	}
+	pane, ok := panes[t.Pane]
+	if t.Pane == "" {
+		pane, ok = windows[t.WindowID]
+	}
+	return pane, ok && pane.SessionID == t.SessionID && pane.WindowID == t.WindowID
+}
+
+// claimTmuxServer records the tmux server a live task was seen in, for tasks
+// that have none yet. It reports whether t changed.
+func (t *taskRecord) claimTmuxServer(tmuxServer string) bool {
+	if t.TmuxServer != "" || tmuxServer == "" {
+		return false
+	}
+	t.TmuxServer = tmuxServer
+	return true
+}
+
+func paneWindows(panes map[string]tmuxTarget) map[string]tmuxTarget {
+	windows := make(map[string]tmuxTarget, len(panes))
+	for _, pane := range panes {
+		windows[pane.WindowID] = pane
+	}
+	return windows
+}
+
+// reconcileRestoredLocked applies the restore-time reconciliation that was
+// deferred because tmux was not running, using the first pane listing since.
+// It reports whether any task changed.
+func (s *server) reconcileRestoredLocked(panes map[string]tmuxTarget, tmuxServer string) bool {
+	if !s.unreconciled {
+		return false
+	}
+	s.unreconciled = false
+	restored := s.tasks
+	s.tasks = reconcileTasksWithPanes(restored, panes, tmuxServer)
+	for key := range restored {
+		s.taskChangedLocked(key)
+	}
+	return len(restored) > 0
+}
+
+func (s *server) persistLocked(key string) {
+	if s.store == nil {
+		return
+	}
+	var err error
+	if task, ok := s.tasks[key]; ok {
+		err = s.store.put(key, task)
+	} else {
+		err = s.store.remove(key)
+	}
+	if err != nil {
+		log.Printf("task journal error: %v", err)
+		return
+	}
+	if s.store.pending >= journalCompactThreshold {
+		if err := s.store.compact(s.tasks); err != nil {
+			log.Printf("task compaction error: %v", err)
+		}
+	}
+}
+
+func (s *server) compactLoop() {
+	ticker := time.NewTicker(journalCompactInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mu.Lock()
+		if s.store != nil && s.store.pending > 0 {
+			if err := s.store.compact(s.tasks); err != nil {
+				log.Printf("task compaction error: %v", err)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *server) closeStore() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store == nil {
+		return
+	}
+	if s.store.pending > 0 {
+		if err := s.store.compact(s.tasks); err != nil {
+			log.Printf("task compaction error: %v", err)
+		}
+	}
+	if err := s.store.close(); err != nil {
+		log.Printf("task journal close error: %v", err)
+	}
+}
+
+// tmuxLivePanes lists every tmux pane by ID, along with the identity of the
+// tmux server they belong to: its PID and start time.
+func tmuxLivePanes() (map[string]tmuxTarget, string, error) {
+	cmd := exec.Command("tmux", "list-panes", "-a", "-F", "#{session_name}:::#{session_id}:::#{window_name}:::#{window_id}:::#{pane_id}:::#{window_index}:::#{pane_index}:::#{pid} #{start_time}")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		trimmed := strings.TrimSpace(string(output))
+		if isTmuxNoServer(trimmed) {
+			return nil, "", errTmuxNoServer
+		}
+		return nil, "", fmt.Errorf("tmux list-panes: %w (%s)", err, trimmed)
+	}
+	panes := make(map[string]tmuxTarget)
+	tmuxServer := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ":::")
+		if len(parts) != 8 {
+			continue
+		}
+		tmuxServer = strings.TrimSpace(parts[7])
+		target := tmuxTarget{
+			SessionName: strings.TrimSpace(parts[0]),
+			SessionID:   strings.TrimSpace(parts[1]),
+			WindowName:  strings.TrimSpace(parts[2]),
+			WindowID:    strings.TrimSpace(parts[3]),
+			PaneID:      strings.TrimSpace(parts[4]),
+			WindowIndex: strings.TrimSpace(parts[5]),
+			PaneIndex:   strings.TrimSpace(parts[6]),
+		}
+		if target.PaneID != "" {
+			panes[target.PaneID] = target
+		}
+	}
+	return panes, tmuxServer, nil
+}
+
+// errTmuxNoServer means tmux answered that no server is running, so no pane
+// exists.
+var errTmuxNoServer = errors.New("no tmux server running")
+
+// isTmuxNoServer reports whether tmux failed because no server is running.
+func isTmuxNoServer(output string) bool {
+	output = strings.ToLower(output)
+	return strings.Contains(output, "no server running") || strings.Contains(output, "error connecting to")
+}
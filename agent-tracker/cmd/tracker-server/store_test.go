@@ -0,0 +1,182 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestTaskStoreLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot string
+		journal  string
+		want     map[string]string
+	}{
+		{
+			name: "nothing on disk",
+			want: map[string]string{},
+		},
+		{
+			name:     "snapshot only",
+			snapshot: `{"a":{"summary":"one"}}`,
+			want:     map[string]string{"a": "one"},
+		},
+		{
+			name:     "journal replays over the snapshot",
+			snapshot: `{"a":{"summary":"one"},"b":{"summary":"two"}}`,
+			journal: `{"op":"put","key":"a","task":{"summary":"changed"}}
+{"op":"delete","key":"b"}
+{"op":"put","key":"c","task":{"summary":"three"}}
+`,
+			want: map[string]string{"a": "changed", "c": "three"},
+		},
+		{
+			name: "torn last line is skipped",
+			journal: `{"op":"put","key":"a","task":{"summary":"one"}}
+{"op":"put","key":"b","tas`,
+			want: map[string]string{"a": "one"},
+		},
+		{
+			name:    "put without a task is ignored",
+			journal: `{"op":"put","key":"a"}` + "\n",
+			want:    map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := tempTaskStore(t)
+			if tt.snapshot != "" {
+				writeTestFile(t, st.snapshotPath, tt.snapshot)
+			}
+			if tt.journal != "" {
+				writeTestFile(t, st.journalPath, tt.journal)
+			}
+			tasks, err := st.load()
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if got := taskSummaries(tasks); !maps.Equal(got, tt.want) {
+				t.Errorf("load = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskStoreCompact(t *testing.T) {
+	st := tempTaskStore(t)
+	if err := st.open(); err != nil {
+		t.Fatal(err)
+	}
+	defer st.close()
+	tasks := map[string]*taskRecord{"a": {Summary: "one"}, "b": {Summary: "two"}}
+	for key, task := range tasks {
+		if err := st.put(key, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	delete(tasks, "b")
+	if err := st.remove("b"); err != nil {
+		t.Fatal(err)
+	}
+	if st.pending != 3 {
+		t.Errorf("pending = %d, want 3", st.pending)
+	}
+	if err := st.compact(tasks); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if st.pending != 0 {
+		t.Errorf("pending after compact = %d, want 0", st.pending)
+	}
+	if info, err := os.Stat(st.journalPath); err != nil {
+		t.Errorf("journal after compact: %v", err)
+	} else if info.Size() != 0 {
+		t.Errorf("journal after compact has %d bytes, want 0", info.Size())
+	}
+	if err := st.put("c", &taskRecord{Summary: "three"}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := st.load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	want := map[string]string{"a": "one", "c": "three"}
+	if got := taskSummaries(loaded); !maps.Equal(got, want) {
+		t.Errorf("load after compact = %v, want %v", got, want)
+	}
+}
+
+func TestReconcileTasksWithPanes(t *testing.T) {
+	panes := map[string]tmuxTarget{
+		"%1": {SessionID: "$1", SessionName: "work", WindowID: "@1", WindowName: "api", PaneID: "%1"},
+		"%2": {SessionID: "$1", SessionName: "work", WindowID: "@2", WindowName: "web", PaneID: "%2"},
+	}
+	tasks := map[string]*taskRecord{
+		"alive":         {SessionID: "$1", WindowID: "@1", Pane: "%1", WindowName: "old"},
+		"pane gone":     {SessionID: "$1", WindowID: "@1", Pane: "%9"},
+		"pane moved":    {SessionID: "$1", WindowID: "@1", Pane: "%2"},
+		"other session": {SessionID: "$2", WindowID: "@1", Pane: "%1"},
+		"window task":   {SessionID: "$1", WindowID: "@2"},
+		"window gone":   {SessionID: "$1", WindowID: "@7"},
+		"same server":   {SessionID: "$1", WindowID: "@2", Pane: "%2", TmuxServer: "100 1700000000"},
+		"old server":    {SessionID: "$1", WindowID: "@1", Pane: "%1", TmuxServer: "90 1600000000"},
+	}
+	live := reconcileTasksWithPanes(tasks, panes, "100 1700000000")
+	got := slices.Sorted(maps.Keys(live))
+	want := []string{"alive", "same server", "window task"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("survivors = %v, want %v", got, want)
+	}
+	if name := live["alive"].WindowName; name != "api" {
+		t.Errorf("window name = %q, want renamed to api", name)
+	}
+	if server := live["alive"].TmuxServer; server != "100 1700000000" {
+		t.Errorf("tmux server = %q, want the survivor claimed by the running server", server)
+	}
+}
+
+func TestReconcileRestoredLocked(t *testing.T) {
+	s := &server{tasks: map[string]*taskRecord{
+		"alive": {SessionID: "$1", WindowID: "@1", Pane: "%1"},
+		"gone":  {SessionID: "$1", WindowID: "@1", Pane: "%2"},
+	}}
+	panes := map[string]tmuxTarget{"%1": {SessionID: "$1", WindowID: "@1", PaneID: "%1"}}
+	if s.reconcileRestoredLocked(panes, "") {
+		t.Fatal("reconciled tasks that were already reconciled at restore")
+	}
+	if len(s.tasks) != 2 {
+		t.Fatalf("tasks = %d, want 2", len(s.tasks))
+	}
+	s.unreconciled = true
+	if !s.reconcileRestoredLocked(panes, "") {
+		t.Fatal("deferred reconciliation reported no change")
+	}
+	if _, ok := s.tasks["gone"]; ok || len(s.tasks) != 1 {
+		t.Errorf("tasks after reconcile = %v, want only alive", s.tasks)
+	}
+	if s.unreconciled {
+		t.Error("still unreconciled after the first pane listing")
+	}
+}
+
+func tempTaskStore(t *testing.T) *taskStore {
+	dir := t.TempDir()
+	return newTaskStore(filepath.Join(dir, "tasks.json"), filepath.Join(dir, "tasks.journal"))
+}
+
+func writeTestFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func taskSummaries(tasks map[string]*taskRecord) map[string]string {
+	summaries := make(map[string]string, len(tasks))
+	for key, task := range tasks {
+		summaries[key] = task.Summary
+	}
+	return summaries
+}
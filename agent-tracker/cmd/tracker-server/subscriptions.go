@@ -0,0 +1,222 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// maxRetainedChanges bounds how far behind a delta subscriber may fall and
+// still resume with Since instead of receiving a fresh snapshot.
+const maxRetainedChanges = 1024
+
+type taskChange struct {
+	seq uint64
+	key string
+}
+
+// taskChangedLocked persists the current state of key and records the change
+// for delta subscribers. Callers hold s.mu.
+func (s *server) taskChangedLocked(key string) {
+	s.persistLocked(key)
+	s.seq++
+	s.changes = append(s.changes, taskChange{seq: s.seq, key: key})
+	if len(s.changes) > maxRetainedChanges {
+		s.changes = append([]taskChange(nil), s.changes[len(s.changes)-maxRetainedChanges/2:]...)
+	}
+}
+
+func (s *server) registerSubscriber(sub *uiSubscriber, env ipc.Envelope) {
+	sub.mu.Lock()
+	if strings.TrimSpace(env.Mode) == ipc.SubscribeModeDelta {
+		sub.mode = ipc.SubscribeModeDelta
+		sub.seq = env.Since
+	}
+	sub.detail = env.Detail
+	sub.filter = normalizeFilter(env.Filter)
+	sub.visible = make(map[string]bool)
+	sub.mu.Unlock()
+	s.addSubscriber(sub)
+}
+
+func normalizeFilter(filter *ipc.Filter) *ipc.Filter {
+	if filter == nil {
+		return nil
+	}
+	normalized := &ipc.Filter{
+		SessionID:          strings.TrimSpace(filter.SessionID),
+		WindowID:           strings.TrimSpace(filter.WindowID),
+		UnacknowledgedOnly: filter.UnacknowledgedOnly,
+	}
+	for _, status := range filter.Statuses {
+		if status = strings.TrimSpace(status); status != "" {
+			normalized.Statuses = append(normalized.Statuses, status)
+		}
+	}
+	if normalized.SessionID == "" && normalized.WindowID == "" && len(normalized.Statuses) == 0 && !normalized.UnacknowledgedOnly {
+		return nil
+	}
+	return normalized
+}
+
+func (t *taskRecord) matches(filter *ipc.Filter) bool {
+	if filter == nil {
+		return true
+	}
+	return filter.Matches(ipc.Task{SessionID: t.SessionID, WindowID: t.WindowID, Status: t.Status, Acknowledged: t.Acknowledged})
+}
+
+// sendFilteredStateTo sends a full-state subscriber with a filter its
+// matching tasks, skipping broadcasts whose changes it would not see.
+func (s *server) sendFilteredStateTo(sub *uiSubscriber, force bool) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if !force {
+		s.mu.Lock()
+		relevant := s.changedForLocked(sub) || sub.mailSeq != s.mailSeq
+		s.mu.Unlock()
+		if !relevant {
+			return nil
+		}
+	}
+	tasks, seq := s.snapshotTasks(sub.filter, sub.detail)
+	messages, mailSeq := s.snapshotMessages(sub.filter)
+	env := &ipc.Envelope{Kind: "state", Message: stateSummary(tasks), Tasks: tasks, Messages: messages}
+	if err := sub.enc.Encode(env); err != nil {
+		return err
+	}
+	sub.seq = seq
+	sub.mailSeq = mailSeq
+	sub.visible = make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		sub.visible[task.ID] = true
+	}
+	return nil
+}
+
+// changedForLocked reports whether any change since the subscriber's last
+// update touched a task it can see now or could see before.
+func (s *server) changedForLocked(sub *uiSubscriber) bool {
+	if !s.changesCoverLocked(sub.seq) {
+		return true
+	}
+	for _, change := range s.changes {
+		if change.seq <= sub.seq {
+			continue
+		}
+		if sub.visible[change.key] {
+			return true
+		}
+		if task, ok := s.tasks[change.key]; ok && task.matches(sub.filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// changesCoverLocked reports whether every change after seq is still
+// retained, so a subscriber at seq can catch up from the change log.
+func (s *server) changesCoverLocked(seq uint64) bool {
+	if seq == 0 || seq > s.seq {
+		return false
+	}
+	if len(s.changes) == 0 {
+		return seq == s.seq
+	}
+	return seq+1 >= s.changes[0].seq
+}
+
+// sendSyncedTo ends a delta subscriber's catch-up on registration with a
+// "synced" event at the seq it reached, so a resume with nothing to catch up
+// on still hears back.
+func (s *server) sendSyncedTo(sub *uiSubscriber) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.enc.Encode(&ipc.Envelope{Kind: "synced", Seq: sub.seq})
+}
+
+// sendDeltaTo brings a delta subscriber up to date: a snapshot when it is
+// new or too far behind, otherwise one upsert or remove per changed task,
+// plus a "messages" event with the unread list when the mailbox changed.
+func (s *server) sendDeltaTo(sub *uiSubscriber) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	s.mu.Lock()
+	if !s.changesCoverLocked(sub.seq) {
+		s.mu.Unlock()
+		tasks, seq := s.snapshotTasks(sub.filter, sub.detail)
+		messages, mailSeq := s.snapshotMessages(sub.filter)
+		env := &ipc.Envelope{Kind: "snapshot", Seq: seq, Message: stateSummary(tasks), Tasks: tasks, Messages: messages}
+		if err := sub.enc.Encode(env); err != nil {
+			return err
+		}
+		sub.seq = seq
+		sub.mailSeq = mailSeq
+		sub.visible = make(map[string]bool, len(tasks))
+		for _, task := range tasks {
+			sub.visible[task.ID] = true
+		}
+		return nil
+	}
+	latest := make(map[string]uint64)
+	for _, change := range s.changes {
+		if change.seq > sub.seq {
+			latest[change.key] = change.seq
+		}
+	}
+	lastSeq := s.seq
+	mailChanged := sub.mailSeq != s.mailSeq
+	type pendingChange struct {
+		key  string
+		seq  uint64
+		task *taskRecord
+	}
+	pending := make([]pendingChange, 0, len(latest))
+	for key, seq := range latest {
+		change := pendingChange{key: key, seq: seq}
+		if task, ok := s.tasks[key]; ok && task.matches(sub.filter) {
+			snapshot := *task
+			change.task = &snapshot
+		} else if !sub.visible[key] {
+			continue
+		}
+		pending = append(pending, change)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].seq < pending[j].seq })
+	now := time.Now()
+	nameCache := make(map[string][2]string)
+	for _, change := range pending {
+		env := &ipc.Envelope{Kind: "task_remove", Seq: change.seq, TaskID: change.key}
+		if change.task != nil {
+			task := s.wireTask(change.key, change.task, now, nameCache, sub.detail)
+			env = &ipc.Envelope{Kind: "task_upsert", Seq: change.seq, Task: &task}
+		}
+		if err := sub.enc.Encode(env); err != nil {
+			return err
+		}
+		sub.seq = change.seq
+		if change.task != nil {
+			sub.visible[change.key] = true
+		} else {
+			delete(sub.visible, change.key)
+		}
+	}
+	// Changes outside the filter still advance the subscriber so it does not
+	// rescan them on the next broadcast.
+	if lastSeq > sub.seq {
+		sub.seq = lastSeq
+	}
+	if mailChanged {
+		messages, mailSeq := s.snapshotMessages(sub.filter)
+		if err := sub.enc.Encode(&ipc.Envelope{Kind: "messages", Messages: messages}); err != nil {
+			return err
+		}
+		sub.mailSeq = mailSeq
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestChangesCoverLocked(t *testing.T) {
+	s := &server{seq: 10, changes: []taskChange{{seq: 8, key: "a"}, {seq: 9, key: "b"}, {seq: 10, key: "a"}}}
+	tests := []struct {
+		seq  uint64
+		want bool
+	}{
+		{seq: 0, want: false},
+		{seq: 6, want: false},
+		{seq: 7, want: true},
+		{seq: 9, want: true},
+		{seq: 10, want: true},
+		{seq: 11, want: false},
+	}
+	for _, tt := range tests {
+		if got := s.changesCoverLocked(tt.seq); got != tt.want {
+			t.Errorf("changesCoverLocked(%d) = %v, want %v", tt.seq, got, tt.want)
+		}
+	}
+	empty := &server{seq: 5}
+	if !empty.changesCoverLocked(5) || empty.changesCoverLocked(4) {
+		t.Error("with no retained changes only the current seq is covered")
+	}
+}
+
+func TestSendDeltaTo(t *testing.T) {
+	s := newTestServer()
+	s.seq = 100
+	putTestTask(s, "a", "first")
+	putTestTask(s, "b", "second")
+
+	var out bytes.Buffer
+	sub := &uiSubscriber{enc: json.NewEncoder(&out), mode: ipc.SubscribeModeDelta, visible: map[string]bool{}}
+	if err := s.sendDeltaTo(sub); err != nil {
+		t.Fatal(err)
+	}
+	events := decodeEvents(t, &out)
+	if len(events) != 1 || events[0].Kind != "snapshot" || len(events[0].Tasks) != 2 || events[0].Seq != s.seq {
+		t.Fatalf("first update = %+v, want a snapshot of 2 tasks at seq %d", events, s.seq)
+	}
+
+	s.mu.Lock()
+	s.tasks["a"].Summary = "first, changed"
+	s.taskChangedLocked("a")
+	delete(s.tasks, "b")
+	s.taskChangedLocked("b")
+	s.mu.Unlock()
+	if err := s.sendDeltaTo(sub); err != nil {
+		t.Fatal(err)
+	}
+	events = decodeEvents(t, &out)
+	if len(events) != 2 {
+		t.Fatalf("delta = %+v, want 2 events", events)
+	}
+	if events[0].Kind != "task_upsert" || events[0].Task == nil || events[0].Task.Summary != "first, changed" {
+		t.Errorf("first delta event = %+v, want the upsert of a", events[0])
+	}
+	if events[1].Kind != "task_remove" || events[1].TaskID != "b" {
+		t.Errorf("second delta event = %+v, want the removal of b", events[1])
+	}
+	if sub.seq != s.seq {
+		t.Errorf("subscriber seq = %d, want %d", sub.seq, s.seq)
+	}
+
+	s.mu.Lock()
+	s.mailSeq++
+	s.mu.Unlock()
+	if err := s.sendDeltaTo(sub); err != nil {
+		t.Fatal(err)
+	}
+	if events = decodeEvents(t, &out); len(events) != 1 || events[0].Kind != "messages" {
+		t.Errorf("mailbox change = %+v, want one messages event", events)
+	}
+
+	sub.seq = 1
+	if err := s.sendDeltaTo(sub); err != nil {
+		t.Fatal(err)
+	}
+	if events = decodeEvents(t, &out); len(events) != 1 || events[0].Kind != "snapshot" {
+		t.Errorf("resume from a pruned seq = %+v, want a snapshot", events)
+	}
+}
+
+func putTestTask(s *server, key, summary string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[key] = &taskRecord{
+		SessionID: "$1", SessionName: "work", WindowID: "@1", WindowName: "api", Pane: "%1",
+		Summary: summary, Status: statusInProgress, StartedAt: time.Now(),
+	}
+	s.taskChangedLocked(key)
+}
+
+func decodeEvents(t *testing.T, r io.Reader) []ipc.Envelope {
+	t.Helper()
+	var events []ipc.Envelope
+	dec := json.NewDecoder(r)
+	for {
+		var env ipc.Envelope
+		if err := dec.Decode(&env); errors.Is(err, io.EOF) {
+			return events
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, env)
+	}
+}
+
+func TestNormalizeFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter *ipc.Filter
+		want   *ipc.Filter
+	}{
+		{name: "nil", filter: nil, want: nil},
+		{name: "blank fields mean no filter", filter: &ipc.Filter{SessionID: " ", Statuses: []string{"", "  "}}, want: nil},
+		{
+			name:   "fields are trimmed",
+			filter: &ipc.Filter{SessionID: " $1 ", WindowID: "@2\n", Statuses: []string{" completed", ""}},
+			want:   &ipc.Filter{SessionID: "$1", WindowID: "@2", Statuses: []string{"completed"}},
+		},
+		{name: "unacknowledged only on its own", filter: &ipc.Filter{UnacknowledgedOnly: true}, want: &ipc.Filter{UnacknowledgedOnly: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeFilter(tt.filter)
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("normalizeFilter = %+v, want %+v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			if got.SessionID != tt.want.SessionID || got.WindowID != tt.want.WindowID || got.UnacknowledgedOnly != tt.want.UnacknowledgedOnly || !slices.Equal(got.Statuses, tt.want.Statuses) {
+				t.Errorf("normalizeFilter = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedForLockedWithFilter(t *testing.T) {
+	s := newTestServer()
+	s.seq = 100
+	putTestTask(s, "a", "first")
+	s.tasks["b"] = &taskRecord{SessionID: "$2", WindowID: "@9", Status: statusInProgress}
+	sub := &uiSubscriber{filter: &ipc.Filter{SessionID: "$1"}, seq: s.seq, visible: map[string]bool{"a": true}}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.taskChangedLocked("b")
+	if s.changedForLocked(sub) {
+		t.Error("a change outside the filter counted as relevant")
+	}
+	s.taskChangedLocked("a")
+	if !s.changedForLocked(sub) {
+		t.Error("a change to a visible task was not relevant")
+	}
+	sub.seq = s.seq
+	s.tasks["a"].SessionID = "$2"
+	s.taskChangedLocked("a")
+	if !s.changedForLocked(sub) {
+		t.Error("a task leaving the filter was not relevant")
+	}
+}
+
+func TestDeltaResumeWithoutChanges(t *testing.T) {
+	s := newTestServer()
+	s.seq = 100
+	putTestTask(s, "a", "first")
+	seq := s.seq
+
+	var out bytes.Buffer
+	sub := &uiSubscriber{enc: json.NewEncoder(&out)}
+	s.registerSubscriber(sub, ipc.Envelope{Kind: "ui-register", Mode: ipc.SubscribeModeDelta, Since: seq})
+	if err := s.sendStateTo(sub); err != nil {
+		t.Fatal(err)
+	}
+	events := decodeEvents(t, &out)
+	if len(events) != 1 || events[0].Kind != "synced" || events[0].Seq != seq {
+		t.Fatalf("resume = %+v, want only a synced event at seq %d", events, seq)
+	}
+
+	out.Reset()
+	fresh := &uiSubscriber{enc: json.NewEncoder(&out)}
+	s.registerSubscriber(fresh, ipc.Envelope{Kind: "ui-register", Mode: ipc.SubscribeModeDelta})
+	if err := s.sendStateTo(fresh); err != nil {
+		t.Fatal(err)
+	}
+	events = decodeEvents(t, &out)
+	if len(events) != 2 || events[0].Kind != "snapshot" || events[1].Kind != "synced" || events[1].Seq != seq {
+		t.Errorf("first registration = %+v, want a snapshot then synced at seq %d", events, seq)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// A pane's root task is keyed by taskKey. Nested agents push sub-tasks on top
+// of it; those are keyed by the root key plus a "#" suffix and point at the
+// task they were pushed on through Parent. Commands that name no task go to
+// the innermost open task of the pane, so a sub-agent reporting through the
+// same pane finishes its own sub-task instead of its parent.
+
+func subtaskKey(rootKey string, now time.Time) string {
+	return fmt.Sprintf("%s#%d", rootKey, now.UnixNano())
+}
+
+func (t *taskRecord) onPane(sessionID, windowID, paneID string) bool {
+	return t.SessionID == sessionID && t.WindowID == windowID && t.Pane == paneID
+}
+
+// taskKeyForLocked returns the key of the task a command for target
+// addresses: target.TaskID when set, otherwise the most recently started open
+// sub-task on the pane, otherwise the pane's root task. Callers hold s.mu.
+func (s *server) taskKeyForLocked(target tmuxTarget) string {
+	if target.TaskID != "" {
+		return target.TaskID
+	}
+	best := ""
+	var bestStarted time.Time
+	for key, t := range s.tasks {
+		if t.Parent == "" || statusIsFinal(t.Status) || !t.onPane(target.SessionID, target.WindowID, target.PaneID) {
+			continue
+		}
+		if best == "" || t.StartedAt.After(bestStarted) {
+			best, bestStarted = key, t.StartedAt
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return taskKey(target.SessionID, target.WindowID, target.PaneID)
+}
+
+// isTopTaskLocked reports whether key is the task its pane's commands go to
+// by default. Only that task can show pane activity or an approval prompt.
+func (s *server) isTopTaskLocked(key string, t *taskRecord) bool {
+	return s.taskKeyForLocked(t.target()) == key
+}
+
+// descendantsLocked returns the keys of every sub-task below key.
+func (s *server) descendantsLocked(key string) []string {
+	var keys []string
+	for childKey, t := range s.tasks {
+		if t.Parent == key {
+			keys = append(keys, childKey)
+			keys = append(keys, s.descendantsLocked(childKey)...)
+		}
+	}
+	return keys
+}
+
+// targetTask points env at the pane of the task it names by TaskID, so
+// commands can address a task by ID alone.
+func (s *server) targetTask(env *ipc.Envelope) error {
+	id := strings.TrimSpace(env.TaskID)
+	if id == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[id]
+	if !ok {
+		return invalidTarget("unknown task %s", id)
+	}
+	env.TaskID = id
+	env.SessionID = t.SessionID
+	env.WindowID = t.WindowID
+	env.Pane = t.Pane
+	return nil
+}
+
+// pushTask starts a sub-task on top of the pane's innermost open task, or on
+// top of target.TaskID, and returns its key. A pane with nothing running gets
+// an ordinary root task instead.
+func (s *server) pushTask(target tmuxTarget, summary string) (string, error) {
+	if target.SessionID == "" || target.WindowID == "" {
+		return "", invalidTarget("cannot create task: missing session or window ID")
+	}
+	target = normalizeTargetNames(target)
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	parentKey := s.taskKeyForLocked(target)
+	parent, ok := s.tasks[parentKey]
+	if !ok || statusIsFinal(parent.Status) {
+		if target.TaskID != "" {
+			return "", invalidArgument("task %s has already finished", target.TaskID)
+		}
+		key := taskKey(target.SessionID, target.WindowID, target.PaneID)
+		s.startTaskLocked(key, target, summary, now)
+		return key, nil
+	}
+	key := subtaskKey(taskKey(parent.SessionID, parent.WindowID, parent.Pane), now)
+	t := &taskRecord{
+		SessionID:    parent.SessionID,
+		SessionName:  parent.SessionName,
+		WindowID:     parent.WindowID,
+		WindowName:   parent.WindowName,
+		Pane:         parent.Pane,
+		Parent:       parentKey,
+		Summary:      summary,
+		StartedAt:    now,
+		Status:       statusInProgress,
+		Acknowledged: true,
+	}
+	t.recordEvent(eventStart, summary, now)
+	mergeTaskNamesFromTarget(t, target)
+	s.tasks[key] = t
+	touchActivityLocked(t, now)
+	s.taskChangedLocked(key)
+	return key, nil
+}
+
+// finishDescendantsLocked completes the open sub-tasks below key, which end
+// with the task they were pushed on.
+func (s *server) finishDescendantsLocked(key string, now time.Time) {
+	for _, childKey := range s.descendantsLocked(key) {
+		t := s.tasks[childKey]
+		if statusIsFinal(t.Status) {
+			continue
+		}
+		t.Status = statusCompleted
+		t.StatusNote = ""
+		t.PromptDetected = false
+		t.Question = nil
+		t.CompletedAt = &now
+		t.Acknowledged = true
+		t.AcknowledgedAt = &now
+		t.recordEvent(eventFinish, "finished with its parent task", now)
+		s.taskChangedLocked(childKey)
+		s.archiveTaskLocked(childKey)
+	}
+}
+
+// dropFinishedSubtasksLocked removes the finished sub-tasks of a root task
+// that starts over; they are already in the history archive.
+func (s *server) dropFinishedSubtasksLocked(rootKey string) {
+	for _, childKey := range s.descendantsLocked(rootKey) {
+		if t, ok := s.tasks[childKey]; ok && statusIsFinal(t.Status) {
+			delete(s.tasks, childKey)
+			s.taskChangedLocked(childKey)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestTaskKeyForLocked(t *testing.T) {
+	root := taskKey("$1", "@1", "%1")
+	started := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestServer()
+	s.tasks[root] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1", Status: statusInProgress, StartedAt: started}
+	target := tmuxTarget{SessionID: "$1", WindowID: "@1", PaneID: "%1"}
+	if got := s.taskKeyForLocked(target); got != root {
+		t.Errorf("no sub-tasks = %q, want the root", got)
+	}
+
+	s.tasks[root+"#1"] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1", Parent: root, Status: statusInProgress, StartedAt: started.Add(time.Minute)}
+	s.tasks[root+"#2"] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1", Parent: root + "#1", Status: statusInProgress, StartedAt: started.Add(2 * time.Minute)}
+	s.tasks[root+"#3"] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1", Parent: root, Status: statusCompleted, StartedAt: started.Add(3 * time.Minute)}
+	s.tasks["other"] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%2", Parent: root, Status: statusInProgress, StartedAt: started.Add(4 * time.Minute)}
+	if got := s.taskKeyForLocked(target); got != root+"#2" {
+		t.Errorf("innermost = %q, want the newest open sub-task", got)
+	}
+	if !s.isTopTaskLocked(root+"#2", s.tasks[root+"#2"]) || s.isTopTaskLocked(root, s.tasks[root]) {
+		t.Error("isTopTaskLocked disagrees with taskKeyForLocked")
+	}
+	target.TaskID = root
+	if got := s.taskKeyForLocked(target); got != root {
+		t.Errorf("explicit task = %q, want the root", got)
+	}
+
+	got := s.descendantsLocked(root)
+	slices.Sort(got)
+	if want := []string{root + "#1", root + "#2", root + "#3", "other"}; !slices.Equal(got, want) {
+		t.Errorf("descendants = %v, want %v", got, want)
+	}
+}
+
+func TestTargetTask(t *testing.T) {
+	s := newTestServer()
+	s.tasks["$1|@1|%1#5"] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1"}
+	env := ipc.Envelope{TaskID: " $1|@1|%1#5 ", Pane: "%9"}
+	if err := s.targetTask(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.TaskID != "$1|@1|%1#5" || env.SessionID != "$1" || env.WindowID != "@1" || env.Pane != "%1" {
+		t.Errorf("envelope = %+v, want pointed at the task's pane", env)
+	}
+	var cmdErr *commandError
+	if err := s.targetTask(&ipc.Envelope{TaskID: "gone"}); !errors.As(err, &cmdErr) || cmdErr.code != ipc.ErrorCodeInvalidTarget {
+		t.Errorf("unknown task = %v, want invalid target", err)
+	}
+	untouched := ipc.Envelope{Pane: "%3"}
+	if err := s.targetTask(&untouched); err != nil || untouched.Pane != "%3" {
+		t.Errorf("no task ID = %+v, %v; want unchanged", untouched, err)
+	}
+}
+
+func TestPushTask(t *testing.T) {
+	s := newTestServer()
+	target := tmuxTarget{SessionID: "$1", SessionName: "work", WindowID: "@1", WindowName: "api", PaneID: "%1"}
+	root, err := s.pushTask(target, "Ship the login page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != taskKey("$1", "@1", "%1") || s.tasks[root].Parent != "" {
+		t.Fatalf("push on an idle pane = %q, want a root task", root)
+	}
+	sub, err := s.pushTask(target, "Write the tests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := s.tasks[sub]; task.Parent != root || task.Status != statusInProgress || task.WindowName != "api" {
+		t.Errorf("sub-task = %+v, want pushed on the root", task)
+	}
+	if s.tasks[root].Status != statusInProgress {
+		t.Errorf("root status = %s, want still in progress", s.tasks[root].Status)
+	}
+
+	s.finishDescendantsLocked(root, time.Now())
+	if task := s.tasks[sub]; task.Status != statusCompleted || !task.Acknowledged {
+		t.Errorf("sub-task after its parent finished = %+v", task)
+	}
+
+	s.tasks[root].Status = statusCompleted
+	var cmdErr *commandError
+	target.TaskID = root
+	if _, err := s.pushTask(target, "Too late"); !errors.As(err, &cmdErr) || cmdErr.code != ipc.ErrorCodeInvalidArgument {
+		t.Errorf("push on a finished task = %v, want invalid argument", err)
+	}
+
+	s.dropFinishedSubtasksLocked(root)
+	if _, ok := s.tasks[sub]; ok {
+		t.Error("finished sub-task kept after the root started over")
+	}
+	if _, err := s.pushTask(tmuxTarget{SessionID: "$1"}, "No window"); !errors.As(err, &cmdErr) || cmdErr.code != ipc.ErrorCodeInvalidTarget {
+		t.Errorf("push without a window = %v, want invalid target", err)
+	}
+}
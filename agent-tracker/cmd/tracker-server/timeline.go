@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+// Timeline event kinds besides the statuses a task can enter.
+const (
+	eventStart   = "start"
+	eventUpdate  = "update"
+	eventNotify  = "notify"
+	eventResume  = "resume"
+	eventAnswer  = "answer"
+	eventStalled = "stalled"
+	eventFinish  = "finish"
+)
+
+// maxTimelineEvents bounds a task's timeline; the oldest events are dropped
+// first. Broadcasts carry only the last broadcastTimelineEvents of it, the
+// whole timeline goes to clients that ask for detail.
+const (
+	maxTimelineEvents       = 100
+	broadcastTimelineEvents = 5
+)
+
+// timelineEvent is one step of the path a task took, kept because updates
+// overwrite the summary.
+type timelineEvent struct {
+	At   time.Time `json:"at"`
+	Kind string    `json:"kind"`
+	Text string    `json:"text,omitempty"`
+}
+
+// recordEvent appends an event to the task's timeline. Callers hold s.mu.
+func (t *taskRecord) recordEvent(kind, text string, now time.Time) {
+	t.Timeline = append(t.Timeline, timelineEvent{At: now, Kind: kind, Text: strings.TrimSpace(text)})
+	if over := len(t.Timeline) - maxTimelineEvents; over > 0 {
+		t.Timeline = t.Timeline[over:]
+	}
+}
+
+// recordEvent adds an event to the timeline of the task target addresses,
+// if there is one.
+func (s *server) recordEvent(target tmuxTarget, kind, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.taskKeyForLocked(target)
+	t, ok := s.tasks[key]
+	if !ok {
+		return
+	}
+	t.recordEvent(kind, text, time.Now())
+	s.taskChangedLocked(key)
+}
+
+// wireTimeline converts the last limit events, or all of them when limit is
+// zero, and reports how many earlier ones were left out.
+func wireTimeline(events []timelineEvent, limit int) ([]ipc.TimelineEvent, int) {
+	omitted := 0
+	if limit > 0 && len(events) > limit {
+		omitted = len(events) - limit
+		events = events[omitted:]
+	}
+	if len(events) == 0 {
+		return nil, omitted
+	}
+	wire := make([]ipc.TimelineEvent, 0, len(events))
+	for _, event := range events {
+		wire = append(wire, ipc.TimelineEvent{At: event.At.Format(time.RFC3339), Kind: event.Kind, Text: event.Text})
+	}
+	return wire, omitted
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestRecordEventKeepsTheLatest(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	task := &taskRecord{}
+	for i := 0; i < maxTimelineEvents; i++ {
+		task.recordEvent(eventUpdate, " "+strconv.Itoa(i)+" ", now)
+	}
+	snapshot := *task
+	for i := maxTimelineEvents; i < maxTimelineEvents+5; i++ {
+		task.recordEvent(eventUpdate, strconv.Itoa(i), now)
+	}
+	if len(task.Timeline) != maxTimelineEvents {
+		t.Fatalf("timeline holds %d events, want %d", len(task.Timeline), maxTimelineEvents)
+	}
+	if first, last := task.Timeline[0].Text, task.Timeline[maxTimelineEvents-1].Text; first != "5" || last != "104" {
+		t.Errorf("timeline runs %q to %q, want 5 to 104", first, last)
+	}
+	if first := snapshot.Timeline[0].Text; first != "0" || len(snapshot.Timeline) != maxTimelineEvents {
+		t.Errorf("earlier snapshot changed: %d events starting %q", len(snapshot.Timeline), first)
+	}
+}
+
+func TestWireTimeline(t *testing.T) {
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	events := []timelineEvent{{At: at, Kind: eventStart, Text: "a"}, {At: at, Kind: eventUpdate, Text: "b"}, {At: at, Kind: eventFinish, Text: "c"}}
+	tests := []struct {
+		limit       int
+		wantTexts   string
+		wantOmitted int
+	}{
+		{limit: 0, wantTexts: "abc"},
+		{limit: 3, wantTexts: "abc"},
+		{limit: 5, wantTexts: "abc"},
+		{limit: 2, wantTexts: "bc", wantOmitted: 1},
+		{limit: 1, wantTexts: "c", wantOmitted: 2},
+	}
+	for _, tt := range tests {
+		wire, omitted := wireTimeline(events, tt.limit)
+		texts := ""
+		for _, event := range wire {
+			texts += event.Text
+		}
+		if texts != tt.wantTexts || omitted != tt.wantOmitted {
+			t.Errorf("wireTimeline(limit %d) = %q with %d omitted, want %q with %d", tt.limit, texts, omitted, tt.wantTexts, tt.wantOmitted)
+		}
+	}
+	if wire, _ := wireTimeline(events, 1); wire[0].Kind != eventFinish || wire[0].At != "2026-03-02T09:00:00Z" {
+		t.Errorf("wire event = %+v", wire[0])
+	}
+	if wire, omitted := wireTimeline(nil, 5); wire != nil || omitted != 0 {
+		t.Errorf("empty timeline = %v, %d", wire, omitted)
+	}
+}
+
+func TestSnapshotTasksTimelineDetail(t *testing.T) {
+	s := newTestServer()
+	putTestTask(s, "a", "work")
+	now := time.Now()
+	s.mu.Lock()
+	for i := 0; i < broadcastTimelineEvents+3; i++ {
+		s.tasks["a"].recordEvent(eventUpdate, strconv.Itoa(i), now)
+	}
+	s.mu.Unlock()
+
+	tasks, _ := s.snapshotTasks(nil, false)
+	if got := tasks[0]; len(got.Timeline) != broadcastTimelineEvents || got.TimelineOmitted != 3 || got.Timeline[0].Text != "3" {
+		t.Errorf("broadcast timeline = %d events from %q, %d omitted; want %d from \"3\", 3 omitted",
+			len(got.Timeline), got.Timeline[0].Text, got.TimelineOmitted, broadcastTimelineEvents)
+	}
+	tasks, _ = s.snapshotTasks(nil, true)
+	if got := tasks[0]; len(got.Timeline) != broadcastTimelineEvents+3 || got.TimelineOmitted != 0 {
+		t.Errorf("detailed timeline = %d events, %d omitted; want %d, none omitted", len(got.Timeline), got.TimelineOmitted, broadcastTimelineEvents+3)
+	}
+}
+
+func TestDetailSubscriberGetsWholeTimelines(t *testing.T) {
+	s := newTestServer()
+	s.seq = 100
+	putTestTask(s, "a", "work")
+	now := time.Now()
+	s.mu.Lock()
+	for i := 0; i < broadcastTimelineEvents*2; i++ {
+		s.tasks["a"].recordEvent(eventUpdate, strconv.Itoa(i), now)
+	}
+	s.mu.Unlock()
+
+	var detailed, plain bytes.Buffer
+	detailSub := &uiSubscriber{enc: json.NewEncoder(&detailed)}
+	s.registerSubscriber(detailSub, ipc.Envelope{Kind: "ui-register", Mode: ipc.SubscribeModeDelta, Detail: true})
+	plainSub := &uiSubscriber{enc: json.NewEncoder(&plain)}
+	s.registerSubscriber(plainSub, ipc.Envelope{Kind: "ui-register", Mode: ipc.SubscribeModeDelta})
+	for _, sub := range []*uiSubscriber{detailSub, plainSub} {
+		if err := s.sendDeltaTo(sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.mu.Lock()
+	s.tasks["a"].recordEvent(eventUpdate, "latest", now)
+	s.taskChangedLocked("a")
+	s.mu.Unlock()
+	s.broadcastState()
+
+	for _, tt := range []struct {
+		name string
+		out  *bytes.Buffer
+		want int
+	}{
+		{name: "detail", out: &detailed, want: broadcastTimelineEvents*2 + 1},
+		{name: "plain", out: &plain, want: broadcastTimelineEvents},
+	} {
+		events := decodeEvents(t, tt.out)
+		if len(events) != 2 || events[1].Kind != "task_upsert" || events[1].Task == nil {
+			t.Fatalf("%s: events = %+v, want a snapshot and an upsert", tt.name, events)
+		}
+		timeline := events[1].Task.Timeline
+		if len(timeline) != tt.want || timeline[len(timeline)-1].Text != "latest" {
+			t.Errorf("%s: upsert timeline has %d events, want %d ending with the latest", tt.name, len(timeline), tt.want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/sha256"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStallAfter    = 10 * time.Minute
+	defaultStallInterval = 30 * time.Second
+
+	// notifyStatusStalled is the status rules and status_notifications see
+	// when a running task stops making progress.
+	notifyStatusStalled = "stalled"
+)
+
+// stallSettings tunes the watchdog: After is how long an in-progress task may
+// go without a heartbeat, update or pane output before it is flagged, and
+// Interval is how often panes are checked. An After of "0s" turns it off.
+type stallSettings struct {
+	After    string `json:"after,omitempty"`
+	Interval string `json:"interval,omitempty"`
+}
+
+func (st *stallSettings) durations() (time.Duration, time.Duration) {
+	after, interval := defaultStallAfter, defaultStallInterval
+	if st == nil {
+		return after, interval
+	}
+	if value := strings.TrimSpace(st.After); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			after = d
+		} else {
+			log.Printf("watchdog: ignoring invalid after %q", st.After)
+		}
+	}
+	if value := strings.TrimSpace(st.Interval); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			interval = d
+		} else {
+			log.Printf("watchdog: ignoring invalid interval %q", st.Interval)
+		}
+	}
+	return after, interval
+}
+
+// touchActivityLocked records progress on t and clears a stall flag.
+// Callers hold s.mu.
+func touchActivityLocked(t *taskRecord, now time.Time) {
+	t.LastActivityAt = &now
+	t.StalledAt = nil
+}
+
+func (t *taskRecord) lastActivity() time.Time {
+	if t.LastActivityAt != nil && t.LastActivityAt.After(t.StartedAt) {
+		return *t.LastActivityAt
+	}
+	return t.StartedAt
+}
+
+func (s *server) heartbeat(target tmuxTarget) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.taskKeyForLocked(target)
+	t, ok := s.tasks[key]
+	if !ok {
+		return invalidTarget("no task for pane %s", target.PaneID)
+	}
+	wasStalled := t.StalledAt != nil
+	touchActivityLocked(t, time.Now())
+	// Heartbeats arrive often; only a cleared stall is worth a broadcast, the
+	// timestamp alone is persisted with the next change.
+	if wasStalled {
+		s.taskChangedLocked(key)
+	}
+	return nil
+}
+
+func (s *server) watchdogLoop() {
+	s.mu.Lock()
+	after, interval := s.stall.durations()
+	s.mu.Unlock()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.checkPanes(after, time.Now())
+	}
+}
+
+type paneAlert struct {
+	target tmuxTarget
+	status string
+	note   string
+}
+
+// checkPanes captures every running pane once per tick. Changed output counts
+// as activity, an approval prompt moves the task to waiting_input until the
+// prompt goes away, and tasks quiet for longer than after are flagged as
+// stalled. An after of zero skips stall detection.
+func (s *server) checkPanes(after time.Duration, now time.Time) {
+	s.mu.Lock()
+	panes := map[string]string{}
+	for key, t := range s.tasks {
+		// A pane is watched on behalf of its innermost open task only.
+		if t.Pane == "" || !s.isTopTaskLocked(key, t) {
+			continue
+		}
+		if t.Status == statusInProgress || (t.Status == statusWaitingInput && t.PromptDetected) {
+			panes[key] = t.Pane
+		}
+	}
+	prompts := s.prompts
+	s.mu.Unlock()
+	if len(panes) == 0 {
+		return
+	}
+
+	commands := prompts.paneAgentCommands()
+	captures := make(map[string]string, len(panes))
+	found := make(map[string]string, len(panes))
+	for key, pane := range panes {
+		output, err := exec.Command("tmux", "capture-pane", "-p", "-t", pane).Output()
+		if err != nil {
+			continue
+		}
+		captures[key] = string(output)
+		if prompt := prompts.match(commands[pane], string(output)); prompt != "" {
+			found[key] = prompt
+		}
+	}
+	// Which panes are being looked at is asked of tmux before taking the
+	// lock, and only when there is a prompt to acknowledge.
+	var active map[string]bool
+	if len(found) > 0 {
+		active = activePanes()
+	}
+
+	var alerts []paneAlert
+	changed := false
+	s.mu.Lock()
+	for key, output := range captures {
+		t, ok := s.tasks[key]
+		if !ok {
+			continue
+		}
+		prompt := found[key]
+		switch {
+		case t.Status == statusInProgress && prompt != "":
+			s.setPromptWaitingLocked(key, t, prompt, active[t.Pane])
+			changed = true
+			alerts = append(alerts, paneAlert{target: t.target(), status: statusWaitingInput, note: t.StatusNote})
+			continue
+		case t.Status == statusWaitingInput && t.PromptDetected && prompt == "":
+			s.clearPromptWaitingLocked(key, t)
+			touchActivityLocked(t, now)
+			changed = true
+			continue
+		case t.Status != statusInProgress:
+			continue
+		}
+		hash := sha256.Sum256([]byte(output))
+		// The first capture after a restart counts as activity too, since
+		// output seen before the restart was not kept.
+		if previous, seen := s.paneHashes[key]; !seen || previous != hash {
+			wasStalled := t.StalledAt != nil
+			touchActivityLocked(t, now)
+			if wasStalled {
+				s.taskChangedLocked(key)
+				changed = true
+			}
+		}
+		s.paneHashes[key] = hash
+	}
+	for key := range s.paneHashes {
+		if t, ok := s.tasks[key]; !ok || t.Status != statusInProgress {
+			delete(s.paneHashes, key)
+		}
+	}
+	for key, t := range s.tasks {
+		if after == 0 || t.Status != statusInProgress || t.StalledAt != nil || t.OrphanedAt != nil {
+			continue
+		}
+		// A parent waits quietly while its sub-task works.
+		if !s.isTopTaskLocked(key, t) {
+			continue
+		}
+		if now.Sub(t.lastActivity()) < after {
+			continue
+		}
+		message := "No progress for " + formatReminderWait(after)
+		if summary := firstLine(t.Summary); summary != "" {
+			message += ": " + summary
+		}
+		stalledAt := now
+		t.StalledAt = &stalledAt
+		t.recordEvent(eventStalled, message, now)
+		s.taskChangedLocked(key)
+		changed = true
+		alerts = append(alerts, paneAlert{target: t.target(), status: notifyStatusStalled, note: message})
+		log.Printf("watchdog: %s stalled", key)
+	}
+	s.mu.Unlock()
+
+	if changed {
+		s.broadcastState()
+		s.statusRefreshAsync()
+	}
+	for _, alert := range alerts {
+		if !s.shouldNotifyStatus(alert.status, alert.target) {
+			continue
+		}
+		if alert.status == notifyStatusStalled {
+			if err := s.dispatchNotification(s.notificationFor(alert.target, alert.status, alert.note)); err != nil {
+				log.Printf("notification error: %v", err)
+			}
+			continue
+		}
+		s.notifyStatus(alert.target, alert.status, alert.note)
+	}
+}
+
+func (t *taskRecord) target() tmuxTarget {
+	return tmuxTarget{SessionID: t.SessionID, SessionName: t.SessionName, WindowID: t.WindowID, WindowName: t.WindowName, PaneID: t.Pane}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/david/agent-tracker/internal/ipc"
+)
+
+func TestStallSettingsDurations(t *testing.T) {
+	tests := []struct {
+		settings     *stallSettings
+		after, every time.Duration
+	}{
+		{settings: nil, after: defaultStallAfter, every: defaultStallInterval},
+		{settings: &stallSettings{}, after: defaultStallAfter, every: defaultStallInterval},
+		{settings: &stallSettings{After: "20m", Interval: "1m"}, after: 20 * time.Minute, every: time.Minute},
+		{settings: &stallSettings{After: "0s"}, after: 0, every: defaultStallInterval},
+		{settings: &stallSettings{After: "-1m", Interval: "0s"}, after: defaultStallAfter, every: defaultStallInterval},
+		{settings: &stallSettings{After: "later", Interval: "often"}, after: defaultStallAfter, every: defaultStallInterval},
+	}
+	for _, tt := range tests {
+		after, every := tt.settings.durations()
+		if after != tt.after || every != tt.every {
+			t.Errorf("durations(%+v) = %v, %v, want %v, %v", tt.settings, after, every, tt.after, tt.every)
+		}
+	}
+}
+
+func TestTaskLastActivity(t *testing.T) {
+	started := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	earlier := started.Add(-time.Minute)
+	later := started.Add(time.Minute)
+	tests := []struct {
+		name     string
+		activity *time.Time
+		want     time.Time
+	}{
+		{name: "never active", want: started},
+		{name: "active before a restart of the task", activity: &earlier, want: started},
+		{name: "active since starting", activity: &later, want: later},
+	}
+	for _, tt := range tests {
+		task := &taskRecord{StartedAt: started, LastActivityAt: tt.activity}
+		if got := task.lastActivity(); !got.Equal(tt.want) {
+			t.Errorf("%s: lastActivity = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	s := newTestServer()
+	target := tmuxTarget{SessionID: "$1", WindowID: "@1", PaneID: "%1"}
+	var cmdErr *commandError
+	if err := s.heartbeat(target); !errors.As(err, &cmdErr) || cmdErr.code != ipc.ErrorCodeInvalidTarget {
+		t.Fatalf("heartbeat without a task = %v, want invalid target", err)
+	}
+
+	key := taskKey("$1", "@1", "%1")
+	stalled := time.Now().Add(-time.Minute)
+	s.tasks[key] = &taskRecord{SessionID: "$1", WindowID: "@1", Pane: "%1", Status: statusInProgress, StalledAt: &stalled}
+	seq := s.seq
+	if err := s.heartbeat(target); err != nil {
+		t.Fatal(err)
+	}
+	if task := s.tasks[key]; task.StalledAt != nil || task.LastActivityAt == nil {
+		t.Errorf("task after heartbeat = %+v, want activity recorded and stall cleared", task)
+	}
+	if s.seq == seq {
+		t.Error("clearing a stall did not record a change")
+	}
+
+	seq = s.seq
+	if err := s.heartbeat(target); err != nil {
+		t.Fatal(err)
+	}
+	if s.seq != seq {
+		t.Error("a plain heartbeat recorded a change")
+	}
+}
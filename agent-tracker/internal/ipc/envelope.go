@@ -1,30 +1,232 @@
 package ipc
 
+import "time"
+
+const (
+	ErrorCodeInvalidArgument = "invalid_argument"
+	ErrorCodeInvalidTarget   = "invalid_target"
+	ErrorCodeUnknownCommand  = "unknown_command"
+	ErrorCodeUnauthorized    = "unauthorized"
+	ErrorCodeInternal        = "internal"
+)
+
 type Envelope struct {
-	Kind      string `json:"kind"`
-	Command   string `json:"command,omitempty"`
-	Client    string `json:"client,omitempty"`
-	Session   string `json:"session,omitempty"`
-	SessionID string `json:"session_id,omitempty"`
-	Window    string `json:"window,omitempty"`
-	WindowID  string `json:"window_id,omitempty"`
-	Pane      string `json:"pane,omitempty"`
-	Message   string `json:"message,omitempty"`
-	Summary   string `json:"summary,omitempty"`
-	Tasks     []Task `json:"tasks,omitempty"`
+	Kind      string    `json:"kind"`
+	ID        string    `json:"id,omitempty"`
+	Command   string    `json:"command,omitempty"`
+	Client    string    `json:"client,omitempty"`
+	Session   string    `json:"session,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	Window    string    `json:"window,omitempty"`
+	WindowID  string    `json:"window_id,omitempty"`
+	Pane      string    `json:"pane,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Summary   string    `json:"summary,omitempty"`
+	Tasks     []Task    `json:"tasks,omitempty"`
+	Error     *Error    `json:"error,omitempty"`
+	Mode      string    `json:"mode,omitempty"`
+	Seq       uint64    `json:"seq,omitempty"`
+	Since     uint64    `json:"since,omitempty"`
+	Task      *Task     `json:"task,omitempty"`
+	TaskID    string    `json:"task_id,omitempty"`
+	Filter    *Filter   `json:"filter,omitempty"`
+	Question  *Question `json:"question,omitempty"`
+	Mail      *Message  `json:"mail,omitempty"`
+	Messages  []Message `json:"messages,omitempty"`
+	Progress  *Progress `json:"progress,omitempty"`
+	Checklist []Step    `json:"checklist,omitempty"`
+	// Detail asks a ui-register for every task's whole timeline instead of
+	// only its latest events.
+	Detail bool `json:"detail,omitempty"`
+	// PeerPID is the sending process as tracker-server learned it from the
+	// socket's peer credentials. It never travels on the wire, so clients
+	// cannot claim someone else's.
+	PeerPID int `json:"-"`
+}
+
+// Filter narrows a ui-register subscription. Empty fields match everything;
+// the subscriber only hears about tasks matching every set field.
+type Filter struct {
+	SessionID          string   `json:"session_id,omitempty"`
+	WindowID           string   `json:"window_id,omitempty"`
+	Statuses           []string `json:"statuses,omitempty"`
+	UnacknowledgedOnly bool     `json:"unacknowledged_only,omitempty"`
 }
 
+func (f *Filter) Matches(t Task) bool {
+	if f == nil {
+		return true
+	}
+	if f.SessionID != "" && f.SessionID != t.SessionID {
+		return false
+	}
+	if f.WindowID != "" && f.WindowID != t.WindowID {
+		return false
+	}
+	if f.UnacknowledgedOnly && t.Acknowledged {
+		return false
+	}
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, status := range f.Statuses {
+		if status == t.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscribeModeDelta, passed as Mode on ui-register, asks for a "snapshot"
+// envelope followed by "task_upsert" and "task_remove" events instead of a
+// full "state" envelope on every change. Since resumes from the last Seq seen.
+// Each registration's catch-up ends with a "synced" envelope carrying the Seq
+// it reached, even when there was nothing to catch up on.
+const SubscribeModeDelta = "delta"
+
+// Error is carried by replies of kind "error" when the server rejects a
+// command.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Task is one tracked unit of work. A pane holds a root task and, while
+// nested agents run inside it, sub-tasks whose ParentID points at the task
+// they were pushed on top of. Timeline holds only the latest events unless
+// the state was asked for in detail; TimelineOmitted counts the ones left
+// out.
 type Task struct {
-	SessionID       string  `json:"session_id"`
-	Session         string  `json:"session"`
-	WindowID        string  `json:"window_id"`
-	Window          string  `json:"window"`
-	Pane            string  `json:"pane,omitempty"`
-	Status          string  `json:"status"`
-	Summary         string  `json:"summary"`
-	CompletionNote  string  `json:"completion_note,omitempty"`
-	StartedAt       string  `json:"started_at,omitempty"`
-	CompletedAt     string  `json:"completed_at,omitempty"`
-	DurationSeconds float64 `json:"duration_seconds"`
-	Acknowledged    bool    `json:"acknowledged"`
+	ID              string          `json:"id,omitempty"`
+	ParentID        string          `json:"parent_id,omitempty"`
+	SessionID       string          `json:"session_id"`
+	Session         string          `json:"session"`
+	WindowID        string          `json:"window_id"`
+	Window          string          `json:"window"`
+	Pane            string          `json:"pane,omitempty"`
+	Status          string          `json:"status"`
+	Summary         string          `json:"summary"`
+	CompletionNote  string          `json:"completion_note,omitempty"`
+	StatusNote      string          `json:"status_note,omitempty"`
+	StartedAt       string          `json:"started_at,omitempty"`
+	CompletedAt     string          `json:"completed_at,omitempty"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	Acknowledged    bool            `json:"acknowledged"`
+	Orphaned        bool            `json:"orphaned,omitempty"`
+	OrphanedAt      string          `json:"orphaned_at,omitempty"`
+	Stalled         bool            `json:"stalled,omitempty"`
+	LastActivityAt  string          `json:"last_activity_at,omitempty"`
+	Question        *Question       `json:"question,omitempty"`
+	Progress        *Progress       `json:"progress,omitempty"`
+	Checklist       []Step          `json:"checklist,omitempty"`
+	Timeline        []TimelineEvent `json:"timeline,omitempty"`
+	TimelineOmitted int             `json:"timeline_omitted,omitempty"`
+	PID             int             `json:"pid,omitempty"`
+}
+
+// TimelineEvent is one entry of a task's timeline: Kind is what happened
+// ("start", "update", "notify", a status such as "waiting_input", "resume",
+// "answer" or "finish") and Text the summary, note or message that came with
+// it.
+type TimelineEvent struct {
+	At   string `json:"at"`
+	Kind string `json:"kind"`
+	Text string `json:"text,omitempty"`
+}
+
+// WaitingSeconds adds up how long a timeline spent waiting for input or
+// blocked, counting a wait that is still open up to end.
+func WaitingSeconds(events []TimelineEvent, end time.Time) float64 {
+	var total time.Duration
+	var since time.Time
+	for _, event := range events {
+		at, err := time.Parse(time.RFC3339, event.At)
+		if err != nil {
+			continue
+		}
+		switch event.Kind {
+		case "waiting_input", "blocked":
+			if since.IsZero() {
+				since = at
+			}
+		case "update", "notify", "stalled":
+		default:
+			if !since.IsZero() {
+				total += at.Sub(since)
+				since = time.Time{}
+			}
+		}
+	}
+	if !since.IsZero() && end.After(since) {
+		total += end.Sub(since)
+	}
+	return total.Seconds()
+}
+
+// Progress is how far a task has come: Done of Total steps, or Percent when
+// the work is not counted in steps. Label names what is counted, as in
+// "4/7 migrations".
+type Progress struct {
+	Done    int     `json:"done,omitempty"`
+	Total   int     `json:"total,omitempty"`
+	Percent float64 `json:"percent,omitempty"`
+	Label   string  `json:"label,omitempty"`
+}
+
+// Step is one named item of a task's checklist.
+type Step struct {
+	Name string `json:"name"`
+	Done bool   `json:"done,omitempty"`
+}
+
+// Question is something an agent asked the human through ask_user. Options,
+// when present, are the suggested answers; an answer of "2" picks the second.
+type Question struct {
+	ID         string   `json:"id,omitempty"`
+	Text       string   `json:"text,omitempty"`
+	Options    []string `json:"options,omitempty"`
+	AskedAt    string   `json:"asked_at,omitempty"`
+	Answer     string   `json:"answer,omitempty"`
+	AnsweredAt string   `json:"answered_at,omitempty"`
+}
+
+// Message is a note left in an agent's mailbox. To is the recipient's agent
+// ID, which is also its tmux window name; WindowID is filled in once that
+// window is found. State envelopes carry unread messages only.
+type Message struct {
+	ID       string `json:"id,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	WindowID string `json:"window_id,omitempty"`
+	Text     string `json:"text,omitempty"`
+	PostedAt string `json:"posted_at,omitempty"`
+	ReadAt   string `json:"read_at,omitempty"`
+}
+
+// HistoryEntry is one finished task run as archived by tracker-server. The
+// archive is append-only; a later entry with the same ID supersedes an earlier
+// one, as when the run is acknowledged. WaitingSeconds is the part of the run
+// spent waiting for input or blocked.
+type HistoryEntry struct {
+	ID              string     `json:"id"`
+	SessionID       string     `json:"session_id"`
+	Session         string     `json:"session"`
+	WindowID        string     `json:"window_id"`
+	Window          string     `json:"window"`
+	Pane            string     `json:"pane,omitempty"`
+	Status          string     `json:"status,omitempty"`
+	Summary         string     `json:"summary"`
+	CompletionNote  string     `json:"completion_note,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	CompletedAt     time.Time  `json:"completed_at"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	WaitingSeconds  float64    `json:"waiting_seconds,omitempty"`
+	AcknowledgedAt  *time.Time `json:"acknowledged_at,omitempty"`
 }
@@ -0,0 +1,100 @@
+package ipc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilterMatches(t *testing.T) {
+	task := Task{SessionID: "$1", WindowID: "@2", Status: "completed", Acknowledged: false}
+	tests := []struct {
+		name   string
+		filter *Filter
+		want   bool
+	}{
+		{name: "nil filter", filter: nil, want: true},
+		{name: "empty filter", filter: &Filter{}, want: true},
+		{name: "same session", filter: &Filter{SessionID: "$1"}, want: true},
+		{name: "other session", filter: &Filter{SessionID: "$2"}, want: false},
+		{name: "same window", filter: &Filter{SessionID: "$1", WindowID: "@2"}, want: true},
+		{name: "other window", filter: &Filter{WindowID: "@3"}, want: false},
+		{name: "status listed", filter: &Filter{Statuses: []string{"in_progress", "completed"}}, want: true},
+		{name: "status not listed", filter: &Filter{Statuses: []string{"in_progress"}}, want: false},
+		{name: "unacknowledged only", filter: &Filter{UnacknowledgedOnly: true}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(task); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	task.Acknowledged = true
+	if (&Filter{UnacknowledgedOnly: true}).Matches(task) {
+		t.Error("unacknowledged-only filter matched an acknowledged task")
+	}
+}
+
+func TestWaitingSeconds(t *testing.T) {
+	at := func(minute int) string {
+		return time.Date(2026, 3, 2, 10, minute, 0, 0, time.UTC).Format(time.RFC3339)
+	}
+	end := time.Date(2026, 3, 2, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		events []TimelineEvent
+		end    time.Time
+		want   float64
+	}{
+		{name: "no events", end: end, want: 0},
+		{
+			name:   "no waits",
+			events: []TimelineEvent{{At: at(0), Kind: "start"}, {At: at(5), Kind: "update"}, {At: at(9), Kind: "finish"}},
+			end:    end,
+			want:   0,
+		},
+		{
+			name:   "updates do not end a wait",
+			events: []TimelineEvent{{At: at(0), Kind: "waiting_input"}, {At: at(1), Kind: "update"}, {At: at(2), Kind: "notify"}, {At: at(5), Kind: "resume"}},
+			end:    end,
+			want:   300,
+		},
+		{
+			name:   "a block then a question is one wait",
+			events: []TimelineEvent{{At: at(0), Kind: "blocked"}, {At: at(2), Kind: "waiting_input"}, {At: at(10), Kind: "answer"}},
+			end:    end,
+			want:   600,
+		},
+		{
+			name:   "separate waits add up",
+			events: []TimelineEvent{{At: at(0), Kind: "blocked"}, {At: at(1), Kind: "resume"}, {At: at(4), Kind: "waiting_input"}, {At: at(6), Kind: "finish"}},
+			end:    end,
+			want:   180,
+		},
+		{
+			name:   "an open wait counts up to end",
+			events: []TimelineEvent{{At: at(0), Kind: "start"}, {At: at(20), Kind: "waiting_input"}},
+			end:    end,
+			want:   600,
+		},
+		{
+			name:   "an open wait after end counts nothing",
+			events: []TimelineEvent{{At: at(20), Kind: "waiting_input"}},
+			end:    end.Add(-time.Hour),
+			want:   0,
+		},
+		{
+			name:   "unparsable times are skipped",
+			events: []TimelineEvent{{At: at(0), Kind: "waiting_input"}, {At: "soon", Kind: "resume"}, {At: at(3), Kind: "resume"}},
+			end:    end,
+			want:   180,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WaitingSeconds(tt.events, tt.end); got != tt.want {
+				t.Errorf("WaitingSeconds = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,7 @@
 import { appendFileSync, mkdirSync, readFileSync, renameSync, writeFileSync } from "fs";
 
-const NOTIFY_BIN = "/usr/bin/python3";
-const NOTIFY_SCRIPT = "/Users/david/.config/codex/notify.py";
 const MAX_SUMMARY_CHARS = 600;
-const TRACKER_BIN = "/Users/david/.config/agent-tracker/bin/tracker-client";
+const AGENT_BIN = "/Users/david/.config/agent-tracker/bin/agent";
 const LOG_FILE = "/tmp/tracker-notify-debug.log";
 const STATE_ROOT = process.env.XDG_STATE_HOME || `${process.env.HOME || ""}/.local/state`;
 const OP_STATE_DIR = `${STATE_ROOT}/op`;
@@ -165,16 +163,18 @@ export const TrackerNotifyPlugin = async ({ client, directory, $ }) => {
 	}
 
 	const trackerReady = async () => {
-		const check = await $`test -x ${TRACKER_BIN}`.nothrow();
+		const check = await $`test -x ${AGENT_BIN}`.nothrow();
 		return check?.exitCode === 0;
 	};
 
-	const buildTrackerArgs = () => {
-		const args = [];
-		if (tmuxContext?.sessionId) args.push("-session-id", tmuxContext.sessionId);
-		if (tmuxContext?.windowId) args.push("-window-id", tmuxContext.windowId);
-		if (tmuxContext?.paneId) args.push("-pane", tmuxContext.paneId);
-		return args;
+	// `agent hook opencode` maps the event to tracker commands for this pane.
+	const forwardEvent = async (type, properties = {}, text = "") => {
+		if (!(await trackerReady())) return;
+		const payload = JSON.stringify({ type, properties, text });
+		const result = await $`${AGENT_BIN} hook opencode < ${new Response(payload)}`.nothrow().quiet();
+		if (result?.exitCode !== 0) {
+			log("agent hook failed", { type, stderr: result?.stderr?.toString() });
+		}
 	};
 
 	const summarizeText = (parts = []) => {
@@ -186,63 +186,18 @@ export const TrackerNotifyPlugin = async ({ client, directory, $ }) => {
 		return text.slice(0, MAX_SUMMARY_CHARS);
 	};
 
-	const collectUserInputs = (messages) => {
-		return messages
-			.filter((m) => m?.info?.role === "user")
-			.slice(-3)
-			.map((m) => summarizeText(m.parts))
-			.filter((text) => text);
-	};
-
 	const startTask = async (summary, sessionID) => {
 		if (!summary) return;
-		if (!(await trackerReady())) return;
 		taskActive = true;
 		currentSessionID = sessionID;
-		const args = buildTrackerArgs();
-		await $`${TRACKER_BIN} command ${args} -summary ${summary} start_task`.nothrow();
+		await forwardEvent("session.status", { status: { type: "busy" } }, summary);
 	};
 
 	const finishTask = async (summary) => {
 		if (!taskActive) return;
-		if (!(await trackerReady())) return;
 		taskActive = false;
 		currentSessionID = null;
-		const args = buildTrackerArgs();
-		await $`${TRACKER_BIN} command ${args} -summary ${summary || "done"} finish_task`.nothrow();
-	};
-
-	const notify = async (sessionID) => {
-		try {
-			const messages =
-				(await client.session.messages({
-					path: { id: sessionID },
-					query: { directory },
-				})) || [];
-
-			const assistant = [...messages]
-				.reverse()
-				.find((m) => m?.info?.role === "assistant");
-			if (!assistant) return;
-
-			const assistantText = summarizeText(assistant.parts);
-			if (!assistantText) return;
-
-			const payload = {
-				type: "agent-turn-complete",
-				"last-assistant-message": assistantText,
-				input_messages: collectUserInputs(messages),
-			};
-
-			const serialized = JSON.stringify(payload);
-			try {
-				await $`${NOTIFY_BIN} ${NOTIFY_SCRIPT} ${serialized}`;
-			} catch {
-				// ignore notification failures
-			}
-		} catch {
-			// Ignore notification failures
-		}
+		await forwardEvent("session.status", { status: { type: "idle" } }, summary || "done");
 	};
 
 	const getLastMessageText = async (sessionID, role, retries = 3) => {
@@ -290,12 +245,23 @@ export const TrackerNotifyPlugin = async ({ client, directory, $ }) => {
 		event: async ({ event }) => {
 			if (event?.type === "question.asked") {
 				await applyQuestionPending(true);
+				if (taskActive) await forwardEvent(event.type);
 				return;
 			}
 
 			if (event?.type === "question.replied" || event?.type === "question.rejected") {
 				const sessionID = event?.properties?.sessionID || rootSessionID;
 				await syncPendingQuestionState(sessionID);
+				if (taskActive) await forwardEvent(event.type);
+				return;
+			}
+
+			if (
+				event?.type === "permission.asked" ||
+				event?.type === "permission.updated" ||
+				event?.type === "permission.replied"
+			) {
+				if (taskActive) await forwardEvent(event.type, { title: event?.properties?.title || "" });
 				return;
 			}
 			
@@ -359,7 +325,6 @@ export const TrackerNotifyPlugin = async ({ client, directory, $ }) => {
 				if (currentSessionID && sessionID !== currentSessionID) return;
 				const text = await getLastMessageText(sessionID, "assistant");
 				await finishTask(text || "done");
-				await notify(sessionID);
 			}
 		},
 	};
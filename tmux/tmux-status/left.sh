@@ -87,14 +87,16 @@ get_session_icon() {
     local result
     result=$(echo "$tracker_state" | jq -r --arg sid "$sid" '
       .tasks // [] | .[] | select(.session_id == $sid) |
-      if .status == "completed" and .acknowledged != true then "waiting"
+      if .status == "waiting_input" or .status == "blocked" then "question"
+      elif .status == "failed" and .acknowledged != true then "failed"
+      elif .status == "completed" and .acknowledged != true then "waiting"
       elif .status == "in_progress" then "in_progress"
       else empty end
-    ' 2>/dev/null | head -1 || true)
-    case "$result" in
-      waiting) has_bell=1 ;;
-      in_progress) has_watch=1 ;;
-    esac
+    ' 2>/dev/null || true)
+    grep -qx question <<< "$result" && has_question=1
+    grep -qx failed <<< "$result" && has_fail=1
+    grep -qx waiting <<< "$result" && has_bell=1
+    grep -qx in_progress <<< "$result" && has_watch=1
   fi
 
   if (( has_question )); then
@@ -7,18 +7,25 @@ session_id="$1"
 agent_bin="$HOME/.config/agent-tracker/bin/agent"
 [[ ! -x "$agent_bin" ]] && exit 0
 
-state=$("$agent_bin" tracker state 2>/dev/null || true)
+state=$("$agent_bin" tracker state --session-id "$session_id" 2>/dev/null || true)
 [[ -z "$state" ]] && exit 0
 
-# Check for in_progress or unacknowledged completed tasks in this session
+# Show the most urgent tracker status in this session
 result=$(echo "$state" | jq -r --arg sid "$session_id" '
   .tasks // [] | .[] | select(.session_id == $sid) |
-  if .status == "in_progress" then "in_progress"
+  if .status == "waiting_input" or .status == "blocked" then "question"
+  elif .status == "failed" and .acknowledged != true then "failed"
   elif .status == "completed" and .acknowledged != true then "waiting"
+  elif .status == "in_progress" then "in_progress"
   else empty end
-' 2>/dev/null | head -1 || true)
+' 2>/dev/null || true)
 
-case "$result" in
-  in_progress) printf '⏳' ;;
-  waiting) printf '🔔' ;;
-esac
+if grep -qx question <<< "$result"; then
+  printf '❓'
+elif grep -qx failed <<< "$result"; then
+  printf '❌'
+elif grep -qx waiting <<< "$result"; then
+  printf '🔔'
+elif grep -qx in_progress <<< "$result"; then
+  printf '⏳'
+fi
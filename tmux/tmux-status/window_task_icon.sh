@@ -26,14 +26,16 @@ if [[ -f "$CACHE_FILE" ]]; then
   if [[ -n "$state" ]]; then
     result=$(echo "$state" | jq -r --arg wid "$window_id" '
       .tasks // [] | .[] | select(.window_id == $wid) |
-      if .status == "completed" and .acknowledged != true then "waiting"
+      if .status == "waiting_input" or .status == "blocked" then "question"
+      elif .status == "failed" and .acknowledged != true then "failed"
+      elif .status == "completed" and .acknowledged != true then "waiting"
       elif .status == "in_progress" then "in_progress"
       else empty end
-    ' 2>/dev/null | head -1 || true)
-    case "$result" in
-      waiting) has_bell=1 ;;
-      in_progress) has_watch=1 ;;
-    esac
+    ' 2>/dev/null || true)
+    grep -qx question <<< "$result" && has_question=1
+    grep -qx failed <<< "$result" && has_fail=1
+    grep -qx waiting <<< "$result" && has_bell=1
+    grep -qx in_progress <<< "$result" && has_watch=1
   fi
 fi
 